@@ -5,6 +5,8 @@ import (
 	"context"
 
 	"github.com/dmitrovia/collector-metrics/internal/functions/compress"
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
 	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -13,20 +15,30 @@ import (
 
 const cun = codes.Unknown
 
-func DecompressInterceptor() grpc.UnaryServerInterceptor {
+const cre = codes.ResourceExhausted
+
+func DecompressInterceptor(
+	params *bizmodels.InitParams,
+) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context,
 		req any,
-		_ *grpc.UnaryServerInfo,
+		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
 		reqType, ok := req.(*pb.SenderRequest)
 		if !ok {
-			return nil, status.Errorf(cun, "DecryptInterceptor->Ca")
+			return handler(ctx, req)
 		}
 
+		limits := params.GetBodyLimits(info.FullMethod)
 		r := bytes.NewReader(reqType.GetMetrics())
 
-		decompress, err := compress.DeflateDecompress(r)
+		decompress, err := compress.DeflateDecompressLimit(r,
+			limits.MaxDecompressed)
+		if limit.IsTooLarge(err) {
+			return nil, status.Errorf(cre, "DecryptInterceptor->Li")
+		}
+
 		if err != nil {
 			return nil, status.Errorf(cun, "DecryptInterceptor->De")
 		}
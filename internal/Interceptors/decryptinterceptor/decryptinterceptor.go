@@ -14,22 +14,30 @@ import (
 
 const cun = codes.Unknown
 
+const cre = codes.ResourceExhausted
+
 func DecryptInterceptor(
 	params *bizmodels.InitParams,
 ) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context,
 		req any,
-		_ *grpc.UnaryServerInfo,
+		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
+		reqType, ok := req.(*pb.SenderRequest)
+		if !ok {
+			return handler(ctx, req)
+		}
+
 		key, err := os.ReadFile(params.CryptoPrivateKeyPath)
 		if err != nil {
 			return nil, status.Errorf(cun, "DecryptInterceptor->RF")
 		}
 
-		reqType, ok := req.(*pb.SenderRequest)
-		if !ok {
-			return nil, status.Errorf(cun, "DecryptInterceptor->C")
+		limits := params.GetBodyLimits(info.FullMethod)
+		if limits.MaxCompressed > 0 &&
+			int64(len(reqType.GetMetrics())) > limits.MaxCompressed {
+			return nil, status.Errorf(cre, "DecryptInterceptor->L")
 		}
 
 		decr, err := asymcrypto.Decrypt(&reqType.Metrics, &key)
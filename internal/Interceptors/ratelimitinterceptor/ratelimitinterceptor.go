@@ -0,0 +1,124 @@
+// Package ratelimitinterceptor
+// implements interceptors to limit
+// the rate of the ingestion methods.
+package ratelimitinterceptor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RateLimitInterceptor - takes a token of
+// the client for every call of the methods,
+// calls without one are rejected with
+// RESOURCE_EXHAUSTED and the retry delay.
+func RateLimitInterceptor(
+	limiters *ratelimit.Set,
+	mode string,
+	methods []string,
+) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ok, wait := limiters.Get(info.FullMethod).Allow(
+			ratelimit.ContextKey(ctx, mode))
+		if !ok {
+			err := grpc.SetHeader(ctx, retryHeader(wait))
+			if err != nil {
+				fmt.Println("RateLimitInterceptor->SetHeader: %w",
+					err)
+			}
+
+			return nil, ratelimit.Exhausted(wait)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor - takes a token when the
+// stream is opened, the messages of client
+// streams wait for a token, so a fast
+// client is slowed down instead of
+// losing its stream.
+func StreamInterceptor(
+	limiters *ratelimit.Set,
+	mode string,
+	methods []string,
+) grpc.StreamServerInterceptor {
+	return func(srv any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if !slices.Contains(methods, info.FullMethod) {
+			return handler(srv, stream)
+		}
+
+		limiter := limiters.Get(info.FullMethod)
+		key := ratelimit.ContextKey(stream.Context(), mode)
+
+		ok, wait := limiter.Allow(key)
+		if !ok {
+			err := stream.SetHeader(retryHeader(wait))
+			if err != nil {
+				fmt.Println("StreamInterceptor->SetHeader: %w", err)
+			}
+
+			return ratelimit.Exhausted(wait)
+		}
+
+		if !info.IsClientStream {
+			return handler(srv, stream)
+		}
+
+		return handler(srv, &limitedStream{
+			ServerStream: stream,
+			limiter:      limiter,
+			key:          key,
+		})
+	}
+}
+
+// limitedStream - waits for a token
+// before receiving the next message.
+type limitedStream struct {
+	grpc.ServerStream
+	limiter  *ratelimit.Limiter
+	key      string
+	received bool
+}
+
+// RecvMsg - receives the message, the first
+// one is covered by the token of the stream.
+func (l *limitedStream) RecvMsg(msg any) error {
+	if l.received {
+		err := l.limiter.Wait(l.Context(), l.key)
+		if err != nil {
+			return fmt.Errorf("RecvMsg->Wait: %w", err)
+		}
+	}
+
+	l.received = true
+
+	return l.ServerStream.RecvMsg(msg) //nolint:wrapcheck
+}
+
+// retryHeader - the retry delay
+// as the header metadata.
+func retryHeader(wait time.Duration) metadata.MD {
+	return metadata.Pairs(ratelimit.MetadataKey,
+		ratelimit.Seconds(wait))
+}
@@ -0,0 +1,82 @@
+// Package validateinterceptor
+// implements interceptor to check
+// buf.validate constraints of requests.
+package validateinterceptor
+
+import (
+	"context"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/protovalid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const cia = codes.InvalidArgument
+
+// ValidateInterceptor - rejects requests
+// that break the constraints declared
+// in the proto with INVALID_ARGUMENT.
+func ValidateInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		err := protovalid.Validate(msg)
+		if err != nil {
+			return nil, status.Error(cia, err.Error())
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor - rejects the request
+// of server-streaming methods with INVALID_ARGUMENT.
+// Client streams check every message in the handler,
+// so one bad message does not end the stream.
+func StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if info.IsClientStream {
+			return handler(srv, stream)
+		}
+
+		return handler(srv, &validatedStream{stream})
+	}
+}
+
+// validatedStream - checks received messages.
+type validatedStream struct {
+	grpc.ServerStream
+}
+
+// RecvMsg - receives and checks the message.
+func (v *validatedStream) RecvMsg(msg any) error {
+	err := v.ServerStream.RecvMsg(msg)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	protoMsg, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	err = protovalid.Validate(protoMsg)
+	if err != nil {
+		return status.Error(cia, err.Error())
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
@@ -18,23 +19,29 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dmitrovia/collector-metrics/internal/aggregate"
+	"github.com/dmitrovia/collector-metrics/internal/backoff"
+	"github.com/dmitrovia/collector-metrics/internal/collector"
 	"github.com/dmitrovia/collector-metrics/internal/endpoints/sendmetricsjsonendpoint"
+	"github.com/dmitrovia/collector-metrics/internal/endpoints/streamsenderendpoint"
 	"github.com/dmitrovia/collector-metrics/internal/functions/asymcrypto"
+	"github.com/dmitrovia/collector-metrics/internal/functions/batchid"
 	"github.com/dmitrovia/collector-metrics/internal/functions/compress"
 	"github.com/dmitrovia/collector-metrics/internal/functions/config"
+	"github.com/dmitrovia/collector-metrics/internal/functions/grpcclient"
 	"github.com/dmitrovia/collector-metrics/internal/functions/hash"
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
 	"github.com/dmitrovia/collector-metrics/internal/functions/ip"
-	"github.com/dmitrovia/collector-metrics/internal/functions/random"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
 	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
 	"github.com/dmitrovia/collector-metrics/internal/logger"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
-	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v1"
-	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/dmitrovia/collector-metrics/internal/outbox"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 //nolint:gochecknoglobals
@@ -48,6 +55,12 @@ const defPORT string = ""
 
 const validAddrPattern = "^[a-zA-Z/ ]{1,100}:[0-9]{1,10}$"
 
+const validDestPattern = "^[0-9a-zA-Z]{1,40}$"
+
+const transportHTTP = "http"
+
+const transportGRPC = "grpc"
+
 const defKeyHashSha256 = ""
 
 const defPollInterval int = 0
@@ -58,6 +71,26 @@ const metricGaugeCount int = 30
 
 const defCountJobs int = 3
 
+const defOutboxSize int = 100
+
+const defRetryInitial = time.Second
+
+const defRetryMax = 30 * time.Second
+
+const defRetryMaxElapsed = 2 * time.Minute
+
+const defRetryMultiplier = 2
+
+const defRetryJitter = 0.2
+
+const defRetryAttempts = 3
+
+const defBreakerFailures = 5
+
+const defBreakerCooldown = 30 * time.Second
+
+const defReportJitter = 0.1
+
 var errGetENV = errors.New(
 	"REPORT_INTERVAL failed converting to int")
 
@@ -71,6 +104,31 @@ var errParseFlags = errors.New("addr is not valid")
 
 var errResponse = errors.New("error response")
 
+var errRetries = errors.New("request retries exhausted")
+
+var errDestName = errors.New(
+	"destination name is not valid")
+
+var errDestDuplicate = errors.New(
+	"destination is duplicated")
+
+var errTransport = errors.New("transport is not valid")
+
+var errPlainGRPC = errors.New(
+	"grpc transport requires TLS")
+
+var errStreamDests = errors.New(
+	"use-stream does not support configured destinations")
+
+var errGetENV3 = errors.New(
+	"OUTBOX_SIZE failed converting to int")
+
+var errGetENV4 = errors.New(
+	"GRPC_TLS failed converting to bool")
+
+var errGetENV5 = errors.New(
+	"failed converting to int")
+
 const defCryptoKeyPath string = ""
 
 const defConfigPath string = "/internal/config/agent.json"
@@ -83,20 +141,19 @@ func worker(jobs <-chan bizmodels.JobData,
 		wg.Add(1)
 
 		switch event.Event {
-		case "setValuesMonitor":
-			setValuesMonitor(event.Mon, event.Mutex)
-		case "setMonitorFromGoPsUtil":
-			setMonitorFromGoPsUtil(event.Mon, event.Mutex)
+		case "collect":
+			collect(event.Collector, event.Mon, event.Par)
 		case "reqMetricsJSON":
-			reqMetricsJSON(event.Par, event.Client, event.Mon)
+			reqMetricsJSON(event.Par, event.Dest, event.Client,
+				event.Mon, event.Data, event.Taken)
 		}
 
 		wg.Done()
 	}
 }
 
-// Collect - collects device metrics
-// every PollInterval seconds using workers.
+// Collect - polls every collector
+// with its own interval using workers.
 func Collect(
 	chc *chan os.Signal,
 	par *bizmodels.InitParamsAgent,
@@ -107,45 +164,99 @@ func Collect(
 ) {
 	defer wg.Done()
 
-	var mutex sync.Mutex
-
 	signal.Notify(*chc,
 		os.Interrupt,
 		syscall.SIGTERM,
 		syscall.SIGINT,
 		syscall.SIGQUIT)
 
+	next := make([]time.Time, len(par.Collectors))
+	for i := range par.Collectors {
+		next[i] = time.Now().Add(pollInterval(&par.Collectors[i]))
+	}
+
 	for {
 		select {
 		case <-*chc:
 			wgEndWork.Wait()
 
 			return
-		case <-time.After(
-			time.Duration(par.PollInterval) * time.Second):
-			dataChan := &bizmodels.JobData{}
-			dataChan.Event = "setValuesMonitor"
-			dataChan.Mutex = &mutex
-			dataChan.Mon = mon
+		case <-time.After(untilNextPoll(next, par.PollInterval)):
+			now := time.Now()
 
-			jobs <- *dataChan
+			for i := range par.Collectors {
+				if now.Before(next[i]) {
+					continue
+				}
 
-			go worker(jobs, wgEndWork)
+				next[i] = now.Add(pollInterval(&par.Collectors[i]))
 
-			dataChan1 := &bizmodels.JobData{}
-			dataChan1.Event = "setMonitorFromGoPsUtil"
-			dataChan1.Mutex = &mutex
-			dataChan1.Mon = mon
+				dataChan := &bizmodels.JobData{}
+				dataChan.Event = "collect"
+				dataChan.Collector = &par.Collectors[i]
+				dataChan.Mon = mon
+				dataChan.Par = par
 
-			jobs <- *dataChan1
+				jobs <- *dataChan
 
-			go worker(jobs, wgEndWork)
+				go worker(jobs, wgEndWork)
+			}
+		}
+	}
+}
+
+// pollInterval - returns the
+// poll interval of the collector.
+func pollInterval(
+	polled *bizmodels.PolledCollector,
+) time.Duration {
+	return time.Duration(polled.Interval) * time.Second
+}
+
+// untilNextPoll - returns the time until
+// the earliest collector poll.
+func untilNextPoll(
+	next []time.Time,
+	interval int,
+) time.Duration {
+	if len(next) == 0 {
+		return time.Duration(interval) * time.Second
+	}
+
+	earliest := next[0]
+	for _, tim := range next[1:] {
+		if tim.Before(earliest) {
+			earliest = tim
 		}
 	}
+
+	return time.Until(earliest)
+}
+
+// collect - polls the collector, merges the
+// sample into the monitor and the aggregator.
+func collect(polled *bizmodels.PolledCollector,
+	mon *bizmodels.Monitor,
+	par *bizmodels.InitParamsAgent,
+) {
+	sample := bizmodels.NewSample()
+
+	err := polled.Collector.Collect(sample)
+	if err != nil {
+		fmt.Println("collect->Collect:", polled.Name, err)
+	}
+
+	mon.Merge(sample)
+
+	if par.Aggregator != nil {
+		par.Aggregator.Add(sample)
+	}
 }
 
-// Send - sends metrics to the server
-// every ReportInterval seconds using workers.
+// Send - sends metrics to every destination
+// every ReportInterval seconds spread by
+// ReportJitter using a worker per destination,
+// so a slow destination does not delay others.
 func Send(
 	chc *chan os.Signal,
 	par *bizmodels.InitParamsAgent,
@@ -169,13 +280,21 @@ func Send(
 			wgEndWork.Wait()
 
 			return
-		case <-time.After(
-			time.Duration(par.ReportInterval) * time.Second):
-			if !par.RepeatedReq {
+		case <-time.After(backoff.Jitter(
+			time.Duration(par.ReportInterval)*time.Second,
+			par.ReportJitter)):
+			taken := time.Now()
+			data := snapshotData(mon)
+
+			for _, dest := range par.Destinations {
 				dataChan := &bizmodels.JobData{}
 				dataChan.Event = "reqMetricsJSON"
 				dataChan.Mon = mon
 				dataChan.Par = par
+				dataChan.Dest = dest
+				dataChan.Data = withAggregated(data,
+					par.Aggregator, dest, taken)
+				dataChan.Taken = taken
 				dataChan.Client = client
 
 				jobs <- *dataChan
@@ -192,75 +311,78 @@ func fillMetrics(mon *bizmodels.Monitor,
 	gauges *[]bizmodels.Gauge,
 	counters map[string]bizmodels.Counter,
 ) {
-	counters["PollCount"] = mon.PollCount
-
-	*gauges = append(
-		*gauges,
-		mon.TotalMemory,
-		mon.FreeMemory,
-		mon.CPUutilization1,
-		mon.Alloc,
-		mon.BuckHashSys,
-		mon.Frees,
-		mon.GCCPUFraction,
-		mon.GCSys,
-		mon.HeapAlloc,
-		mon.HeapIdle,
-		mon.HeapInuse,
-		mon.HeapObjects,
-		mon.HeapReleased,
-		mon.HeapSys,
-		mon.LastGC,
-		mon.Lookups,
-		mon.MCacheInuse,
-		mon.MCacheInuse,
-		mon.MCacheSys,
-		mon.MSpanInuse,
-		mon.MSpanSys,
-		mon.Mallocs,
-		mon.NextGC,
-		mon.NumForcedGC,
-		mon.NumGC,
-		mon.OtherSys,
-		mon.PauseTotalNs,
-		mon.StackInuse,
-		mon.StackSys,
-		mon.Sys,
-		mon.TotalAlloc,
-		mon.RandomValue)
+	monGauges, monCounters := mon.Snapshot()
+
+	*gauges = append(*gauges, monGauges...)
+
+	maps.Copy(counters, monCounters)
 }
 
-func getSettings(client *http.Client,
-	par *bizmodels.InitParamsAgent,
+// snapshotData - returns the
+// monitor metrics in API format.
+func snapshotData(
 	mon *bizmodels.Monitor,
-) (*bizmodels.EndpointSettings, error) {
+) *apimodels.ArrMetrics {
 	gauges := make([]bizmodels.Gauge, 0, metricGaugeCount)
 	counters := make(map[string]bizmodels.Counter, 1)
 
 	fillMetrics(mon, &gauges, counters)
 
+	return getDataSend(&gauges, counters)
+}
+
+// withAggregated - returns a copy of the data
+// with the aggregations of the gauges polled
+// since the last report delivered to the
+// destination until the time added.
+func withAggregated(data *apimodels.ArrMetrics,
+	agg bizmodels.Aggregator,
+	dest *bizmodels.Destination,
+	until time.Time,
+) *apimodels.ArrMetrics {
+	if agg == nil {
+		return data
+	}
+
+	gauges := agg.Gauges(dest.Name, until)
+	if len(gauges) == 0 {
+		return data
+	}
+
+	aggregated := getDataSend(&gauges, nil)
+
+	merged := make(apimodels.ArrMetrics, 0,
+		len(*data)+len(*aggregated))
+	merged = append(merged, *data...)
+	merged = append(merged, *aggregated...)
+
+	return &merged
+}
+
+func getSettings(client *http.Client,
+	dest *bizmodels.Destination,
+	data *apimodels.ArrMetrics,
+) (*bizmodels.EndpointSettings, error) {
+	batchID, err := batchid.New()
+	if err != nil {
+		return nil, fmt.Errorf("getSettings->New: %w", err)
+	}
+
 	settings := &bizmodels.EndpointSettings{}
+	settings.BatchID = batchID
 	settings.Client = client
+	settings.Agent = dest.Agent
 	settings.ContentType = "application/json"
 	settings.Encoding = "gzip"
-	settings.URL = par.URL + "/updates/"
+	settings.URL = dest.URL + "/updates/?summary=true"
 
-	if par.UpdateURL != "" {
-		settings.URL = par.UpdateURL
+	if dest.UpdateURL != "" {
+		settings.URL = dest.UpdateURL
 	}
 
-	if par.UseGRPC {
-		conn, err1 := grpc.NewClient(
-			"localhost:"+par.GRPCPort,
-			grpc.WithTransportCredentials(
-				(insecure.NewCredentials())))
-		if err1 != nil {
-			return nil, fmt.Errorf("getSettings->NewClien: %w", err1)
-		}
-
-		settings.ConnGRPC = conn
-		settings.
-			MicroServiceClient = pb.NewMicroServiceClient(conn)
+	if dest.UseGRPC {
+		settings.ConnGRPC = dest.ConnGRPC
+		settings.MicroServiceClient = dest.MicroServiceClient
 	}
 
 	ips, err := ip.GetLocalIPs()
@@ -270,7 +392,16 @@ func getSettings(client *http.Client,
 
 	settings.RealIPHeader = ips[0]
 
-	req, err := initReqData(&gauges, counters, settings, par)
+	if dest.UseGRPC {
+		err = initReqDataGRPC(data, settings, dest)
+		if err != nil {
+			return nil, fmt.Errorf("getSettings->initGRPC: %w", err)
+		}
+
+		return settings, nil
+	}
+
+	req, err := initReqData(data, settings, dest)
 	if err != nil {
 		return nil, fmt.Errorf("getSettings->initReqDat: %w", err)
 	}
@@ -280,80 +411,311 @@ func getSettings(client *http.Client,
 	return settings, nil
 }
 
-// reqMetricsJSON - prepares data
-// for the request and sends the request to the server.
+// reqMetricsJSON - sends the data
+// of the report taken at the time
+// to the destination.
+// With the outbox the data is spooled first and
+// the spooled batches are sent oldest first.
+// While a previous report is being sent to the
+// destination the data is only spooled.
+// The counter increments of a report that is
+// neither sent nor spooled are added to the
+// next report of the destination.
 func reqMetricsJSON(par *bizmodels.InitParamsAgent,
+	dest *bizmodels.Destination,
 	client *http.Client,
 	mon *bizmodels.Monitor,
+	data *apimodels.ArrMetrics,
+	taken time.Time,
 ) {
-	settings, err := getSettings(client, par, mon)
-	if err != nil {
-		fmt.Println("reqMetricsJSON->getSettings: %w", err)
+	if par.Stream != nil {
+		reqMetricsStream(par, dest, mon, data, taken)
 
 		return
 	}
 
-	sInterval := par.StartReqInterval
+	data = withUnsent(dest, data)
 
-	for iter := 1; iter <= par.CountReqRetries; iter++ {
-		var resp *http.Response
-
-		var err error
-
-		if par.UseGRPC {
-			_,
-				err = sendmetricsjsonendpoint.SendMJSONEndpointGRPC(
-				settings)
+	if dest.Outbox != nil {
+		err := dest.Outbox.Push(data)
+		if err != nil {
+			fmt.Println("reqMetricsJSON->Push:", dest.Name, err)
+			keepUnsent(dest, data)
 		} else {
-			resp, err = sendmetricsjsonendpoint.SendMJSONEndpoint(
-				settings)
+			dropAggregated(par, dest, taken)
 		}
+	}
+
+	if !dest.Sending.CompareAndSwap(false, true) {
+		if dest.Outbox == nil {
+			deferReport(dest, mon, data)
+		}
+
+		return
+	}
+
+	defer dest.Sending.Store(false)
 
+	if dest.Outbox == nil {
+		err := sendBatch(dest, client, data)
 		if err != nil {
-			par.RepeatedReq = true
+			fmt.Println("reqMetricsJSON->sendBatch:", dest.Name, err)
+			keepUnsent(dest, data)
 
-			fmt.Println("reqMetricsJSON->SendMJSONEndpoint: %w",
-				err)
+			return
+		}
 
-			time.Sleep(time.Duration(sInterval) * time.Second)
+		dropAggregated(par, dest, taken)
 
-			sInterval += par.ReqInternal
+		return
+	}
 
-			continue
+	err := dest.Outbox.Drain(
+		func(batch *apimodels.ArrMetrics) error {
+			return sendBatch(dest, client, batch)
+		})
+	if err != nil {
+		fmt.Println("reqMetricsJSON->Drain:", dest.Name, err)
+	}
+
+	reportOutbox(dest, mon)
+}
+
+// withUnsent - returns a copy of the data
+// with the counter increments not delivered
+// to the destination added, the data is
+// shared by the destinations.
+func withUnsent(dest *bizmodels.Destination,
+	data *apimodels.ArrMetrics,
+) *apimodels.ArrMetrics {
+	unsent := dest.Unsent.Take()
+	if len(unsent) == 0 {
+		return data
+	}
+
+	merged := make(apimodels.ArrMetrics, 0,
+		len(*data)+len(unsent))
+
+	for _, met := range *data {
+		delta, ok := unsent[met.ID]
+		if ok && met.MType == bizmodels.CounterName &&
+			met.Delta != nil {
+			sum := *met.Delta + delta
+			met.Delta = &sum
+
+			delete(unsent, met.ID)
 		}
 
-		if !par.UseGRPC {
-			_, err = parseResponse(resp)
-			resp.Body.Close()
+		merged = append(merged, met)
+	}
+
+	for name, delta := range unsent {
+		merged = append(merged, apimodels.Metrics{
+			ID:    name,
+			MType: bizmodels.CounterName,
+			Delta: &delta,
+		})
+	}
+
+	return &merged
+}
+
+// keepUnsent - keeps the counter increments
+// of the data for the next report.
+func keepUnsent(dest *bizmodels.Destination,
+	data *apimodels.ArrMetrics,
+) {
+	deltas := make(map[string]int64)
+
+	for _, met := range *data {
+		if met.MType == bizmodels.CounterName &&
+			met.Delta != nil {
+			deltas[met.ID] += *met.Delta
 		}
+	}
+
+	dest.Unsent.Add(deltas)
+}
+
+// deferReport - keeps the report for the
+// next one while the previous is still being
+// sent, the counter increments are added to
+// the next report and the aggregated values
+// are aggregated again. The deferred reports
+// are counted in the monitor.
+func deferReport(dest *bizmodels.Destination,
+	mon *bizmodels.Monitor,
+	data *apimodels.ArrMetrics,
+) {
+	keepUnsent(dest, data)
+
+	sample := bizmodels.NewSample()
+	sample.AddCounter("ReportsDeferred"+destSuffix(dest), 1)
+
+	mon.Merge(sample)
+}
+
+// dropAggregated - drops the polled values
+// aggregated in the report taken at the time
+// for the destination once it is sent or
+// spooled, the values are aggregated again
+// for the destination until then.
+func dropAggregated(par *bizmodels.InitParamsAgent,
+	dest *bizmodels.Destination,
+	taken time.Time,
+) {
+	if par.Aggregator != nil {
+		par.Aggregator.Drop(dest.Name, taken)
+	}
+}
+
+// reportOutbox - adds the depth of the outbox and
+// the dropped batches to the monitor, the names
+// of a named destination end with its name.
+func reportOutbox(dest *bizmodels.Destination,
+	mon *bizmodels.Monitor,
+) {
+	suffix := destSuffix(dest)
+
+	sample := bizmodels.NewSample()
+	sample.SetGauge("OutboxDepth"+suffix,
+		float64(dest.Outbox.Depth()))
+	sample.AddCounter("OutboxDropped"+suffix,
+		dest.Outbox.TakeDropped())
+
+	mon.Merge(sample)
+}
+
+// destSuffix - the names of the metrics of
+// a named destination end with its name.
+func destSuffix(dest *bizmodels.Destination) string {
+	if dest.Name == "" {
+		return ""
+	}
+
+	return "/" + dest.Name
+}
+
+// sendBatch - sends the batch to the server,
+// the retries are delayed by the retry policy
+// or by the delay asked by the server and
+// stop while the circuit breaker is open.
+func sendBatch(dest *bizmodels.Destination,
+	client *http.Client,
+	data *apimodels.ArrMetrics,
+) error {
+	settings, err := getSettings(client, dest, data)
+	if err != nil {
+		return fmt.Errorf("sendBatch->getSettings: %w", err)
+	}
 
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		err = dest.Breaker.Allow()
 		if err != nil {
-			par.RepeatedReq = true
+			return fmt.Errorf("sendBatch->Allow: %w", err)
+		}
 
-			fmt.Println("reqMetricsJSON->parseResponse: %w", err)
+		wait, limited, err := sendOnce(dest, settings)
+		if err == nil {
+			dest.Breaker.Success()
 
-			time.Sleep(time.Duration(sInterval) * time.Second)
+			return nil
+		}
 
-			sInterval += par.ReqInternal
+		if !limited {
+			dest.Breaker.Failure()
+		}
 
-			continue
+		fmt.Println("sendBatch->sendOnce:", err)
+
+		delay := backoff.Delay(dest.Retry, attempt)
+		if limited {
+			delay = max(delay, wait)
+		}
+
+		if attempt+1 >= dest.Retry.Attempts ||
+			(dest.Retry.MaxElapsed > 0 &&
+				time.Since(start)+delay > dest.Retry.MaxElapsed) {
+			return fmt.Errorf("sendBatch: %w: %w", errRetries, err)
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+// sendOnce - sends the request, returns the
+// delay asked by a rate limited server.
+func sendOnce(dest *bizmodels.Destination,
+	settings *bizmodels.EndpointSettings,
+) (time.Duration, bool, error) {
+	if dest.UseGRPC {
+		_, err := sendmetricsjsonendpoint.SendMJSONEndpointGRPC(
+			settings)
+		if err != nil {
+			wait, limited := ratelimit.FromError(err)
+
+			return wait, limited,
+				fmt.Errorf("sendOnce->SendMJSONEndpointGRPC: %w", err)
 		}
 
-		break
+		return 0, false, nil
+	}
+
+	resp, err := sendmetricsjsonendpoint.SendMJSONEndpoint(
+		settings)
+	if err != nil {
+		return 0, false,
+			fmt.Errorf("sendOnce->SendMJSONEndpoint: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	wait, limited := ratelimit.FromResponse(resp)
+
+	_, err = parseResponse(resp)
+	if err != nil {
+		return wait, limited,
+			fmt.Errorf("sendOnce->parseResponse: %w", err)
+	}
+
+	return 0, false, nil
+}
+
+// reqMetricsStream - sends metrics as a batch
+// of the long-lived stream, unacknowledged
+// batches are resent by the stream itself.
+// The batches the stream dropped are
+// added to the monitor.
+func reqMetricsStream(par *bizmodels.InitParamsAgent,
+	dest *bizmodels.Destination,
+	mon *bizmodels.Monitor,
+	data *apimodels.ArrMetrics,
+	taken time.Time,
+) {
+	err := par.Stream.SendBatch(data)
+
+	sample := bizmodels.NewSample()
+	sample.AddCounter("StreamDropped",
+		par.Stream.TakeDropped())
+	mon.Merge(sample)
+
+	if err != nil {
+		fmt.Printf("reqMetricsStream->SendBatch: %v\n", err)
+
+		return
 	}
 
-	par.RepeatedReq = false
+	dropAggregated(par, dest, taken)
 }
 
 // initReqData - prepares the body
 // for the encrypted request.
-func initReqData(gauges *[]bizmodels.Gauge,
-	counters map[string]bizmodels.Counter,
+func initReqData(dataMarshal *apimodels.ArrMetrics,
 	settings *bizmodels.EndpointSettings,
-	params *bizmodels.InitParamsAgent,
+	dest *bizmodels.Destination,
 ) (*bytes.Reader, error) {
-	dataMarshal := getDataSend(gauges, counters)
-
 	metricMarshall, err := json.Marshal(dataMarshal)
 	if err != nil {
 		return nil, err
@@ -365,7 +727,7 @@ func initReqData(gauges *[]bizmodels.Gauge,
 		return nil, fmt.Errorf("initReqData->Deflate: %w", err)
 	}
 
-	key, err := os.ReadFile(params.CryptoPublicKeyPath)
+	key, err := os.ReadFile(dest.CryptoPublicKeyPath)
 	if err != nil {
 		return nil, fmt.Errorf("initReqData->ReadFile: %w", err)
 	}
@@ -375,9 +737,9 @@ func initReqData(gauges *[]bizmodels.Gauge,
 		return nil, fmt.Errorf("initReqData->Encrypt: %w", err)
 	}
 
-	if params.Key != "" {
+	if dest.Key != "" {
 		tHash, err := hash.MakeHashSHA256(&metricMarshall,
-			params.Key)
+			dest.Key)
 		if err != nil {
 			return nil, fmt.Errorf("initReqData->MakeHas: %w", err)
 		}
@@ -387,11 +749,40 @@ func initReqData(gauges *[]bizmodels.Gauge,
 		settings.Hash = encodedStr
 	}
 
-	settings.MetricsGRPC = encr
-
 	return bytes.NewReader(*encr), nil
 }
 
+// initReqDataGRPC - prepares the typed request.
+// The hash is computed over the canonical
+// JSON form of the metrics.
+func initReqDataGRPC(dataSend *apimodels.ArrMetrics,
+	settings *bizmodels.EndpointSettings,
+	dest *bizmodels.Destination,
+) error {
+	settings.RequestGRPC = &pb.SenderRequest{
+		Metrics:     pbconv.MetricsToPB(dataSend, time.Now()),
+		SummaryOnly: true,
+	}
+
+	if dest.Key == "" {
+		return nil
+	}
+
+	data, err := pbconv.RequestBytes(settings.RequestGRPC)
+	if err != nil {
+		return fmt.Errorf("initReqDataGRPC->ReqBytes: %w", err)
+	}
+
+	tHash, err := hash.MakeHashSHA256(&data, dest.Key)
+	if err != nil {
+		return fmt.Errorf("initReqDataGRPC->MakeHas: %w", err)
+	}
+
+	settings.Hash = hex.EncodeToString(tHash)
+
+	return nil
+}
+
 // parseResponse - parses the response from the server.
 func parseResponse(
 	response *http.Response,
@@ -449,10 +840,17 @@ func Initialization(params *bizmodels.InitParamsAgent,
 	params.URL = "http://"
 	params.ReportInterval = 10
 	params.PollInterval = 2
-	params.ReqInternal = 2
-	params.StartReqInterval = 1
-	params.CountReqRetries = 3
-	params.RepeatedReq = false
+	params.Retry = bizmodels.RetryPolicy{
+		Initial:    defRetryInitial,
+		Max:        defRetryMax,
+		MaxElapsed: defRetryMaxElapsed,
+		Multiplier: defRetryMultiplier,
+		Jitter:     defRetryJitter,
+		Attempts:   defRetryAttempts,
+	}
+	params.BreakerFailures = defBreakerFailures
+	params.BreakerCooldown = defBreakerCooldown
+	params.ReportJitter = defReportJitter
 
 	err = parseFlags(params)
 	if err != nil {
@@ -474,8 +872,59 @@ func Initialization(params *bizmodels.InitParamsAgent,
 		return nil, err
 	}
 
+	err = getOutboxEnv(params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getGRPCEnv(params)
+	if err != nil {
+		return nil, err
+	}
+
+	getAgentEnv(params)
+
+	err = initAgent(params)
+	if err != nil {
+		return nil, fmt.Errorf("Initialization->initAg: %w", err)
+	}
+
 	params.URL += params.PORT
 
+	if params.GRPC.Addr == "" {
+		params.GRPC.Addr = "localhost:" + params.GRPCPort
+	}
+
+	if params.OutboxSize == 0 {
+		params.OutboxSize = defOutboxSize
+	}
+
+	// the stream sends to the agent address
+	// only, the destinations would be ignored.
+	if params.UseStream && len(params.Destinations) > 0 {
+		return nil, fmt.Errorf("Initialization: %w",
+			errStreamDests)
+	}
+
+	err = initDestinations(params)
+	if err != nil {
+		return nil, fmt.Errorf("Initialization->initDes: %w", err)
+	}
+
+	if len(params.Aggregation) > 0 {
+		params.Aggregator, err = aggregate.New(
+			params.Aggregation, destNames(params))
+		if err != nil {
+			return nil, fmt.Errorf("Initialization->New: %w", err)
+		}
+	}
+
+	params.Collectors, err = collector.Default().Build(
+		params.CollectorsCfg, params.PollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("Initialization->Build: %w", err)
+	}
+
 	mon.Init()
 
 	zlog, err := logger.Initialize(zapLogLevel)
@@ -514,6 +963,116 @@ func getIntervalsEnv(
 	return nil
 }
 
+// getGRPCEnv - gets the grpc
+// connection environment variables.
+func getGRPCEnv(params *bizmodels.InitParamsAgent) error {
+	strs := map[string]*string{
+		"GRPC_ADDRESS":     &params.GRPC.Addr,
+		"GRPC_CA":          &params.GRPC.CAFile,
+		"GRPC_CERT":        &params.GRPC.CertFile,
+		"GRPC_CERT_KEY":    &params.GRPC.KeyFile,
+		"GRPC_SERVER_NAME": &params.GRPC.ServerName,
+	}
+
+	for name, dst := range strs {
+		if value := os.Getenv(name); value != "" {
+			*dst = value
+		}
+	}
+
+	if value := os.Getenv("GRPC_TLS"); value != "" {
+		tlsOn, err := strconv.ParseBool(value)
+		if err != nil {
+			return errGetENV4
+		}
+
+		params.GRPC.TLS = &tlsOn
+	}
+
+	durations := map[string]*time.Duration{
+		"GRPC_KEEPALIVE":         &params.GRPC.Keepalive,
+		"GRPC_KEEPALIVE_TIMEOUT": &params.GRPC.KeepaliveTimeout,
+	}
+
+	for name, dst := range durations {
+		if value := os.Getenv(name); value != "" {
+			secs, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("getGRPCEnv: %w: %s",
+					errGetENV5, name)
+			}
+
+			*dst = seconds(secs)
+		}
+	}
+
+	return nil
+}
+
+// getAgentEnv - gets the agent
+// identity environment variables.
+func getAgentEnv(params *bizmodels.InitParamsAgent) {
+	if value := os.Getenv("AGENT_ID"); value != "" {
+		params.Agent.ID = value
+	}
+
+	if value := os.Getenv("AGENT_ID_FILE"); value != "" {
+		params.AgentIDFile = value
+	}
+}
+
+// initAgent - sets the identity and the
+// attributes sent with every batch.
+// Without a configured id the id is read
+// from the file or generated and saved
+// there, so it is kept between restarts.
+func initAgent(params *bizmodels.InitParamsAgent) error {
+	var err error
+
+	switch {
+	case params.Agent.ID != "":
+		err = identity.Validate(params.Agent.ID)
+	case params.AgentIDFile != "":
+		params.Agent.ID, err = identity.Load(params.AgentIDFile)
+	default:
+		params.Agent.ID, err = identity.New()
+	}
+
+	if err != nil {
+		return fmt.Errorf("initAgent: %w", err)
+	}
+
+	params.Agent.Hostname, _ = os.Hostname()
+	params.Agent.Version = buildVersion
+	params.Agent.OS = runtime.GOOS
+
+	return nil
+}
+
+// getOutboxEnv - gets the outbox
+// environment variables.
+func getOutboxEnv(
+	params *bizmodels.InitParamsAgent,
+) error {
+	envOutboxPath := os.Getenv("OUTBOX_PATH")
+	envOutboxSize := os.Getenv("OUTBOX_SIZE")
+
+	if envOutboxPath != "" {
+		params.OutboxPath = envOutboxPath
+	}
+
+	if envOutboxSize != "" {
+		value, err := strconv.Atoi(envOutboxSize)
+		if err != nil {
+			return errGetENV3
+		}
+
+		params.OutboxSize = value
+	}
+
+	return nil
+}
+
 // getENV - gets environment variables.
 //
 //nolint:cyclop
@@ -565,6 +1124,55 @@ func getENV(params *bizmodels.InitParamsAgent) error {
 	return nil
 }
 
+// parseGRPCFlags - defines the
+// grpc connection flags.
+func parseGRPCFlags(params *bizmodels.InitParamsAgent) {
+	flag.StringVar(&params.GRPC.Addr,
+		"grpc-addr", "",
+		"grpc server address, localhost:grpcp by default.")
+	flag.BoolFunc("grpc-tls", "use TLS for grpc.",
+		func(value string) error {
+			tlsOn, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("grpc-tls->ParseBool: %w", err)
+			}
+
+			params.GRPC.TLS = &tlsOn
+
+			return nil
+		})
+	flag.StringVar(&params.GRPC.CAFile,
+		"grpc-ca", "", "CA certificate of the grpc server.")
+	flag.StringVar(&params.GRPC.CertFile,
+		"grpc-cert", "", "grpc client certificate.")
+	flag.StringVar(&params.GRPC.KeyFile,
+		"grpc-cert-key", "", "grpc client certificate key.")
+	flag.StringVar(&params.GRPC.ServerName,
+		"grpc-server-name", "",
+		"server name checked in the grpc certificate.")
+	flag.Func("grpc-keepalive",
+		"grpc keepalive ping interval in seconds.",
+		secondsFlag(&params.GRPC.Keepalive))
+	flag.Func("grpc-keepalive-timeout",
+		"grpc keepalive ping timeout in seconds.",
+		secondsFlag(&params.GRPC.KeepaliveTimeout))
+}
+
+// secondsFlag - parses the flag
+// of seconds into the duration.
+func secondsFlag(dst *time.Duration) func(string) error {
+	return func(value string) error {
+		secs, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("secondsFlag->Atoi: %w", err)
+		}
+
+		*dst = seconds(secs)
+
+		return nil
+	}
+}
+
 // parseFlags - parses passed flags into variables.
 func parseFlags(params *bizmodels.InitParamsAgent) error {
 	var err error
@@ -609,6 +1217,22 @@ func parseFlags(params *bizmodels.InitParamsAgent) error {
 		"update url.")
 	flag.BoolVar(&params.UseGRPC,
 		"use-grpc", false, "use grpc")
+	flag.BoolVar(&params.UseStream,
+		"use-stream", false,
+		"use grpc ingestion stream, without destinations")
+	parseGRPCFlags(params)
+	flag.StringVar(&params.OutboxPath,
+		"outbox", "",
+		"directory of the unsent batches.")
+	flag.IntVar(&params.OutboxSize,
+		"outbox-size", 0,
+		"maximum number of the unsent batches.")
+	flag.StringVar(&params.Agent.ID,
+		"agent-id", "",
+		"agent id, read from the id file when empty.")
+	flag.StringVar(&params.AgentIDFile,
+		"agent-id-file", "",
+		"file keeping the generated agent id.")
 	flag.Parse()
 
 	res, err := validate.IsMatchesTemplate(params.PORT,
@@ -621,84 +1245,6 @@ func parseFlags(params *bizmodels.InitParamsAgent) error {
 	return nil
 }
 
-// setMonitorFromGoPsUtil - gets CPU Metrics.
-func setMonitorFromGoPsUtil(mon *bizmodels.Monitor,
-	mutex *sync.Mutex,
-) {
-	virtMem, _ := mem.VirtualMemory()
-
-	cores, _ := cpu.Info()
-
-	mutex.Lock()
-	mon.TotalMemory.Value = float64(virtMem.Total)
-	mon.FreeMemory.Value = float64(virtMem.Free)
-
-	for _, core := range cores {
-		mon.CPUutilization1.Value += float64(core.CPU)
-	}
-	mutex.Unlock()
-}
-
-// setMonitorFromGoPsUtil - gets
-// and sets CPU Metrics to monitor.
-func setValuesMonitor(mon *bizmodels.Monitor,
-	mutex *sync.Mutex,
-) {
-	const maxRandomValue int64 = 1000
-
-	mutex.Lock()
-	writeFromMemory(mon)
-
-	mon.PollCount.Value++
-
-	tmpCounters := make(map[string]bizmodels.Counter, 1)
-	tmpCounters["PollCount"] = mon.PollCount
-
-	rand, err := random.RandF64(maxRandomValue)
-	if err != nil {
-		fmt.Println("setValuesMonitor->random.RandF64: %w", err)
-	} else {
-		mon.RandomValue.Value = rand
-	}
-
-	mutex.Unlock()
-}
-
-// writeFromMemory - read runtime metrics.
-func writeFromMemory(mon *bizmodels.Monitor) {
-	var rtm runtime.MemStats
-
-	runtime.ReadMemStats(&rtm)
-
-	mon.Alloc.Value = float64(rtm.Alloc)
-	mon.BuckHashSys.Value = float64(rtm.BuckHashSys)
-	mon.Frees.Value = float64(rtm.Frees)
-	mon.GCCPUFraction.Value = rtm.GCCPUFraction
-	mon.GCSys.Value = float64(rtm.GCSys)
-	mon.HeapAlloc.Value = float64(rtm.HeapAlloc)
-	mon.HeapIdle.Value = float64(rtm.HeapIdle)
-	mon.HeapInuse.Value = float64(rtm.HeapInuse)
-	mon.HeapObjects.Value = float64(rtm.HeapObjects)
-	mon.HeapReleased.Value = float64(rtm.HeapReleased)
-	mon.HeapSys.Value = float64(rtm.HeapSys)
-	mon.LastGC.Value = float64(rtm.LastGC)
-	mon.Lookups.Value = float64(rtm.Lookups)
-	mon.MCacheInuse.Value = float64(rtm.MCacheInuse)
-	mon.MCacheSys.Value = float64(rtm.MCacheSys)
-	mon.MSpanInuse.Value = float64(rtm.MSpanInuse)
-	mon.MSpanSys.Value = float64(rtm.MSpanSys)
-	mon.Mallocs.Value = float64(rtm.Mallocs)
-	mon.NextGC.Value = float64(rtm.NextGC)
-	mon.NumForcedGC.Value = float64(rtm.NumForcedGC)
-	mon.NumGC.Value = float64(rtm.NumGC)
-	mon.OtherSys.Value = float64(rtm.OtherSys)
-	mon.PauseTotalNs.Value = float64(rtm.PauseTotalNs)
-	mon.StackInuse.Value = float64(rtm.StackInuse)
-	mon.StackSys.Value = float64(rtm.StackSys)
-	mon.Sys.Value = float64(rtm.Sys)
-	mon.TotalAlloc.Value = float64(rtm.TotalAlloc)
-}
-
 func GetParamsFromCFG(
 	par *bizmodels.InitParamsAgent,
 ) error {
@@ -735,9 +1281,375 @@ func GetParamsFromCFG(
 		par.ReportInterval = cfg.ReportInterval
 	}
 
+	if par.OutboxPath == "" {
+		par.OutboxPath = cfg.OutboxPath
+	}
+
+	if par.OutboxSize == 0 {
+		par.OutboxSize = cfg.OutboxSize
+	}
+
+	if par.Agent.ID == "" {
+		par.Agent.ID = cfg.AgentID
+	}
+
+	if par.AgentIDFile == "" && cfg.AgentIDFile != "" {
+		par.AgentIDFile = Root + cfg.AgentIDFile
+	}
+
+	setRetryFromCFG(par, cfg)
+
+	cfgGRPC := grpcFromCFG(&cfg.GRPC)
+	fillGRPC(&par.GRPC, &cfgGRPC)
+
+	err = setDestinationsFromCFG(par, cfg, Root)
+	if err != nil {
+		return fmt.Errorf("getParamsFromCFG->setDest: %w", err)
+	}
+
+	par.Aggregation = cfg.Aggregation
+
+	par.CollectorsCfg = make(
+		map[string]bizmodels.CollectorConfig, len(cfg.Collectors))
+
+	for name, col := range cfg.Collectors {
+		par.CollectorsCfg[name] = bizmodels.CollectorConfig{
+			Enabled:      col.Enabled,
+			Options:      col.Options,
+			PollInterval: col.PollInterval,
+		}
+	}
+
 	return nil
 }
 
+// setRetryFromCFG - sets the retry policy,
+// the circuit breaker and the report jitter
+// configured in the file over the defaults.
+func setRetryFromCFG(par *bizmodels.InitParamsAgent,
+	cfg *apimodels.CfgAgent,
+) {
+	applyRetryCFG(&par.Retry, &cfg.Retry)
+	applyBreakerCFG(&par.BreakerFailures,
+		&par.BreakerCooldown, &cfg.Breaker)
+
+	if cfg.ReportJitter > 0 {
+		par.ReportJitter = cfg.ReportJitter
+	}
+}
+
+// applyRetryCFG - sets the configured
+// fields of the retry policy.
+func applyRetryCFG(policy *bizmodels.RetryPolicy,
+	cfg *apimodels.CfgRetry,
+) {
+	if cfg.Initial > 0 {
+		policy.Initial = seconds(cfg.Initial)
+	}
+
+	if cfg.Max > 0 {
+		policy.Max = seconds(cfg.Max)
+	}
+
+	if cfg.MaxElapsed > 0 {
+		policy.MaxElapsed = seconds(cfg.MaxElapsed)
+	}
+
+	if cfg.Multiplier > 0 {
+		policy.Multiplier = cfg.Multiplier
+	}
+
+	if cfg.Jitter > 0 {
+		policy.Jitter = cfg.Jitter
+	}
+
+	if cfg.Attempts > 0 {
+		policy.Attempts = cfg.Attempts
+	}
+}
+
+// applyBreakerCFG - sets the configured
+// fields of the circuit breaker.
+func applyBreakerCFG(failures *int,
+	cooldown *time.Duration,
+	cfg *apimodels.CfgBreaker,
+) {
+	if cfg.Failures != 0 {
+		*failures = cfg.Failures
+	}
+
+	if cfg.Cooldown > 0 {
+		*cooldown = seconds(cfg.Cooldown)
+	}
+}
+
+// setDestinationsFromCFG - sets the destinations
+// configured in the file, their retry policy and
+// circuit breaker are set over the ones of the agent.
+func setDestinationsFromCFG(par *bizmodels.InitParamsAgent,
+	cfg *apimodels.CfgAgent,
+	root string,
+) error {
+	names := make(map[string]struct{}, len(cfg.Destinations))
+
+	for idx := range cfg.Destinations {
+		cfgDest := &cfg.Destinations[idx]
+
+		res, err := validate.IsMatchesTemplate(cfgDest.Name,
+			validDestPattern)
+		if err != nil || !res {
+			return fmt.Errorf("setDestinationsFromCFG: %w: %q",
+				errDestName, cfgDest.Name)
+		}
+
+		if _, ok := names[cfgDest.Name]; ok {
+			return fmt.Errorf("setDestinationsFromCFG: %w: %s",
+				errDestDuplicate, cfgDest.Name)
+		}
+
+		names[cfgDest.Name] = struct{}{}
+
+		dest := &bizmodels.Destination{
+			Name:      cfgDest.Name,
+			UpdateURL: cfgDest.UpdateURL,
+			Key:       cfgDest.Key,
+			GRPC:      grpcFromCFG(&cfgDest.GRPC),
+			Retry:     par.Retry,
+		}
+
+		useGRPC, err := useGRPCTransport(cfgDest.Transport,
+			par.UseGRPC)
+		if err != nil {
+			return fmt.Errorf("setDestinationsFromCFG: %w", err)
+		}
+
+		dest.UseGRPC = useGRPC
+
+		if cfgDest.PORT != "" {
+			dest.URL = "http://" + cfgDest.PORT
+		}
+
+		if cfgDest.CryptoPublicKeyPath != "" {
+			dest.CryptoPublicKeyPath = root +
+				cfgDest.CryptoPublicKeyPath
+		}
+
+		dest.BreakerFailures = par.BreakerFailures
+		dest.BreakerCooldown = par.BreakerCooldown
+
+		applyRetryCFG(&dest.Retry, &cfgDest.Retry)
+		applyBreakerCFG(&dest.BreakerFailures,
+			&dest.BreakerCooldown, &cfgDest.Breaker)
+
+		par.Destinations = append(par.Destinations, dest)
+	}
+
+	return nil
+}
+
+// closeDestinations - closes
+// the grpc connections.
+func closeDestinations(params *bizmodels.InitParamsAgent) {
+	for _, dest := range params.Destinations {
+		if dest.ConnGRPC != nil {
+			dest.ConnGRPC.Close()
+		}
+	}
+}
+
+// grpcFromCFG - returns the grpc
+// connection configured in the file.
+func grpcFromCFG(
+	cfg *apimodels.CfgGRPC,
+) bizmodels.GRPCClient {
+	return bizmodels.GRPCClient{
+		Addr:                cfg.Addr,
+		CAFile:              cfg.CAFile,
+		CertFile:            cfg.CertFile,
+		KeyFile:             cfg.KeyFile,
+		ServerName:          cfg.ServerName,
+		Keepalive:           seconds(cfg.Keepalive),
+		KeepaliveTimeout:    seconds(cfg.KeepaliveTimeout),
+		TLS:                 cfg.TLS,
+		PermitWithoutStream: cfg.PermitWithoutStream,
+	}
+}
+
+// fillGRPC - sets the empty fields
+// of the grpc connection from src,
+// TLS is taken when it is not set.
+func fillGRPC(dst, src *bizmodels.GRPCClient) {
+	if dst.Addr == "" {
+		dst.Addr = src.Addr
+	}
+
+	if dst.CAFile == "" {
+		dst.CAFile = src.CAFile
+	}
+
+	if dst.CertFile == "" && dst.KeyFile == "" {
+		dst.CertFile = src.CertFile
+		dst.KeyFile = src.KeyFile
+	}
+
+	if dst.ServerName == "" {
+		dst.ServerName = src.ServerName
+	}
+
+	if dst.Keepalive == 0 {
+		dst.Keepalive = src.Keepalive
+	}
+
+	if dst.KeepaliveTimeout == 0 {
+		dst.KeepaliveTimeout = src.KeepaliveTimeout
+	}
+
+	if dst.TLS == nil {
+		dst.TLS = src.TLS
+	}
+
+	dst.PermitWithoutStream = dst.PermitWithoutStream ||
+		src.PermitWithoutStream
+}
+
+// useGRPCTransport - tells if the transport
+// is grpc, the empty one is the default.
+func useGRPCTransport(
+	transport string,
+	def bool,
+) (bool, error) {
+	switch transport {
+	case "":
+		return def, nil
+	case transportHTTP:
+		return false, nil
+	case transportGRPC:
+		return true, nil
+	default:
+		return false, fmt.Errorf("useGRPCTransport: %w: %s",
+			errTransport, transport)
+	}
+}
+
+// initDestinations - takes the empty fields of the
+// destinations from the agent, without configured
+// destinations the agent sends to its own address.
+// The outbox of a named destination is kept
+// in the subdirectory of its name. The grpc
+// connections are closed by closeDestinations.
+func initDestinations(
+	params *bizmodels.InitParamsAgent,
+) error {
+	if len(params.Destinations) == 0 {
+		params.Destinations = []*bizmodels.Destination{{
+			UpdateURL:       params.UpdateURL,
+			Retry:           params.Retry,
+			BreakerFailures: params.BreakerFailures,
+			BreakerCooldown: params.BreakerCooldown,
+			UseGRPC:         params.UseGRPC,
+		}}
+	}
+
+	for _, dest := range params.Destinations {
+		if dest.URL == "" {
+			dest.URL = params.URL
+		}
+
+		fillGRPC(&dest.GRPC, &params.GRPC)
+
+		if dest.Key == "" {
+			dest.Key = params.Key
+		}
+
+		if dest.CryptoPublicKeyPath == "" {
+			dest.CryptoPublicKeyPath = params.CryptoPublicKeyPath
+		}
+
+		dest.Agent = &params.Agent
+		dest.Breaker = backoff.NewBreaker(dest.BreakerFailures,
+			dest.BreakerCooldown)
+
+		if dest.UseGRPC {
+			// the metrics are sent as plain protos,
+			// TLS protects them instead of the RSA
+			// encryption of the http body.
+			if !grpcclient.UseTLS(&dest.GRPC) {
+				return fmt.Errorf("initDestinations: %w: %q",
+					errPlainGRPC, dest.Name)
+			}
+
+			conn, err := grpcclient.New(&dest.GRPC)
+			if err != nil {
+				return fmt.Errorf("initDestinations->New: %w", err)
+			}
+
+			dest.ConnGRPC = conn
+			dest.MicroServiceClient = pb.NewMicroServiceClient(conn)
+		}
+
+		if params.OutboxPath == "" {
+			continue
+		}
+
+		box, err := outbox.Open(
+			filepath.Join(params.OutboxPath, dest.Name),
+			params.OutboxSize)
+		if err != nil {
+			return fmt.Errorf("initDestinations->Open: %w", err)
+		}
+
+		dest.Outbox = box
+	}
+
+	return nil
+}
+
+// destNames - returns the names of the
+// destinations, each one drops the
+// aggregated values on its own.
+func destNames(params *bizmodels.InitParamsAgent) []string {
+	names := make([]string, 0, len(params.Destinations))
+
+	for _, dest := range params.Destinations {
+		names = append(names, dest.Name)
+	}
+
+	return names
+}
+
+// seconds - returns the duration of seconds.
+func seconds(secs int) time.Duration {
+	return time.Duration(secs) * time.Second
+}
+
+// initStream - opens the connection
+// for the ingestion stream.
+func initStream(
+	params *bizmodels.InitParamsAgent,
+) (*grpc.ClientConn, error) {
+	if !grpcclient.UseTLS(&params.GRPC) {
+		return nil, fmt.Errorf("initStream: %w", errPlainGRPC)
+	}
+
+	conn, err := grpcclient.New(&params.GRPC)
+	if err != nil {
+		return nil, fmt.Errorf("initStream->New: %w", err)
+	}
+
+	stream, err := streamsenderendpoint.NewStreamSender(
+		pb.NewMicroServiceClient(conn),
+		identity.MD(&params.Agent), params.Key, 0)
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("initStream->NewStream: %w", err)
+	}
+
+	params.Stream = stream
+
+	return conn, nil
+}
+
 func AgentProcess() error {
 	waitGroup := &sync.WaitGroup{}
 	monitor := &bizmodels.Monitor{}
@@ -750,10 +1662,22 @@ func AgentProcess() error {
 		return fmt.Errorf("Initialization: %w", err)
 	}
 
+	defer closeDestinations(params)
+
 	logger.DoInfoLog("Build version: "+buildVersion, zlog)
 	logger.DoInfoLog("Build date: "+buildDate, zlog)
 	logger.DoInfoLog("Build commit: "+buildCommit, zlog)
 
+	if params.UseStream {
+		conn, err := initStream(params)
+		if err != nil {
+			return fmt.Errorf("initStream: %w", err)
+		}
+
+		defer conn.Close()
+		defer params.Stream.Close()
+	}
+
 	jobs := make(chan bizmodels.JobData, params.RateLimit)
 	channelCancel := make(chan os.Signal, 1)
 	channelCancel1 := make(chan os.Signal, 1)
@@ -0,0 +1,200 @@
+package agentimplement_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/agentimplement"
+	"github.com/dmitrovia/collector-metrics/internal/backoff"
+	"github.com/dmitrovia/collector-metrics/internal/handlers/sender"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/decryptmid"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/gzipcompressmiddleware"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const keyBits = 2048
+
+// writeKeys - writes the key pair
+// of the request encryption.
+func writeKeys(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	assert.NoError(t, err)
+
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+
+	privPath := filepath.Join(dir, "private.pem")
+	pubPath := filepath.Join(dir, "public.pem")
+
+	privPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	pubPEM := pem.EncodeToMemory(
+		&pem.Block{Type: "RSA PUBLIC KEY", Bytes: pub})
+
+	assert.NoError(t, os.WriteFile(privPath, privPEM, 0o600))
+	assert.NoError(t, os.WriteFile(pubPath, pubPEM, 0o600))
+
+	return privPath, pubPath
+}
+
+// newServer - starts the server
+// storing the metrics in memory.
+func newServer(
+	t *testing.T,
+	privPath string,
+) (*httptest.Server, *service.DS) {
+	t.Helper()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	params := &bizmodels.InitParams{
+		CryptoPrivateKeyPath: privPath,
+		WaitSecRespDB:        time.Second,
+	}
+	dse := service.NewMemoryService(mem, params.WaitSecRespDB)
+
+	router := mux.NewRouter()
+	updates := router.Methods(http.MethodPost).Subrouter()
+	updates.HandleFunc("/updates/",
+		sender.NewSenderHandler(dse, params).SenderHandler)
+	updates.Use(
+		decryptmid.DecryptMiddleware(*params),
+		gzipcompressmiddleware.GzipMiddleware(
+			params.GetBodyLimits("/updates/")))
+
+	return httptest.NewServer(router), dse
+}
+
+// poll - merges the counter increment.
+func poll(mon *bizmodels.Monitor, delta int64) {
+	sample := bizmodels.NewSample()
+	sample.AddCounter("PollCount", delta)
+	mon.Merge(sample)
+}
+
+func TestSendCounters(t *testing.T) {
+	t.Parallel()
+
+	privPath, pubPath := writeKeys(t, t.TempDir())
+
+	server, dse := newServer(t, privPath)
+	defer server.Close()
+
+	par := &bizmodels.InitParamsAgent{
+		ReportInterval: 1,
+		Destinations: []*bizmodels.Destination{{
+			Breaker:             backoff.NewBreaker(0, 0),
+			URL:                 server.URL,
+			CryptoPublicKeyPath: pubPath,
+		}},
+	}
+
+	mon := &bizmodels.Monitor{}
+	mon.Init()
+	poll(mon, 3)
+
+	chc := make(chan os.Signal, 1)
+	jobs := make(chan bizmodels.JobData, 1)
+	waitGroup := &sync.WaitGroup{}
+	wgEndWork := &sync.WaitGroup{}
+
+	waitGroup.Add(1)
+
+	go agentimplement.Send(&chc, par, waitGroup, wgEndWork,
+		server.Client(), mon, jobs)
+
+	value := func() int64 {
+		count, _ := dse.GetValueCM("PollCount")
+
+		return count
+	}
+
+	assert.Eventually(t, func() bool { return value() == 3 },
+		5*time.Second, 50*time.Millisecond)
+
+	poll(mon, 2)
+
+	assert.Eventually(t, func() bool { return value() == 5 },
+		5*time.Second, 50*time.Millisecond)
+
+	time.Sleep(2 * time.Second)
+	assert.Equal(t, int64(5), value())
+
+	chc <- os.Interrupt
+
+	waitGroup.Wait()
+}
+
+func TestSendDeferred(t *testing.T) {
+	t.Parallel()
+
+	privPath, pubPath := writeKeys(t, t.TempDir())
+
+	server, dse := newServer(t, privPath)
+	defer server.Close()
+
+	dest := &bizmodels.Destination{
+		Breaker:             backoff.NewBreaker(0, 0),
+		URL:                 server.URL,
+		CryptoPublicKeyPath: pubPath,
+	}
+	par := &bizmodels.InitParamsAgent{
+		ReportInterval: 1,
+		Destinations:   []*bizmodels.Destination{dest},
+	}
+
+	mon := &bizmodels.Monitor{}
+	mon.Init()
+	poll(mon, 3)
+
+	// a previous report is still being sent.
+	dest.Sending.Store(true)
+
+	chc := make(chan os.Signal, 1)
+	jobs := make(chan bizmodels.JobData, 1)
+	waitGroup := &sync.WaitGroup{}
+	wgEndWork := &sync.WaitGroup{}
+
+	waitGroup.Add(1)
+
+	go agentimplement.Send(&chc, par, waitGroup, wgEndWork,
+		server.Client(), mon, jobs)
+
+	value := func(name string) int64 {
+		count, _ := dse.GetValueCM(name)
+
+		return count
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Zero(t, value("PollCount"))
+
+	dest.Sending.Store(false)
+
+	assert.Eventually(t, func() bool {
+		return value("PollCount") == 3 &&
+			value("ReportsDeferred") > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	chc <- os.Interrupt
+
+	waitGroup.Wait()
+}
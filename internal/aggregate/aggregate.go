@@ -0,0 +1,312 @@
+// Package aggregate provides the aggregation
+// of the agent gauges polled between reports.
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// Min - smallest polled value.
+const Min = "min"
+
+// Max - largest polled value.
+const Max = "max"
+
+// Avg - mean of the polled values.
+const Avg = "avg"
+
+// Last - last polled value.
+const Last = "last"
+
+// percentilePrefix - prefix of the percentiles,
+// p95 is the 95th percentile.
+const percentilePrefix = "p"
+
+// maxPercentile - largest percentile.
+const maxPercentile = 100
+
+// maxPoints - polled values kept for a gauge,
+// the oldest ones are dropped first.
+const maxPoints = 10000
+
+// Separator - joins the gauge and the
+// aggregation names, the metric id
+// patterns of the server accept it.
+const Separator = "_"
+
+var errFunc = errors.New("unknown aggregation")
+
+var errPattern = errors.New("metric pattern is not valid")
+
+// rule - aggregations of the gauges
+// matching the path.Match pattern.
+type rule struct {
+	pattern string
+	funcs   []string
+}
+
+// point - polled value of a gauge.
+type point struct {
+	time  time.Time
+	value float64
+}
+
+// Aggregator - polled values of the gauges
+// matching the rules. Every consumer drops the
+// values it delivered on its own, a value is
+// kept until all consumers dropped it.
+type Aggregator struct {
+	points map[string][]point
+	funcs  map[string][]string
+	drops  map[string]time.Time
+	rules  []rule
+	mutex  sync.Mutex
+}
+
+// New - creates the aggregator of the rules
+// keyed by the metric name pattern for
+// the consumers of the aggregations.
+func New(rules map[string][]string,
+	consumers []string,
+) (*Aggregator, error) {
+	agg := &Aggregator{
+		points: make(map[string][]point),
+		funcs:  make(map[string][]string),
+		drops:  make(map[string]time.Time, len(consumers)),
+		rules:  make([]rule, 0, len(rules)),
+	}
+
+	for _, consumer := range consumers {
+		agg.drops[consumer] = time.Time{}
+	}
+
+	for pattern, funcs := range rules {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("New: %w: %s",
+				errPattern, pattern)
+		}
+
+		for _, fn := range funcs {
+			if !validFunc(fn) {
+				return nil, fmt.Errorf("New: %w: %s", errFunc, fn)
+			}
+		}
+
+		agg.rules = append(agg.rules,
+			rule{pattern: pattern, funcs: funcs})
+	}
+
+	slices.SortFunc(agg.rules, func(a, b rule) int {
+		return strings.Compare(a.pattern, b.pattern)
+	})
+
+	return agg, nil
+}
+
+// Add - keeps the polled values of the gauges.
+func (a *Aggregator) Add(sample *bizmodels.Sample) {
+	now := time.Now()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for name, value := range sample.Gauges {
+		if len(a.match(name)) == 0 {
+			continue
+		}
+
+		points := append(a.points[name],
+			point{time: now, value: value})
+		if len(points) > maxPoints {
+			points = points[len(points)-maxPoints:]
+		}
+
+		a.points[name] = points
+	}
+}
+
+// Gauges - returns the aggregations of the
+// values polled since the last drop of the
+// consumer until the time sorted by name.
+func (a *Aggregator) Gauges(consumer string,
+	until time.Time,
+) []bizmodels.Gauge {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	since := a.drops[consumer]
+	gauges := make([]bizmodels.Gauge, 0, len(a.points))
+
+	for name, points := range a.points {
+		values := make([]float64, 0, len(points))
+
+		for _, pnt := range points[search(points, since):] {
+			if pnt.time.After(until) {
+				break
+			}
+
+			values = append(values, pnt.value)
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		for _, fn := range a.match(name) {
+			gauges = append(gauges, bizmodels.Gauge{
+				Name:  name + Separator + fn,
+				Value: apply(fn, values),
+			})
+		}
+	}
+
+	slices.SortFunc(gauges, func(a, b bizmodels.Gauge) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return gauges
+}
+
+// Drop - marks the values polled until the
+// time as delivered to the consumer, the
+// values delivered to every consumer
+// are removed.
+func (a *Aggregator) Drop(consumer string,
+	until time.Time,
+) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if until.After(a.drops[consumer]) {
+		a.drops[consumer] = until
+	}
+
+	oldest := until
+
+	for _, dropped := range a.drops {
+		if dropped.Before(oldest) {
+			oldest = dropped
+		}
+	}
+
+	for name, points := range a.points {
+		idx := search(points, oldest)
+
+		if idx == len(points) {
+			delete(a.points, name)
+
+			continue
+		}
+
+		if idx > 0 {
+			a.points[name] = slices.Clone(points[idx:])
+		}
+	}
+}
+
+// search - returns the index of the
+// first value polled after the time.
+func search(points []point, after time.Time) int {
+	idx, _ := slices.BinarySearchFunc(points, after,
+		func(pnt point, after time.Time) int {
+			if pnt.time.After(after) {
+				return 1
+			}
+
+			return -1
+		})
+
+	return idx
+}
+
+// match - returns the aggregations of the gauge.
+func (a *Aggregator) match(name string) []string {
+	funcs, ok := a.funcs[name]
+	if ok {
+		return funcs
+	}
+
+	for _, rul := range a.rules {
+		ok, _ := path.Match(rul.pattern, name)
+		if !ok {
+			continue
+		}
+
+		for _, fn := range rul.funcs {
+			if !slices.Contains(funcs, fn) {
+				funcs = append(funcs, fn)
+			}
+		}
+	}
+
+	a.funcs[name] = funcs
+
+	return funcs
+}
+
+// validFunc - tells if the aggregation is known.
+func validFunc(fn string) bool {
+	switch fn {
+	case Min, Max, Avg, Last:
+		return true
+	}
+
+	_, ok := percentile(fn)
+
+	return ok
+}
+
+// percentile - returns the rank of the percentile.
+func percentile(fn string) (int, bool) {
+	num, ok := strings.CutPrefix(fn, percentilePrefix)
+	if !ok {
+		return 0, false
+	}
+
+	rank, err := strconv.Atoi(num)
+	if err != nil || rank <= 0 || rank > maxPercentile ||
+		strconv.Itoa(rank) != num {
+		return 0, false
+	}
+
+	return rank, true
+}
+
+// apply - aggregates the values.
+func apply(fn string, values []float64) float64 {
+	switch fn {
+	case Min:
+		return slices.Min(values)
+	case Max:
+		return slices.Max(values)
+	case Last:
+		return values[len(values)-1]
+	case Avg:
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+
+		return sum / float64(len(values))
+	}
+
+	rank, _ := percentile(fn)
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	idx := int(math.Ceil(
+		float64(rank)/maxPercentile*float64(len(sorted)))) - 1
+
+	return sorted[max(idx, 0)]
+}
@@ -0,0 +1,111 @@
+package aggregate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/aggregate"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregator(t *testing.T) {
+	t.Parallel()
+
+	agg, err := aggregate.New(map[string][]string{
+		"HeapAlloc": {"min", "max", "avg"},
+		"Heap*":     {"max", "last", "p50", "p100"},
+	}, []string{""})
+	assert.NoError(t, err)
+
+	for _, value := range []float64{4, 1, 3, 2} {
+		sample := bizmodels.NewSample()
+		sample.SetGauge("HeapAlloc", value)
+		sample.SetGauge("Sys", value)
+		agg.Add(sample)
+	}
+
+	until := time.Now()
+
+	assert.Equal(t, []bizmodels.Gauge{
+		{Name: "HeapAlloc_avg", Value: 2.5},
+		{Name: "HeapAlloc_last", Value: 2},
+		{Name: "HeapAlloc_max", Value: 4},
+		{Name: "HeapAlloc_min", Value: 1},
+		{Name: "HeapAlloc_p100", Value: 4},
+		{Name: "HeapAlloc_p50", Value: 2},
+	}, agg.Gauges("", until))
+
+	time.Sleep(time.Millisecond)
+
+	sample := bizmodels.NewSample()
+	sample.SetGauge("HeapIdle", 7)
+	agg.Add(sample)
+
+	assert.Len(t, agg.Gauges("", until), 6)
+
+	agg.Drop("", until)
+
+	assert.Equal(t, []bizmodels.Gauge{
+		{Name: "HeapIdle_last", Value: 7},
+		{Name: "HeapIdle_max", Value: 7},
+		{Name: "HeapIdle_p100", Value: 7},
+		{Name: "HeapIdle_p50", Value: 7},
+	}, agg.Gauges("", time.Now()))
+}
+
+func TestAggregatorConsumers(t *testing.T) {
+	t.Parallel()
+
+	agg, err := aggregate.New(map[string][]string{
+		"HeapAlloc": {"max"},
+	}, []string{"main", "backup"})
+	assert.NoError(t, err)
+
+	add := func(value float64) time.Time {
+		sample := bizmodels.NewSample()
+		sample.SetGauge("HeapAlloc", value)
+		agg.Add(sample)
+
+		time.Sleep(time.Millisecond)
+
+		return time.Now()
+	}
+
+	first := add(9)
+
+	// only main delivered the first report
+	agg.Drop("main", first)
+
+	second := add(1)
+
+	assert.Equal(t, []bizmodels.Gauge{
+		{Name: "HeapAlloc_max", Value: 1},
+	}, agg.Gauges("main", second))
+	assert.Equal(t, []bizmodels.Gauge{
+		{Name: "HeapAlloc_max", Value: 9},
+	}, agg.Gauges("backup", second))
+
+	agg.Drop("backup", second)
+
+	assert.Equal(t, []bizmodels.Gauge{
+		{Name: "HeapAlloc_max", Value: 1},
+	}, agg.Gauges("main", second))
+	assert.Empty(t, agg.Gauges("backup", second))
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	for _, funcs := range [][]string{
+		{"sum"}, {"p0"}, {"p101"}, {"p9.5"}, {"p05"},
+	} {
+		_, err := aggregate.New(
+			map[string][]string{"HeapAlloc": funcs}, nil)
+		assert.Error(t, err, funcs)
+	}
+
+	_, err := aggregate.New(
+		map[string][]string{"[": {"max"}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+// Package backoff provides the retry delays
+// of the agent requests and the circuit
+// breaker stopping the requests
+// to a failing server.
+package backoff
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/random"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// ErrOpen - returned while the
+// breaker rejects the requests.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Delay - returns the jittered delay before
+// the retry that follows the failed attempt,
+// attempts are counted from zero.
+func Delay(
+	policy bizmodels.RetryPolicy,
+	attempt int,
+) time.Duration {
+	delay := float64(policy.Initial) *
+		math.Pow(max(policy.Multiplier, 1), float64(attempt))
+
+	if policy.Max > 0 {
+		delay = math.Min(delay, float64(policy.Max))
+	}
+
+	return Jitter(time.Duration(delay), policy.Jitter)
+}
+
+// Jitter - spreads the duration randomly
+// by the fraction in both directions.
+func Jitter(
+	dur time.Duration,
+	fraction float64,
+) time.Duration {
+	if fraction <= 0 || dur <= 0 {
+		return dur
+	}
+
+	value, err := random.RandF64(1)
+	if err != nil {
+		return dur
+	}
+
+	fraction = math.Min(fraction, 1)
+
+	return time.Duration(
+		float64(dur) * (1 - fraction + 2*fraction*value))
+}
+
+// Breaker - opens after the consecutive
+// failures and rejects the requests for
+// the cooldown, then lets a single probe
+// through, a nil breaker never opens.
+type Breaker struct {
+	openUntil time.Time
+	now       func() time.Time
+	cooldown  time.Duration
+	failures  int
+	threshold int
+	probing   bool
+	mutex     sync.Mutex
+}
+
+// NewBreaker - creates the breaker, returns
+// nil when the threshold is not positive.
+func NewBreaker(
+	threshold int,
+	cooldown time.Duration,
+) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &Breaker{
+		now:       time.Now,
+		cooldown:  cooldown,
+		threshold: threshold,
+	}
+}
+
+// Allow - returns ErrOpen when
+// the request must not be sent.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrOpen
+	}
+
+	b.probing = true
+
+	return nil
+}
+
+// Success - closes the breaker.
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure - counts the failed request,
+// opens the breaker at the threshold
+// and after a failed probe.
+func (b *Breaker) Failure() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,71 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/backoff"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	t.Parallel()
+
+	policy := bizmodels.RetryPolicy{
+		Initial:    time.Second,
+		Max:        5 * time.Second,
+		Multiplier: 2,
+	}
+
+	delays := []time.Duration{
+		time.Second, 2 * time.Second,
+		4 * time.Second, 5 * time.Second,
+	}
+
+	for attempt, delay := range delays {
+		assert.Equal(t, delay, backoff.Delay(policy, attempt))
+	}
+
+	policy.Jitter = 0.5
+
+	for attempt := range 10 {
+		delay := backoff.Delay(policy, attempt)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 7500*time.Millisecond)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	const cooldown = 20 * time.Millisecond
+
+	brk := backoff.NewBreaker(2, cooldown)
+
+	brk.Failure()
+	assert.NoError(t, brk.Allow())
+
+	brk.Failure()
+	assert.ErrorIs(t, brk.Allow(), backoff.ErrOpen)
+
+	time.Sleep(cooldown)
+	assert.NoError(t, brk.Allow())
+	assert.ErrorIs(t, brk.Allow(), backoff.ErrOpen)
+
+	brk.Failure()
+	assert.ErrorIs(t, brk.Allow(), backoff.ErrOpen)
+
+	time.Sleep(cooldown)
+	assert.NoError(t, brk.Allow())
+
+	brk.Success()
+	assert.NoError(t, brk.Allow())
+	assert.NoError(t, brk.Allow())
+
+	var disabled *backoff.Breaker
+
+	disabled.Failure()
+	assert.NoError(t, disabled.Allow())
+	assert.Nil(t, backoff.NewBreaker(0, cooldown))
+}
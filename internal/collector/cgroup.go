@@ -0,0 +1,308 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// defCgroupRoot - mount point of cgroup v2.
+const defCgroupRoot = "/sys/fs/cgroup"
+
+// cgroupUnlimited - value of a limit not set.
+const cgroupUnlimited = "max"
+
+var errNotCgroup2 = errors.New("not a cgroup v2 directory")
+
+// cgroupOptions - options of the cgroup
+// collector, Root is the cgroup directory
+// of the container.
+type cgroupOptions struct {
+	Root string `json:"root"`
+}
+
+// cgroupCollector - reads the resource usage
+// and the limits of the container cgroup.
+type cgroupCollector struct {
+	lastPoll  time.Time
+	counters  *deltas
+	opts      cgroupOptions
+	lastUsage uint64
+	mutex     sync.Mutex
+}
+
+// NewCgroup - creates the cgroup collector.
+func NewCgroup(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &cgroupCollector{
+		counters: newDeltas(),
+		opts:     cgroupOptions{Root: defCgroupRoot},
+	}
+
+	err := decodeOptions(options, &col.opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewCgroup->decode: %w", err)
+	}
+
+	return col, nil
+}
+
+// Collect - reads the CgroupMemoryCurrent,
+// CgroupMemoryMax, CgroupPidsCurrent and
+// CgroupPidsMax gauges, the cpu usage and
+// throttling counters of cpu.stat with the
+// CgroupCPUUtilization and CgroupCPULimit
+// gauges in percent of one cpu, and the io
+// counters of every device, e.g.
+// CgroupIOReadBytes/8/0. The limits not set
+// and the disabled controllers are skipped.
+func (c *cgroupCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, err := os.Stat(c.path("cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("Collect: %w: %s", errNotCgroup2,
+			c.opts.Root)
+	}
+
+	for _, read := range []func(*bizmodels.Sample) error{
+		c.readMemory, c.readPids, c.readCPU, c.readIO,
+	} {
+		err := read(sample)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("Collect->read: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readMemory - reads the memory usage and limit.
+func (c *cgroupCollector) readMemory(
+	sample *bizmodels.Sample,
+) error {
+	return c.readCurrentMax(sample, "memory", "CgroupMemory")
+}
+
+// readPids - reads the number of tasks and limit.
+func (c *cgroupCollector) readPids(
+	sample *bizmodels.Sample,
+) error {
+	return c.readCurrentMax(sample, "pids", "CgroupPids")
+}
+
+// readCurrentMax - reads the current
+// and max files of the controller.
+func (c *cgroupCollector) readCurrentMax(
+	sample *bizmodels.Sample,
+	controller string,
+	metric string,
+) error {
+	current, err := c.readValue(controller + ".current")
+	if err != nil {
+		return err
+	}
+
+	sample.SetGauge(metric+"Current", float64(current))
+
+	limit, err := c.readValue(controller + ".max")
+	if errors.Is(err, strconv.ErrRange) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	sample.SetGauge(metric+"Max", float64(limit))
+
+	return nil
+}
+
+// readCPU - reads the cpu usage,
+// throttling and the quota.
+func (c *cgroupCollector) readCPU(
+	sample *bizmodels.Sample,
+) error {
+	stat, err := c.readKeyValues("cpu.stat")
+	if err != nil {
+		return err
+	}
+
+	for key, metric := range map[string]string{
+		"usage_usec":     "CgroupCPUUsageUsec",
+		"user_usec":      "CgroupCPUUserUsec",
+		"system_usec":    "CgroupCPUSystemUsec",
+		"nr_periods":     "CgroupCPUPeriods",
+		"nr_throttled":   "CgroupCPUThrottled",
+		"throttled_usec": "CgroupCPUThrottledUsec",
+	} {
+		if value, ok := stat[key]; ok {
+			c.counters.add(sample, metric, value)
+		}
+	}
+
+	now := time.Now()
+	usage := stat["usage_usec"]
+
+	if !c.lastPoll.IsZero() && usage >= c.lastUsage {
+		elapsed := now.Sub(c.lastPoll).Microseconds()
+		if elapsed > 0 {
+			sample.SetGauge("CgroupCPUUtilization",
+				float64(usage-c.lastUsage)/float64(elapsed)*percent)
+		}
+	}
+
+	c.lastPoll = now
+	c.lastUsage = usage
+
+	return c.readCPULimit(sample)
+}
+
+// readCPULimit - reads the cpu quota
+// of the cgroup in percent of one cpu.
+func (c *cgroupCollector) readCPULimit(
+	sample *bizmodels.Sample,
+) error {
+	data, err := os.ReadFile(c.path("cpu.max"))
+	if err != nil {
+		return fmt.Errorf("readCPULimit->ReadFile: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] == cgroupUnlimited {
+		return nil
+	}
+
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return fmt.Errorf("readCPULimit->ParseFloat: %w", err)
+	}
+
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return fmt.Errorf("readCPULimit->ParseFloat: %w", err)
+	}
+
+	if period <= 0 {
+		return nil
+	}
+
+	sample.SetGauge("CgroupCPULimit", quota/period*percent)
+
+	return nil
+}
+
+// readIO - reads the io of every device.
+func (c *cgroupCollector) readIO(
+	sample *bizmodels.Sample,
+) error {
+	data, err := os.ReadFile(c.path("io.stat"))
+	if err != nil {
+		return fmt.Errorf("readIO->ReadFile: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		stat := parseKeyValues(fields[1:], "=")
+
+		for key, metric := range map[string]string{
+			"rbytes": "CgroupIOReadBytes",
+			"wbytes": "CgroupIOWriteBytes",
+			"rios":   "CgroupIOReads",
+			"wios":   "CgroupIOWrites",
+		} {
+			if value, ok := stat[key]; ok {
+				c.counters.add(sample,
+					seriesName(metric, fields[0]), value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// readValue - reads the number of the
+// file, an unset limit is out of range.
+func (c *cgroupCollector) readValue(
+	name string,
+) (uint64, error) {
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return 0, fmt.Errorf("readValue->ReadFile: %w", err)
+	}
+
+	text := strings.TrimSpace(string(data))
+	if text == cgroupUnlimited {
+		return 0, fmt.Errorf("readValue: %w", strconv.ErrRange)
+	}
+
+	value, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("readValue->ParseUint: %w", err)
+	}
+
+	return value, nil
+}
+
+// readKeyValues - reads the
+// "key value" lines of the file.
+func (c *cgroupCollector) readKeyValues(
+	name string,
+) (map[string]uint64, error) {
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("readKeyValues->ReadFile: %w", err)
+	}
+
+	return parseKeyValues(
+		strings.Split(string(data), "\n"), " "), nil
+}
+
+// path - returns the path of the cgroup file.
+func (c *cgroupCollector) path(name string) string {
+	return filepath.Join(c.opts.Root, name)
+}
+
+// parseKeyValues - parses the pairs split
+// by the separator, invalid ones are skipped.
+func parseKeyValues(
+	pairs []string,
+	sep string,
+) map[string]uint64 {
+	res := make(map[string]uint64, len(pairs))
+
+	for _, pair := range pairs {
+		key, text, ok := strings.Cut(strings.TrimSpace(pair), sep)
+		if !ok {
+			continue
+		}
+
+		value, err := strconv.ParseUint(text, 10, 64)
+		if err == nil {
+			res[key] = value
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,96 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCgroup(
+	t *testing.T,
+	root string,
+	files map[string]string,
+) {
+	t.Helper()
+
+	for name, data := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(root, name),
+			[]byte(data), 0o600))
+	}
+}
+
+func TestCgroup(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeCgroup(t, root, map[string]string{
+		"cgroup.controllers": "cpu io memory pids\n",
+		"memory.current":     "1048576\n",
+		"memory.max":         "4194304\n",
+		"pids.current":       "7\n",
+		"pids.max":           "max\n",
+		"cpu.max":            "50000 100000\n",
+		"cpu.stat": "usage_usec 1000\nuser_usec 600\n" +
+			"system_usec 400\nnr_periods 10\n" +
+			"nr_throttled 2\nthrottled_usec 300\n",
+		"io.stat": "8:0 rbytes=4096 wbytes=8192 rios=1 " +
+			"wios=2 dbytes=0 dios=0\n",
+	})
+
+	options, err := json.Marshal(
+		map[string]string{"root": root})
+	assert.NoError(t, err)
+
+	col, err := collector.NewCgroup(options)
+	assert.NoError(t, err)
+
+	sample := bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+
+	gau, cnt := sample.Gauges, sample.Counters
+	assert.InDelta(t, 1048576, gau["CgroupMemoryCurrent"], 0)
+	assert.InDelta(t, 4194304, gau["CgroupMemoryMax"], 0)
+	assert.InDelta(t, 7, gau["CgroupPidsCurrent"], 0)
+	assert.NotContains(t, gau, "CgroupPidsMax")
+	assert.InDelta(t, 50, gau["CgroupCPULimit"], 0)
+	assert.NotContains(t, gau, "CgroupCPUUtilization")
+	assert.Contains(t, cnt, "CgroupIOReadBytes/8/0")
+	assert.Zero(t, cnt["CgroupCPUThrottled"])
+
+	writeCgroup(t, root, map[string]string{
+		"cpu.max": "max 100000\n",
+		"cpu.stat": "usage_usec 3000\nuser_usec 1600\n" +
+			"system_usec 1400\nnr_periods 20\n" +
+			"nr_throttled 5\nthrottled_usec 900\n",
+		"io.stat": "8:0 rbytes=6144 wbytes=8192 rios=3 wios=2\n",
+	})
+	assert.NoError(t,
+		os.Remove(filepath.Join(root, "pids.current")))
+
+	sample = bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+
+	gau, cnt = sample.Gauges, sample.Counters
+	assert.Equal(t, int64(2000), cnt["CgroupCPUUsageUsec"])
+	assert.Equal(t, int64(3), cnt["CgroupCPUThrottled"])
+	assert.Equal(t, int64(600),
+		cnt["CgroupCPUThrottledUsec"])
+	assert.Equal(t, int64(2048), cnt["CgroupIOReadBytes/8/0"])
+	assert.Zero(t, cnt["CgroupIOWriteBytes/8/0"])
+	assert.Contains(t, gau, "CgroupCPUUtilization")
+	assert.NotContains(t, gau, "CgroupCPULimit")
+	assert.NotContains(t, gau, "CgroupPidsCurrent")
+
+	options, err = json.Marshal(
+		map[string]string{"root": t.TempDir()})
+	assert.NoError(t, err)
+
+	col, err = collector.NewCgroup(options)
+	assert.NoError(t, err)
+	assert.Error(t, col.Collect(bizmodels.NewSample()))
+}
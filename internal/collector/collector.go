@@ -0,0 +1,247 @@
+// Package collector provides the registry
+// of the agent collectors and the built-in
+// collectors of the runtime and host metrics.
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// Runtime - name of the go runtime collector.
+const Runtime = "runtime"
+
+// Memory - name of the host memory collector.
+const Memory = "memory"
+
+// CPU - name of the host cpu collector.
+const CPU = "cpu"
+
+// Disk - name of the filesystem
+// and disk io collector.
+const Disk = "disk"
+
+// Net - name of the network collector.
+const Net = "net"
+
+// Process - name of the process collector.
+const Process = "process"
+
+// Cgroup - name of the container collector.
+const Cgroup = "cgroup"
+
+// Exec - name of the external command collector.
+const Exec = "exec"
+
+// LogTail - name of the log file collector.
+const LogTail = "logtail"
+
+var errUnknown = errors.New("unknown collector")
+
+// Factory - creates the collector
+// from its options, the options are
+// nil when none are configured.
+type Factory func(
+	options json.RawMessage,
+) (bizmodels.Collector, error)
+
+// entry - registered collector.
+type entry struct {
+	factory Factory
+	enabled bool
+}
+
+// Registry - known collectors
+// in the order of registration.
+type Registry struct {
+	entries map[string]entry
+	names   []string
+}
+
+// NewRegistry - creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: make(map[string]entry),
+		names:   make([]string, 0),
+	}
+}
+
+// Default - returns the registry
+// of the built-in collectors.
+func Default() *Registry {
+	reg := NewRegistry()
+	reg.Register(Runtime, NewRuntime, true)
+	reg.Register(Memory, NewMemory, true)
+	reg.Register(CPU, NewCPU, true)
+	reg.Register(Disk, NewDisk, true)
+	reg.Register(Net, NewNet, true)
+	reg.Register(Process, NewProcess, false)
+	reg.Register(Cgroup, NewCgroup, false)
+	reg.Register(Exec, NewExec, false)
+	reg.Register(LogTail, NewLogTail, false)
+
+	return reg
+}
+
+// Register - adds the collector, enabled
+// tells if it runs when not configured.
+func (r *Registry) Register(
+	name string,
+	factory Factory,
+	enabled bool,
+) {
+	if _, ok := r.entries[name]; !ok {
+		r.names = append(r.names, name)
+	}
+
+	r.entries[name] = entry{factory: factory, enabled: enabled}
+}
+
+// Build - creates the enabled collectors,
+// the interval is used for the collectors
+// without their own poll interval.
+func (r *Registry) Build(
+	cfg map[string]bizmodels.CollectorConfig,
+	interval int,
+) ([]bizmodels.PolledCollector, error) {
+	for name := range cfg {
+		if _, ok := r.entries[name]; !ok {
+			return nil, fmt.Errorf("Build: %w: %s", errUnknown, name)
+		}
+	}
+
+	res := make([]bizmodels.PolledCollector, 0, len(r.names))
+
+	for _, name := range r.names {
+		ent := r.entries[name]
+		conf := cfg[name]
+
+		enabled := ent.enabled
+		if conf.Enabled != nil {
+			enabled = *conf.Enabled
+		}
+
+		if !enabled {
+			continue
+		}
+
+		col, err := ent.factory(conf.Options)
+		if err != nil {
+			return nil, fmt.Errorf("Build->%s: %w", name, err)
+		}
+
+		polled := bizmodels.PolledCollector{
+			Collector: col,
+			Name:      name,
+			Interval:  conf.PollInterval,
+		}
+
+		if polled.Interval <= 0 {
+			polled.Interval = interval
+		}
+
+		res = append(res, polled)
+	}
+
+	return res, nil
+}
+
+// filter - include and exclude name
+// patterns of path.Match, an empty
+// include list matches every name.
+type filter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// match - tells if the name passes the filter.
+func (f *filter) match(name string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+
+	return !matchAny(f.Exclude, name)
+}
+
+// matchAny - tells if a pattern matches the name.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, name)
+		if err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// seriesName - joins the metric and the
+// object it describes with a slash, the
+// characters not allowed by the default
+// id pattern of the server become slashes.
+func seriesName(metric, object string) string {
+	clean := strings.Map(func(char rune) rune {
+		if char < utf8.RuneSelf &&
+			(unicode.IsLetter(char) || unicode.IsDigit(char)) {
+			return char
+		}
+
+		return '/'
+	}, object)
+
+	return metric + "/" + strings.TrimLeft(clean, "/")
+}
+
+// deltas - turns cumulative readings
+// into the increments of the counters.
+type deltas struct {
+	prev map[string]uint64
+}
+
+// newDeltas - creates empty readings.
+func newDeltas() *deltas {
+	return &deltas{prev: make(map[string]uint64)}
+}
+
+// add - adds the increment since the previous
+// reading to the counter, the first reading
+// adds zero and a reset counts from zero.
+func (d *deltas) add(
+	sample *bizmodels.Sample,
+	name string,
+	value uint64,
+) {
+	prev, ok := d.prev[name]
+	d.prev[name] = value
+
+	switch {
+	case !ok:
+		sample.AddCounter(name, 0)
+	case value < prev:
+		sample.AddCounter(name, int64(value))
+	default:
+		sample.AddCounter(name, int64(value-prev))
+	}
+}
+
+// decodeOptions - decodes the
+// options over the defaults.
+func decodeOptions(options json.RawMessage, dst any) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(options, dst)
+	if err != nil {
+		return fmt.Errorf("decodeOptions->Unmarshal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+var errOptions = errors.New("options")
+
+type fixed struct{ value float64 }
+
+func (f *fixed) Collect(sample *bizmodels.Sample) error {
+	sample.SetGauge("Fixed", f.value)
+	sample.AddCounter("Polls", 1)
+
+	return nil
+}
+
+func newFixed(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &fixed{}
+
+	if options != nil {
+		err := json.Unmarshal(options, &col.value)
+		if err != nil {
+			return nil, errOptions
+		}
+	}
+
+	return col, nil
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	enabled, disabled := true, false
+
+	reg := collector.NewRegistry()
+	reg.Register("a", newFixed, true)
+	reg.Register("b", newFixed, false)
+	reg.Register("c", newFixed, true)
+
+	cfg := map[string]bizmodels.CollectorConfig{
+		"a": {Enabled: &disabled},
+		"b": {
+			Enabled:      &enabled,
+			PollInterval: 5,
+			Options:      json.RawMessage("3"),
+		},
+	}
+
+	cols, err := reg.Build(cfg, 2)
+	assert.NoError(t, err)
+	assert.Len(t, cols, 2)
+	assert.Equal(t, "b", cols[0].Name)
+	assert.Equal(t, 5, cols[0].Interval)
+	assert.Equal(t, "c", cols[1].Name)
+	assert.Equal(t, 2, cols[1].Interval)
+
+	mon := &bizmodels.Monitor{}
+	mon.Init()
+
+	for range 2 {
+		sample := bizmodels.NewSample()
+		assert.NoError(t, cols[0].Collector.Collect(sample))
+		mon.Merge(sample)
+	}
+
+	gauges, counters := mon.Snapshot()
+	assert.Equal(t,
+		[]bizmodels.Gauge{{Name: "Fixed", Value: 3}}, gauges)
+	assert.Equal(t, int64(2), counters["Polls"].Value)
+
+	_, err = reg.Build(map[string]bizmodels.CollectorConfig{
+		"d": {},
+	}, 2)
+	assert.Error(t, err)
+
+	_, err = reg.Build(map[string]bizmodels.CollectorConfig{
+		"c": {Options: json.RawMessage(`"x"`)},
+	}, 2)
+	assert.ErrorIs(t, err, errOptions)
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	cols, err := collector.Default().Build(nil, 2)
+	assert.NoError(t, err)
+
+	sample := bizmodels.NewSample()
+
+	for _, col := range cols {
+		assert.NoError(t, col.Collector.Collect(sample))
+	}
+
+	assert.Contains(t, sample.Gauges, "HeapAlloc")
+	assert.Contains(t, sample.Gauges, "TotalMemory")
+	assert.Equal(t, int64(1), sample.Counters["PollCount"])
+}
@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/load"
+)
+
+// percent - scale of the utilization.
+const percent = 100
+
+// cpuCollector - computes the utilization
+// of the host cpus from the difference of
+// their times since the previous poll.
+type cpuCollector struct {
+	cores []cpu.TimesStat
+	total []cpu.TimesStat
+	mutex sync.Mutex
+}
+
+// NewCPU - creates the cpu collector.
+func NewCPU(
+	_ json.RawMessage,
+) (bizmodels.Collector, error) {
+	return &cpuCollector{}, nil
+}
+
+// Collect - reads the total utilization with the
+// user, system and iowait parts as CPUutilization,
+// CPUuser, CPUsystem and CPUiowait, the utilization
+// of every core as CPUutilization1..N and the
+// LoadAverage1, 5 and 15. The utilization is
+// reported from the second poll.
+func (c *cpuCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	avg, err := load.Avg()
+	if err != nil {
+		return fmt.Errorf("Collect->Avg: %w", err)
+	}
+
+	sample.SetGauge("LoadAverage1", avg.Load1)
+	sample.SetGauge("LoadAverage5", avg.Load5)
+	sample.SetGauge("LoadAverage15", avg.Load15)
+
+	total, err := cpu.Times(false)
+	if err != nil {
+		return fmt.Errorf("Collect->Times: %w", err)
+	}
+
+	cores, err := cpu.Times(true)
+	if err != nil {
+		return fmt.Errorf("Collect->Times: %w", err)
+	}
+
+	if len(c.total) == len(total) && len(total) > 0 {
+		busy, user, system, iowait := usage(
+			&c.total[0], &total[0])
+		sample.SetGauge("CPUutilization", busy)
+		sample.SetGauge("CPUuser", user)
+		sample.SetGauge("CPUsystem", system)
+		sample.SetGauge("CPUiowait", iowait)
+	}
+
+	if len(c.cores) == len(cores) {
+		for i := range cores {
+			busy, _, _, _ := usage(&c.cores[i], &cores[i])
+			sample.SetGauge("CPUutilization"+strconv.Itoa(i+1), busy)
+		}
+	}
+
+	c.total = total
+	c.cores = cores
+
+	return nil
+}
+
+// usage - returns the busy, user, system
+// and iowait percent of the cpu time
+// passed between the two readings.
+func usage(prev, cur *cpu.TimesStat) (
+	float64, float64, float64, float64,
+) {
+	all := cpuTotal(cur) - cpuTotal(prev)
+	if all <= 0 {
+		return 0, 0, 0, 0
+	}
+
+	idle := (cur.Idle - prev.Idle) + (cur.Iowait - prev.Iowait)
+	share := func(value float64) float64 {
+		return min(percent, max(0, value/all*percent))
+	}
+
+	return share(all - idle),
+		share(cur.User - prev.User),
+		share(cur.System - prev.System),
+		share(cur.Iowait - prev.Iowait)
+}
+
+// cpuTotal - returns the whole cpu time,
+// guest time is already counted as user.
+func cpuTotal(times *cpu.TimesStat) float64 {
+	return times.User + times.System + times.Idle +
+		times.Nice + times.Iowait + times.Irq +
+		times.Softirq + times.Steal
+}
@@ -0,0 +1,35 @@
+package collector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPU(t *testing.T) {
+	t.Parallel()
+
+	col, err := collector.NewCPU(nil)
+	assert.NoError(t, err)
+
+	sample := bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Contains(t, sample.Gauges, "LoadAverage15")
+	assert.NotContains(t, sample.Gauges, "CPUutilization")
+
+	time.Sleep(50 * time.Millisecond)
+
+	sample = bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+
+	for _, name := range []string{
+		"CPUutilization", "CPUutilization1", "CPUuser",
+	} {
+		assert.Contains(t, sample.Gauges, name)
+		assert.GreaterOrEqual(t, sample.Gauges[name], 0.0)
+		assert.LessOrEqual(t, sample.Gauges[name], 100.0)
+	}
+}
@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// diskOptions - options of the disk collector,
+// All reads the virtual filesystems as well.
+type diskOptions struct {
+	FsTypes filter `json:"fsTypes"`
+	Mounts  filter `json:"mounts"`
+	Devices filter `json:"devices"`
+	All     bool   `json:"all"`
+}
+
+// diskCollector - reads the usage of the
+// mounted filesystems and the io of the disks.
+type diskCollector struct {
+	io    *deltas
+	opts  diskOptions
+	mutex sync.Mutex
+}
+
+// NewDisk - creates the disk collector,
+// loop and ram devices are skipped
+// unless the devices are configured.
+func NewDisk(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &diskCollector{
+		io: newDeltas(),
+		opts: diskOptions{
+			Devices: filter{Exclude: []string{"loop*", "ram*"}},
+		},
+	}
+
+	err := decodeOptions(options, &col.opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewDisk->decodeOptions: %w", err)
+	}
+
+	return col, nil
+}
+
+// Collect - reads DiskTotal, DiskFree, DiskUsed,
+// DiskUsedPercent, InodesTotal, InodesFree and
+// InodesUsedPercent gauges of every mount point,
+// e.g. DiskFree/home, and DiskReadBytes,
+// DiskWriteBytes, DiskReads, DiskWrites and
+// DiskIOTime counters of every device.
+func (c *diskCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.collectUsage(sample)
+	if err != nil {
+		return fmt.Errorf("Collect->collectUsage: %w", err)
+	}
+
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return fmt.Errorf("Collect->IOCounters: %w", err)
+	}
+
+	for name, dev := range counters {
+		if !c.opts.Devices.match(name) {
+			continue
+		}
+
+		c.io.add(sample, seriesName("DiskReadBytes", name),
+			dev.ReadBytes)
+		c.io.add(sample, seriesName("DiskWriteBytes", name),
+			dev.WriteBytes)
+		c.io.add(sample, seriesName("DiskReads", name),
+			dev.ReadCount)
+		c.io.add(sample, seriesName("DiskWrites", name),
+			dev.WriteCount)
+		c.io.add(sample, seriesName("DiskIOTime", name),
+			dev.IoTime)
+	}
+
+	return nil
+}
+
+// collectUsage - reads the usage
+// of the filtered mount points.
+func (c *diskCollector) collectUsage(
+	sample *bizmodels.Sample,
+) error {
+	parts, err := disk.Partitions(c.opts.All)
+	if err != nil {
+		return fmt.Errorf("collectUsage->Partitions: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(parts))
+
+	for _, part := range parts {
+		if _, ok := seen[part.Mountpoint]; ok ||
+			!c.opts.FsTypes.match(part.Fstype) ||
+			!c.opts.Mounts.match(part.Mountpoint) {
+			continue
+		}
+
+		seen[part.Mountpoint] = struct{}{}
+
+		usage, err := disk.Usage(part.Mountpoint)
+		if err != nil {
+			fmt.Println("collectUsage->Usage: %w", err)
+
+			continue
+		}
+
+		mount := part.Mountpoint
+		sample.SetGauge(seriesName("DiskTotal", mount),
+			float64(usage.Total))
+		sample.SetGauge(seriesName("DiskFree", mount),
+			float64(usage.Free))
+		sample.SetGauge(seriesName("DiskUsed", mount),
+			float64(usage.Used))
+		sample.SetGauge(seriesName("DiskUsedPercent", mount),
+			usage.UsedPercent)
+		sample.SetGauge(seriesName("InodesTotal", mount),
+			float64(usage.InodesTotal))
+		sample.SetGauge(seriesName("InodesFree", mount),
+			float64(usage.InodesFree))
+		sample.SetGauge(seriesName("InodesUsedPercent", mount),
+			usage.InodesUsedPercent)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisk(t *testing.T) {
+	t.Parallel()
+
+	col, err := collector.NewDisk(json.RawMessage(
+		`{"all": true, "mounts": {"include": ["/"]}}`))
+	assert.NoError(t, err)
+
+	sample := bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Contains(t, sample.Gauges, "DiskTotal/")
+	assert.Contains(t, sample.Gauges, "InodesUsedPercent/")
+
+	for name, delta := range sample.Counters {
+		assert.True(t, strings.HasPrefix(name, "Disk"))
+		assert.NotContains(t, name, "loop")
+		assert.Zero(t, delta)
+	}
+
+	col, err = collector.NewDisk(json.RawMessage(
+		`{"all": true, "fsTypes": {"exclude": ["*"]},
+		"devices": {"exclude": ["*"]}}`))
+	assert.NoError(t, err)
+
+	sample = bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Empty(t, sample.Gauges)
+	assert.Empty(t, sample.Counters)
+
+	_, err = collector.NewDisk(json.RawMessage(`{"all": 1}`))
+	assert.Error(t, err)
+}
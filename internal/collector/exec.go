@@ -0,0 +1,323 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// FormatLine - every output line is
+// "[gauge|counter] name value".
+const FormatLine = "line"
+
+// FormatJSON - the output is a metric
+// or an array of metrics of the api.
+const FormatJSON = "json"
+
+// defExecTimeout - run time of a command.
+const defExecTimeout = 10 * time.Second
+
+// waitDelay - how long the output of a
+// killed command is waited for.
+const waitDelay = time.Second
+
+// maxExecOutput - the longer output
+// of a command is rejected.
+const maxExecOutput = 1 << 20
+
+var errCommand = errors.New(
+	"command needs a name, a path and a known format")
+
+var errBusy = errors.New("previous run is in progress")
+
+var errOutput = errors.New("output too large")
+
+var errLine = errors.New("invalid line")
+
+// execCommand - external command,
+// Name labels its error counters.
+type execCommand struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Format  string   `json:"format"`
+	Timeout string   `json:"timeout"`
+	Args    []string `json:"args"`
+	timeout time.Duration
+}
+
+// execOptions - options of the exec collector.
+type execOptions struct {
+	Commands []execCommand `json:"commands"`
+}
+
+// execCollector - runs the commands
+// and reads the metrics they print.
+type execCollector struct {
+	opts  execOptions
+	mutex sync.Mutex
+}
+
+// NewExec - creates the exec collector.
+func NewExec(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &execCollector{}
+
+	err := decodeOptions(options, &col.opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewExec->decode: %w", err)
+	}
+
+	for i := range col.opts.Commands {
+		cmd := &col.opts.Commands[i]
+
+		if cmd.Format == "" {
+			cmd.Format = FormatLine
+		}
+
+		if cmd.Name == "" || cmd.Command == "" ||
+			(cmd.Format != FormatLine && cmd.Format != FormatJSON) {
+			return nil, fmt.Errorf("NewExec: %w", errCommand)
+		}
+
+		cmd.timeout = defExecTimeout
+
+		if cmd.Timeout != "" {
+			cmd.timeout, err = time.ParseDuration(cmd.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("NewExec->Parse: %w", err)
+			}
+		}
+	}
+
+	return col, nil
+}
+
+// Collect - runs the commands at once and adds
+// the metrics they print, counters add the
+// printed value. ExecErrors counts the failed
+// runs and ExecDuration is the run time in
+// seconds of every command, e.g. ExecErrors/app.
+// A command still running at its timeout is
+// killed with its child processes.
+func (c *execCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	if !c.mutex.TryLock() {
+		return fmt.Errorf("Collect: %w", errBusy)
+	}
+	defer c.mutex.Unlock()
+
+	samples := make([]*bizmodels.Sample, len(c.opts.Commands))
+	waitGroup := &sync.WaitGroup{}
+
+	for i := range c.opts.Commands {
+		samples[i] = bizmodels.NewSample()
+
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			runCommand(&c.opts.Commands[i], samples[i])
+		}()
+	}
+
+	waitGroup.Wait()
+
+	for _, res := range samples {
+		for name, value := range res.Gauges {
+			sample.SetGauge(name, value)
+		}
+
+		for name, delta := range res.Counters {
+			sample.AddCounter(name, delta)
+		}
+	}
+
+	return nil
+}
+
+// runCommand - runs the command
+// and parses its output.
+func runCommand(
+	cmd *execCommand,
+	sample *bizmodels.Sample,
+) {
+	start := time.Now()
+	failed := int64(0)
+
+	out, err := execute(cmd)
+	if err == nil {
+		err = parseOutput(cmd.Format, out, sample)
+	}
+
+	if err != nil {
+		fmt.Println("runCommand:", cmd.Name, err)
+
+		failed = 1
+	}
+
+	sample.AddCounter(seriesName("ExecErrors", cmd.Name),
+		failed)
+	sample.SetGauge(seriesName("ExecDuration", cmd.Name),
+		time.Since(start).Seconds())
+}
+
+// execute - runs the command
+// and returns its output.
+func execute(cmd *execCommand) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), cmd.timeout)
+	defer cancel()
+
+	out := &limitedBuffer{}
+
+	proc := exec.CommandContext(ctx, cmd.Command, cmd.Args...)
+	proc.Stdout = out
+	proc.WaitDelay = waitDelay
+	killGroup(proc)
+
+	err := proc.Run()
+	if err != nil {
+		return nil, fmt.Errorf("execute->Run: %w", err)
+	}
+
+	if out.overflow {
+		return nil, fmt.Errorf("execute: %w", errOutput)
+	}
+
+	return out.Bytes(), nil
+}
+
+// parseOutput - adds the
+// metrics of the output.
+func parseOutput(
+	format string,
+	out []byte,
+	sample *bizmodels.Sample,
+) error {
+	if format == FormatJSON {
+		return parseJSON(out, sample)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		err := parseLine(line, sample)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseLine - adds the metric of the
+// line, the type defaults to gauge.
+func parseLine(
+	line string,
+	sample *bizmodels.Sample,
+) error {
+	fields := strings.Fields(line)
+	if len(fields) == 2 {
+		fields = append([]string{bizmodels.GaugeName}, fields...)
+	}
+
+	if len(fields) != 3 {
+		return fmt.Errorf("parseLine: %w: %q", errLine, line)
+	}
+
+	switch fields[0] {
+	case bizmodels.GaugeName:
+		value, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil ||
+			math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("parseLine: %w: %q", errLine, line)
+		}
+
+		sample.SetGauge(fields[1], value)
+	case bizmodels.CounterName:
+		delta, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("parseLine: %w: %q", errLine, line)
+		}
+
+		sample.AddCounter(fields[1], delta)
+	default:
+		return fmt.Errorf("parseLine: %w: %q", errLine, line)
+	}
+
+	return nil
+}
+
+// parseJSON - adds the metric or
+// the array of metrics of the output.
+func parseJSON(out []byte, sample *bizmodels.Sample) error {
+	var metrics apimodels.ArrMetrics
+
+	out = bytes.TrimSpace(out)
+	if bytes.HasPrefix(out, []byte("{")) {
+		out = append(append([]byte("["), out...), ']')
+	}
+
+	err := json.Unmarshal(out, &metrics)
+	if err != nil {
+		return fmt.Errorf("parseJSON->Unmarshal: %w", err)
+	}
+
+	for _, met := range metrics {
+		isGauge := met.MType == bizmodels.GaugeName
+		isCounter := met.MType == bizmodels.CounterName
+
+		switch {
+		case isGauge && met.Value != nil:
+			sample.SetGauge(met.ID, *met.Value)
+		case isCounter && met.Delta != nil:
+			sample.AddCounter(met.ID, *met.Delta)
+		default:
+			return fmt.Errorf("parseJSON: %w: %s", errLine, met.ID)
+		}
+	}
+
+	return nil
+}
+
+// limitedBuffer - keeps up to maxExecOutput
+// bytes and drops the rest, so the command
+// is not blocked on a full pipe.
+type limitedBuffer struct {
+	bytes.Buffer
+	overflow bool
+}
+
+// Write - writes the bytes that fit.
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	room := maxExecOutput - b.Len()
+	if len(data) > room {
+		b.overflow = true
+		b.Buffer.Write(data[:max(room, 0)])
+
+		return len(data), nil
+	}
+
+	b.Buffer.Write(data)
+
+	return len(data), nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package collector
+
+import "os/exec"
+
+// killGroup - only the command itself
+// is killed when it is canceled.
+func killGroup(_ *exec.Cmd) {}
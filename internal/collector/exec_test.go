@@ -0,0 +1,79 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func shell(
+	name, script, format, timeout string,
+) map[string]any {
+	return map[string]any{
+		"name":    name,
+		"command": "/bin/sh",
+		"args":    []string{"-c", script},
+		"format":  format,
+		"timeout": timeout,
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	options, err := json.Marshal(map[string]any{
+		"commands": []map[string]any{
+			shell("lines", "echo '# queue'; echo 'Queue 3.5';"+
+				" echo 'counter Jobs 2'", "", ""),
+			shell("json", `echo '[{"id":"Users","type":"gauge",`+
+				`"value":7},{"id":"Logins","type":"counter",`+
+				`"delta":4}]'`, collector.FormatJSON, ""),
+			shell("bad", "echo 'gauge Broken x'", "", ""),
+			shell("fail", "echo 'Lost 1'; exit 1", "", ""),
+			shell("hung", "sleep 10 & sleep 10", "", "200ms"),
+		},
+	})
+	assert.NoError(t, err)
+
+	col, err := collector.NewExec(options)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	sample := bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	gau, cnt := sample.Gauges, sample.Counters
+	assert.InDelta(t, 3.5, gau["Queue"], 0)
+	assert.InDelta(t, 7, gau["Users"], 0)
+	assert.Equal(t, int64(2), cnt["Jobs"])
+	assert.Equal(t, int64(4), cnt["Logins"])
+	assert.NotContains(t, gau, "Lost")
+
+	for name, failed := range map[string]int64{
+		"lines": 0, "json": 0, "bad": 1, "fail": 1, "hung": 1,
+	} {
+		assert.Equal(t, failed, cnt["ExecErrors/"+name], name)
+		assert.Contains(t, gau, "ExecDuration/"+name)
+	}
+}
+
+func TestExecOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, options := range []string{
+		`{"commands": [{"name": "a"}]}`,
+		`{"commands": [{"command": "a"}]}`,
+		`{"commands": [{"name": "a", "command": "a",
+			"format": "xml"}]}`,
+		`{"commands": [{"name": "a", "command": "a",
+			"timeout": "soon"}]}`,
+	} {
+		_, err := collector.NewExec(json.RawMessage(options))
+		assert.Error(t, err)
+	}
+}
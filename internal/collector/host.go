@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// memoryCollector - reads the host memory.
+type memoryCollector struct{}
+
+// NewMemory - creates the memory collector.
+func NewMemory(
+	_ json.RawMessage,
+) (bizmodels.Collector, error) {
+	return &memoryCollector{}, nil
+}
+
+// Collect - reads the total and free memory.
+func (c *memoryCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	virtMem, err := mem.VirtualMemory()
+	if err != nil {
+		return fmt.Errorf("Collect->VirtualMemory: %w", err)
+	}
+
+	sample.SetGauge("TotalMemory", float64(virtMem.Total))
+	sample.SetGauge("FreeMemory", float64(virtMem.Free))
+
+	return nil
+}
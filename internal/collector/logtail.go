@@ -0,0 +1,363 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// offsetsMode - permissions of the offsets file.
+const offsetsMode = 0o600
+
+// offsetsDirMode - permissions of
+// the directory of the offsets file.
+const offsetsDirMode = 0o750
+
+var errRule = errors.New(
+	"rule needs a match and either a counter " +
+		"or a gauge with a capture group")
+
+// tailRule - counts the lines matching the
+// expression in Counter, or sets Gauge to
+// the number of its first capture group.
+type tailRule struct {
+	match   *regexp.Regexp
+	Match   string `json:"match"`
+	Counter string `json:"counter"`
+	Gauge   string `json:"gauge"`
+}
+
+// tailFileOptions - tailed file and its rules.
+type tailFileOptions struct {
+	Path  string     `json:"path"`
+	Rules []tailRule `json:"rules"`
+}
+
+// logTailOptions - options of the logtail
+// collector, Offsets is the file keeping
+// the read offsets between restarts and
+// FromStart reads the files not seen
+// before from the beginning.
+type logTailOptions struct {
+	Offsets   string            `json:"offsets"`
+	Files     []tailFileOptions `json:"files"`
+	FromStart bool              `json:"fromStart"`
+}
+
+// tailOffset - read position of a file.
+type tailOffset struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset"`
+}
+
+// tailFile - open tailed file.
+type tailFile struct {
+	file *os.File
+	tailOffset
+}
+
+// logTailCollector - reads the lines
+// appended to the files since the
+// previous poll, follows rotated
+// and truncated files. The offsets
+// last written are kept in saved.
+type logTailCollector struct {
+	files   map[string]*tailFile
+	offsets map[string]tailOffset
+	saved   []byte
+	opts    logTailOptions
+	mutex   sync.Mutex
+}
+
+// NewLogTail - creates the logtail collector.
+func NewLogTail(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &logTailCollector{
+		files:   make(map[string]*tailFile),
+		offsets: make(map[string]tailOffset),
+	}
+
+	err := decodeOptions(options, &col.opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewLogTail->decode: %w", err)
+	}
+
+	for i := range col.opts.Files {
+		for j := range col.opts.Files[i].Rules {
+			err = compileRule(&col.opts.Files[i].Rules[j])
+			if err != nil {
+				return nil, fmt.Errorf("NewLogTail->compile: %w", err)
+			}
+		}
+	}
+
+	err = col.loadOffsets()
+	if err != nil {
+		return nil, fmt.Errorf("NewLogTail->load: %w", err)
+	}
+
+	return col, nil
+}
+
+// Collect - applies the rules to the complete
+// lines appended since the previous poll,
+// the counters of the rules are reported
+// every poll. The lines left in a rotated
+// file are read before the new file.
+func (c *logTailCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := range c.opts.Files {
+		opts := &c.opts.Files[i]
+
+		for _, rule := range opts.Rules {
+			if rule.Counter != "" {
+				sample.AddCounter(rule.Counter, 0)
+			}
+		}
+
+		err := c.tail(opts, sample)
+		if err != nil {
+			fmt.Println("Collect->tail:", opts.Path, err)
+		}
+	}
+
+	return c.saveOffsets()
+}
+
+// tail - reads the new lines of the file.
+func (c *logTailCollector) tail(
+	opts *tailFileOptions,
+	sample *bizmodels.Sample,
+) error {
+	info, err := os.Stat(opts.Path)
+	if err != nil {
+		return fmt.Errorf("tail->Stat: %w", err)
+	}
+
+	cur, ok := c.files[opts.Path]
+	if ok && cur.ID != fileID(info) {
+		// rotated, the rest of the old
+		// file is read to its end.
+		err = readLines(cur, opts.Rules, sample, true)
+		cur.file.Close()
+		delete(c.files, opts.Path)
+
+		if err != nil {
+			fmt.Printf("tail->readLines: %v\n", err)
+		}
+
+		ok = false
+	}
+
+	if !ok {
+		cur, err = c.open(opts.Path, info)
+		if err != nil {
+			return fmt.Errorf("tail->open: %w", err)
+		}
+
+		c.files[opts.Path] = cur
+	}
+
+	if info.Size() < cur.Offset {
+		cur.Offset = 0
+	}
+
+	err = readLines(cur, opts.Rules, sample, false)
+	if err != nil {
+		return fmt.Errorf("tail->readLines: %w", err)
+	}
+
+	c.offsets[opts.Path] = cur.tailOffset
+
+	return nil
+}
+
+// open - opens the file at the saved offset,
+// a new file of a known path is read from the
+// beginning, an unknown path from its end
+// unless FromStart is set.
+func (c *logTailCollector) open(
+	path string,
+	info os.FileInfo,
+) (*tailFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open->Open: %w", err)
+	}
+
+	cur := &tailFile{
+		file:       file,
+		tailOffset: tailOffset{ID: fileID(info)},
+	}
+
+	saved, known := c.offsets[path]
+
+	switch {
+	case known && saved.ID == cur.ID:
+		cur.Offset = saved.Offset
+	case !known && !c.opts.FromStart:
+		cur.Offset = info.Size()
+	}
+
+	return cur, nil
+}
+
+// readLines - applies the rules to the complete
+// lines after the offset, a rotated file has
+// no more writes so its last line is read too.
+func readLines(
+	cur *tailFile,
+	rules []tailRule,
+	sample *bizmodels.Sample,
+	last bool,
+) error {
+	_, err := cur.file.Seek(cur.Offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("readLines->Seek: %w", err)
+	}
+
+	reader := bufio.NewReader(cur.file)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && (!last || len(line) == 0) {
+			return nil
+		}
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("readLines->Read: %w", err)
+		}
+
+		cur.Offset += int64(len(line))
+
+		applyRules(rules, line, sample)
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// applyRules - applies the rules to the line.
+func applyRules(
+	rules []tailRule,
+	line []byte,
+	sample *bizmodels.Sample,
+) {
+	for _, rule := range rules {
+		if rule.Counter != "" {
+			if rule.match.Match(line) {
+				sample.AddCounter(rule.Counter, 1)
+			}
+
+			continue
+		}
+
+		groups := rule.match.FindSubmatch(line)
+		if groups == nil {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(string(groups[1]), 64)
+		if err == nil {
+			sample.SetGauge(rule.Gauge, value)
+		}
+	}
+}
+
+// compileRule - checks and compiles the rule.
+func compileRule(rule *tailRule) error {
+	if rule.Match == "" || (rule.Counter == "") ==
+		(rule.Gauge == "") {
+		return errRule
+	}
+
+	match, err := regexp.Compile(rule.Match)
+	if err != nil {
+		return fmt.Errorf("compileRule->Compile: %w", err)
+	}
+
+	if rule.Gauge != "" && match.NumSubexp() < 1 {
+		return errRule
+	}
+
+	rule.match = match
+
+	return nil
+}
+
+// loadOffsets - reads the saved offsets.
+func (c *logTailCollector) loadOffsets() error {
+	if c.opts.Offsets == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.opts.Offsets)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("loadOffsets->ReadFile: %w", err)
+	}
+
+	err = json.Unmarshal(data, &c.offsets)
+	if err != nil {
+		return fmt.Errorf("loadOffsets->Unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// saveOffsets - replaces the saved offsets
+// when they changed since the last write.
+func (c *logTailCollector) saveOffsets() error {
+	if c.opts.Offsets == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c.offsets)
+	if err != nil {
+		return fmt.Errorf("saveOffsets->Marshal: %w", err)
+	}
+
+	if bytes.Equal(data, c.saved) {
+		return nil
+	}
+
+	tmp := c.opts.Offsets + ".tmp"
+
+	err = os.MkdirAll(filepath.Dir(tmp), offsetsDirMode)
+	if err != nil {
+		return fmt.Errorf("saveOffsets->MkdirAll: %w", err)
+	}
+
+	err = os.WriteFile(tmp, data, offsetsMode)
+	if err != nil {
+		return fmt.Errorf("saveOffsets->WriteFile: %w", err)
+	}
+
+	err = os.Rename(tmp, c.opts.Offsets)
+	if err != nil {
+		return fmt.Errorf("saveOffsets->Rename: %w", err)
+	}
+
+	c.saved = data
+
+	return nil
+}
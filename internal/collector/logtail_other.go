@@ -0,0 +1,11 @@
+//go:build !unix
+
+package collector
+
+import "os"
+
+// fileID - files are not identified,
+// only truncation is detected.
+func fileID(_ os.FileInfo) string {
+	return ""
+}
@@ -0,0 +1,111 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func appendLog(t *testing.T, path, data string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path,
+		os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.NoError(t, err)
+
+	_, err = file.WriteString(data)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func TestLogTail(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	offsets := filepath.Join(dir, "state", "offsets.json")
+
+	options, err := json.Marshal(map[string]any{
+		"offsets": offsets,
+		"files": []map[string]any{{
+			"path": path,
+			"rules": []map[string]string{
+				{"match": "ERROR", "counter": "AppErrors"},
+				{"match": `latency=([0-9.]+)`, "gauge": "Latency"},
+			},
+		}},
+	})
+	assert.NoError(t, err)
+
+	appendLog(t, path, "ERROR before start\n")
+
+	col, err := collector.NewLogTail(options)
+	assert.NoError(t, err)
+
+	collect := func() *bizmodels.Sample {
+		sample := bizmodels.NewSample()
+		assert.NoError(t, col.Collect(sample))
+
+		return sample
+	}
+
+	assert.Zero(t, collect().Counters["AppErrors"])
+
+	appendLog(t, path, "ERROR a\nINFO latency=12.5\nERROR b")
+
+	sample := collect()
+	assert.Equal(t, int64(1), sample.Counters["AppErrors"])
+	assert.InDelta(t, 12.5, sample.Gauges["Latency"], 0)
+
+	appendLog(t, path, "\n")
+	assert.Equal(t, int64(1), collect().Counters["AppErrors"])
+
+	// rotation, the old file gets a last line.
+	assert.NoError(t, os.Rename(path, path+".1"))
+	appendLog(t, path+".1", "ERROR c\n")
+	appendLog(t, path, "ERROR d\n")
+	assert.Equal(t, int64(2), collect().Counters["AppErrors"])
+
+	// truncation.
+	assert.NoError(t,
+		os.WriteFile(path, []byte("ERROR\n"), 0o600))
+	assert.Equal(t, int64(1), collect().Counters["AppErrors"])
+
+	// restart keeps the offsets.
+	appendLog(t, path, "ERROR e\n")
+
+	col, err = collector.NewLogTail(options)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), collect().Counters["AppErrors"])
+	assert.Zero(t, collect().Counters["AppErrors"])
+
+	// unchanged offsets are not written.
+	assert.NoError(t, os.Remove(offsets))
+	collect()
+	assert.NoFileExists(t, offsets)
+
+	appendLog(t, path, "INFO\n")
+	collect()
+	assert.FileExists(t, offsets)
+}
+
+func TestLogTailOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{
+		`{"counter": "A"}`,
+		`{"match": "a"}`,
+		`{"match": "a", "gauge": "A"}`,
+		`{"match": "(a)", "gauge": "A", "counter": "B"}`,
+		`{"match": "(", "counter": "A"}`,
+	} {
+		_, err := collector.NewLogTail(json.RawMessage(
+			`{"files": [{"path": "a", "rules": [` + rule + `]}]}`))
+		assert.Error(t, err)
+	}
+}
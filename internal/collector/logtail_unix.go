@@ -0,0 +1,21 @@
+//go:build unix
+
+package collector
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// fileID - identifies the file by its
+// device and inode across renames.
+func fileID(info os.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(stat.Dev), 10) + ":" +
+		strconv.FormatUint(stat.Ino, 10)
+}
@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	psnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// tcpStates - reported connection states.
+//
+//nolint:gochecknoglobals
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV",
+	"FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK",
+	"LISTEN", "CLOSING",
+}
+
+// netOptions - options of the net collector,
+// Loopback reads the loopback interfaces
+// and TCP counts the tcp connections.
+type netOptions struct {
+	Interfaces filter `json:"interfaces"`
+	Loopback   bool   `json:"loopback"`
+	TCP        bool   `json:"tcp"`
+}
+
+// netCollector - reads the traffic of the
+// network interfaces and the tcp connections.
+type netCollector struct {
+	io    *deltas
+	opts  netOptions
+	mutex sync.Mutex
+}
+
+// NewNet - creates the net collector.
+func NewNet(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &netCollector{
+		io:   newDeltas(),
+		opts: netOptions{TCP: true},
+	}
+
+	err := decodeOptions(options, &col.opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewNet->decodeOptions: %w", err)
+	}
+
+	return col, nil
+}
+
+// Collect - reads NetBytesSent, NetBytesRecv,
+// NetPacketsSent, NetPacketsRecv, NetErrIn,
+// NetErrOut, NetDropIn and NetDropOut counters
+// of every interface, e.g. NetBytesRecv/eth0, and
+// TCPConnections gauges of every connection
+// state, e.g. TCPConnections/TIMEWAIT.
+func (c *netCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	loopback, err := loopbacks()
+	if err != nil {
+		return fmt.Errorf("Collect->loopbacks: %w", err)
+	}
+
+	counters, err := psnet.IOCounters(true)
+	if err != nil {
+		return fmt.Errorf("Collect->IOCounters: %w", err)
+	}
+
+	for _, nic := range counters {
+		_, isLoop := loopback[nic.Name]
+		if (isLoop && !c.opts.Loopback) ||
+			!c.opts.Interfaces.match(nic.Name) {
+			continue
+		}
+
+		c.addInterface(sample, &nic)
+	}
+
+	if !c.opts.TCP {
+		return nil
+	}
+
+	conns, err := psnet.ConnectionsWithoutUids("tcp")
+	if err != nil {
+		return fmt.Errorf("Collect->Connections: %w", err)
+	}
+
+	states := make(map[string]int, len(tcpStates))
+	for _, conn := range conns {
+		states[conn.Status]++
+	}
+
+	for _, state := range tcpStates {
+		sample.SetGauge(seriesName("TCPConnections",
+			strings.ReplaceAll(state, "_", "")),
+			float64(states[state]))
+	}
+
+	return nil
+}
+
+// addInterface - adds the
+// counters of the interface.
+func (c *netCollector) addInterface(
+	sample *bizmodels.Sample,
+	nic *psnet.IOCountersStat,
+) {
+	for name, value := range map[string]uint64{
+		"NetBytesSent":   nic.BytesSent,
+		"NetBytesRecv":   nic.BytesRecv,
+		"NetPacketsSent": nic.PacketsSent,
+		"NetPacketsRecv": nic.PacketsRecv,
+		"NetErrIn":       nic.Errin,
+		"NetErrOut":      nic.Errout,
+		"NetDropIn":      nic.Dropin,
+		"NetDropOut":     nic.Dropout,
+	} {
+		c.io.add(sample, seriesName(name, nic.Name), value)
+	}
+}
+
+// loopbacks - returns the names
+// of the loopback interfaces.
+func loopbacks() (map[string]struct{}, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("loopbacks->Interfaces: %w", err)
+	}
+
+	res := make(map[string]struct{})
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			res[iface.Name] = struct{}{}
+		}
+	}
+
+	return res, nil
+}
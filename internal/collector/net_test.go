@@ -0,0 +1,37 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNet(t *testing.T) {
+	t.Parallel()
+
+	col, err := collector.NewNet(nil)
+	assert.NoError(t, err)
+
+	sample := bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Contains(t, sample.Gauges, "TCPConnections/LISTEN")
+	assert.NotContains(t, sample.Counters, "NetBytesRecv/lo")
+
+	col, err = collector.NewNet(json.RawMessage(
+		`{"loopback": true, "tcp": false,
+		"interfaces": {"include": ["lo"]}}`))
+	assert.NoError(t, err)
+
+	sample = bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Empty(t, sample.Gauges)
+	assert.Contains(t, sample.Counters, "NetBytesRecv/lo")
+
+	for name := range sample.Counters {
+		assert.True(t, strings.HasSuffix(name, "/lo"))
+	}
+}
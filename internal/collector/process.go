@@ -0,0 +1,373 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+var errSelector = errors.New(
+	"process needs a name and one of exe, cmdline, pidFile")
+
+// procSelector - watched processes, selected
+// by the process name, a regular expression
+// of the command line or a pid file.
+// Name labels the series of the processes.
+type procSelector struct {
+	cmdline *regexp.Regexp
+	Name    string `json:"name"`
+	Exe     string `json:"exe"`
+	Cmdline string `json:"cmdline"`
+	PidFile string `json:"pidFile"`
+}
+
+// processOptions - options of the process
+// collector, TopN reports the process
+// names using the most cpu.
+type processOptions struct {
+	Processes []procSelector `json:"processes"`
+	TopN      int            `json:"topN"`
+}
+
+// procReading - a process in the current poll.
+type procReading struct {
+	name    string
+	cpu     float64
+	rss     uint64
+	created int64
+	pid     int32
+	fds     int32
+	threads int32
+}
+
+// processCollector - reads the selected
+// processes, the processes are kept
+// between polls to compute their cpu usage.
+type processCollector struct {
+	procs   map[int32]*process.Process
+	primary map[string]int32
+	opts    processOptions
+	mutex   sync.Mutex
+}
+
+// NewProcess - creates the process collector.
+func NewProcess(
+	options json.RawMessage,
+) (bizmodels.Collector, error) {
+	col := &processCollector{
+		procs:   make(map[int32]*process.Process),
+		primary: make(map[string]int32),
+	}
+
+	err := decodeOptions(options, &col.opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewProcess->decode: %w", err)
+	}
+
+	for i := range col.opts.Processes {
+		sel := &col.opts.Processes[i]
+
+		set := 0
+
+		for _, value := range []string{
+			sel.Exe, sel.Cmdline, sel.PidFile,
+		} {
+			if value != "" {
+				set++
+			}
+		}
+
+		if sel.Name == "" || set != 1 {
+			return nil, fmt.Errorf("NewProcess: %w", errSelector)
+		}
+
+		if sel.Cmdline != "" {
+			sel.cmdline, err = regexp.Compile(sel.Cmdline)
+			if err != nil {
+				return nil, fmt.Errorf("NewProcess->Compile: %w", err)
+			}
+		}
+	}
+
+	return col, nil
+}
+
+// Collect - reads ProcessCount, ProcessCPU,
+// ProcessRSS, ProcessFDs and ProcessThreads
+// gauges summed over the processes of every
+// selector, e.g. ProcessRSS/nginx, and counts
+// the restarts in ProcessRestarts, a restart is
+// a change of the pid of the oldest process.
+// TopN mode reports ProcessTopCPU and
+// ProcessTopRSS by the process name.
+func (c *processCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	pids, err := process.Pids()
+	if err != nil {
+		return fmt.Errorf("Collect->Pids: %w", err)
+	}
+
+	readings := make(map[int32]*procReading)
+
+	for i := range c.opts.Processes {
+		sel := &c.opts.Processes[i]
+		c.report(sample, sel.Name, c.match(sel, pids, readings))
+	}
+
+	if c.opts.TopN > 0 {
+		for _, pid := range pids {
+			c.read(pid, readings)
+		}
+
+		c.top(sample, readings)
+	}
+
+	for pid := range c.procs {
+		if _, ok := readings[pid]; !ok {
+			delete(c.procs, pid)
+		}
+	}
+
+	return nil
+}
+
+// match - returns the readings
+// of the selected processes.
+func (c *processCollector) match(
+	sel *procSelector,
+	pids []int32,
+	readings map[int32]*procReading,
+) []*procReading {
+	res := make([]*procReading, 0)
+
+	if sel.PidFile != "" {
+		pid, err := readPidFile(sel.PidFile)
+		if err != nil {
+			fmt.Println("match->readPidFile: %w", err)
+
+			return res
+		}
+
+		if read := c.read(pid, readings); read != nil {
+			res = append(res, read)
+		}
+
+		return res
+	}
+
+	for _, pid := range pids {
+		proc, err := c.get(pid)
+		if err != nil || !matchProcess(sel, proc) {
+			continue
+		}
+
+		if read := c.read(pid, readings); read != nil {
+			res = append(res, read)
+		}
+	}
+
+	return res
+}
+
+// report - sets the gauges
+// of the selected processes.
+func (c *processCollector) report(
+	sample *bizmodels.Sample,
+	name string,
+	reads []*procReading,
+) {
+	var (
+		sum     procReading
+		primary int32
+	)
+
+	oldest := int64(math.MaxInt64)
+
+	for _, read := range reads {
+		sum.cpu += read.cpu
+		sum.rss += read.rss
+		sum.fds += read.fds
+		sum.threads += read.threads
+
+		if read.created < oldest {
+			oldest = read.created
+			primary = read.pid
+		}
+	}
+
+	sample.SetGauge(seriesName("ProcessCount", name),
+		float64(len(reads)))
+	sample.SetGauge(seriesName("ProcessCPU", name), sum.cpu)
+	sample.SetGauge(seriesName("ProcessRSS", name),
+		float64(sum.rss))
+	sample.SetGauge(seriesName("ProcessFDs", name),
+		float64(sum.fds))
+	sample.SetGauge(seriesName("ProcessThreads", name),
+		float64(sum.threads))
+
+	restarts := int64(0)
+
+	if len(reads) > 0 {
+		prev, known := c.primary[name]
+		if known && prev != primary {
+			restarts = 1
+		}
+
+		c.primary[name] = primary
+	}
+
+	sample.AddCounter(seriesName("ProcessRestarts", name),
+		restarts)
+}
+
+// top - reports the process
+// names using the most cpu.
+func (c *processCollector) top(
+	sample *bizmodels.Sample,
+	readings map[int32]*procReading,
+) {
+	byName := make(map[string]*procReading)
+
+	for _, read := range readings {
+		sum, ok := byName[read.name]
+		if !ok {
+			sum = &procReading{name: read.name}
+			byName[read.name] = sum
+		}
+
+		sum.cpu += read.cpu
+		sum.rss += read.rss
+	}
+
+	sums := make([]*procReading, 0, len(byName))
+	for _, sum := range byName {
+		sums = append(sums, sum)
+	}
+
+	slices.SortFunc(sums, func(a, b *procReading) int {
+		if a.cpu != b.cpu {
+			if a.cpu > b.cpu {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(a.name, b.name)
+	})
+
+	for _, sum := range sums[:min(c.opts.TopN, len(sums))] {
+		sample.SetGauge(seriesName("ProcessTopCPU", sum.name),
+			sum.cpu)
+		sample.SetGauge(seriesName("ProcessTopRSS", sum.name),
+			float64(sum.rss))
+	}
+}
+
+// get - returns the kept process.
+func (c *processCollector) get(
+	pid int32,
+) (*process.Process, error) {
+	proc, ok := c.procs[pid]
+	if ok {
+		return proc, nil
+	}
+
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return nil, fmt.Errorf("get->NewProcess: %w", err)
+	}
+
+	c.procs[pid] = proc
+
+	return proc, nil
+}
+
+// read - reads the process once per poll,
+// returns nil when it is gone.
+func (c *processCollector) read(
+	pid int32,
+	readings map[int32]*procReading,
+) *procReading {
+	if read, ok := readings[pid]; ok {
+		return read
+	}
+
+	proc, err := c.get(pid)
+	if err != nil {
+		return nil
+	}
+
+	read := &procReading{pid: pid}
+	read.name, _ = proc.Name()
+	read.created, err = proc.CreateTime()
+
+	if err != nil {
+		delete(c.procs, pid)
+
+		return nil
+	}
+
+	read.cpu, _ = proc.Percent(0)
+	read.fds, _ = proc.NumFDs()
+	read.threads, _ = proc.NumThreads()
+
+	mem, err := proc.MemoryInfo()
+	if err == nil {
+		read.rss = mem.RSS
+	}
+
+	readings[pid] = read
+
+	return read
+}
+
+// matchProcess - tells if the
+// process is selected.
+func matchProcess(
+	sel *procSelector,
+	proc *process.Process,
+) bool {
+	if sel.Exe != "" {
+		name, err := proc.Name()
+
+		return err == nil && name == sel.Exe
+	}
+
+	cmdline, err := proc.Cmdline()
+
+	return err == nil && sel.cmdline.MatchString(cmdline)
+}
+
+// readPidFile - reads the pid
+// on the first line of the file.
+func readPidFile(name string) (int32, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return 0, fmt.Errorf("readPidFile->ReadFile: %w", err)
+	}
+
+	line, _, _ := strings.Cut(string(data), "\n")
+
+	pid, err := strconv.ParseInt(
+		strings.TrimSpace(line), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("readPidFile->ParseInt: %w", err)
+	}
+
+	return int32(pid), nil
+}
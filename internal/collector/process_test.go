@@ -0,0 +1,81 @@
+package collector_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/collector"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	pidFile := filepath.Join(t.TempDir(), "test.pid")
+	writePid := func(pid int) {
+		assert.NoError(t, os.WriteFile(pidFile,
+			[]byte(strconv.Itoa(pid)+"\n/data\n"), 0o600))
+	}
+
+	writePid(os.Getpid())
+
+	options, err := json.Marshal(map[string]any{
+		"processes": []map[string]string{
+			{"name": "self", "pidFile": pidFile},
+			{"name": "test", "cmdline": `collector\.test`},
+			{"name": "none", "exe": "no such process"},
+		},
+		"topN": 3,
+	})
+	assert.NoError(t, err)
+
+	col, err := collector.NewProcess(options)
+	assert.NoError(t, err)
+
+	sample := bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.InDelta(t, 1, sample.Gauges["ProcessCount/self"], 0)
+	assert.Positive(t, sample.Gauges["ProcessRSS/self"])
+	assert.Positive(t, sample.Gauges["ProcessFDs/self"])
+	assert.Positive(t, sample.Gauges["ProcessThreads/self"])
+	assert.Positive(t, sample.Gauges["ProcessCount/test"])
+	assert.Zero(t, sample.Gauges["ProcessCount/none"])
+	assert.Zero(t, sample.Counters["ProcessRestarts/self"])
+
+	top := 0
+
+	for name := range sample.Gauges {
+		if strings.HasPrefix(name, "ProcessTopCPU/") {
+			top++
+		}
+	}
+
+	assert.Equal(t, 3, top)
+
+	writePid(os.Getppid())
+
+	sample = bizmodels.NewSample()
+	assert.NoError(t, col.Collect(sample))
+	assert.Equal(t, int64(1),
+		sample.Counters["ProcessRestarts/self"])
+}
+
+func TestProcessOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, options := range []string{
+		`{"processes": [{"exe": "a"}]}`,
+		`{"processes": [{"name": "a"}]}`,
+		`{"processes": [{"name": "a", "exe": "a",
+			"pidFile": "b"}]}`,
+		`{"processes": [{"name": "a", "cmdline": "("}]}`,
+	} {
+		_, err := collector.NewProcess(json.RawMessage(options))
+		assert.Error(t, err)
+	}
+}
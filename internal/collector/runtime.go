@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/random"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// maxRandomValue - upper bound of RandomValue.
+const maxRandomValue int64 = 1000
+
+// runtimeCollector - reads the memory
+// statistics of the go runtime.
+type runtimeCollector struct{}
+
+// NewRuntime - creates the runtime collector.
+func NewRuntime(
+	_ json.RawMessage,
+) (bizmodels.Collector, error) {
+	return &runtimeCollector{}, nil
+}
+
+// Collect - reads the runtime metrics,
+// counts the poll and sets a random value.
+func (c *runtimeCollector) Collect(
+	sample *bizmodels.Sample,
+) error {
+	var rtm runtime.MemStats
+
+	runtime.ReadMemStats(&rtm)
+
+	sample.SetGauge("Alloc", float64(rtm.Alloc))
+	sample.SetGauge("BuckHashSys", float64(rtm.BuckHashSys))
+	sample.SetGauge("Frees", float64(rtm.Frees))
+	sample.SetGauge("GCCPUFraction", rtm.GCCPUFraction)
+	sample.SetGauge("GCSys", float64(rtm.GCSys))
+	sample.SetGauge("HeapAlloc", float64(rtm.HeapAlloc))
+	sample.SetGauge("HeapIdle", float64(rtm.HeapIdle))
+	sample.SetGauge("HeapInuse", float64(rtm.HeapInuse))
+	sample.SetGauge("HeapObjects", float64(rtm.HeapObjects))
+	sample.SetGauge("HeapReleased", float64(rtm.HeapReleased))
+	sample.SetGauge("HeapSys", float64(rtm.HeapSys))
+	sample.SetGauge("LastGC", float64(rtm.LastGC))
+	sample.SetGauge("Lookups", float64(rtm.Lookups))
+	sample.SetGauge("MCacheInuse", float64(rtm.MCacheInuse))
+	sample.SetGauge("MCacheSys", float64(rtm.MCacheSys))
+	sample.SetGauge("MSpanInuse", float64(rtm.MSpanInuse))
+	sample.SetGauge("MSpanSys", float64(rtm.MSpanSys))
+	sample.SetGauge("Mallocs", float64(rtm.Mallocs))
+	sample.SetGauge("NextGC", float64(rtm.NextGC))
+	sample.SetGauge("NumForcedGC", float64(rtm.NumForcedGC))
+	sample.SetGauge("NumGC", float64(rtm.NumGC))
+	sample.SetGauge("OtherSys", float64(rtm.OtherSys))
+	sample.SetGauge("PauseTotalNs", float64(rtm.PauseTotalNs))
+	sample.SetGauge("StackInuse", float64(rtm.StackInuse))
+	sample.SetGauge("StackSys", float64(rtm.StackSys))
+	sample.SetGauge("Sys", float64(rtm.Sys))
+	sample.SetGauge("TotalAlloc", float64(rtm.TotalAlloc))
+
+	sample.AddCounter("PollCount", 1)
+
+	value, err := random.RandF64(maxRandomValue)
+	if err != nil {
+		return fmt.Errorf("Collect->RandF64: %w", err)
+	}
+
+	sample.SetGauge("RandomValue", value)
+
+	return nil
+}
@@ -5,17 +5,24 @@ package sendmetricsjsonendpoint
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
+	"github.com/dmitrovia/collector-metrics/internal/functions/batchid"
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
-	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v1"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
 )
 
 const timeout = 60
 
 // SendMJSONEndpoint - main endpoint method.
+// The body is rewound first,
+// so a retry sends the same payload.
 func SendMJSONEndpoint(
 	epSettings *bizmodels.EndpointSettings,
 ) (*http.Response, error) {
@@ -24,8 +31,14 @@ func SendMJSONEndpoint(
 
 	defer cancel()
 
+	_, err := epSettings.SendData.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("SendMJSONEndpoint->Seek: %w", err)
+	}
+
 	req, err := http.NewRequestWithContext(
-		ctx, http.MethodPost, epSettings.URL, epSettings.SendData)
+		ctx, http.MethodPost, epSettings.URL,
+		epSettings.SendData)
 	if err != nil {
 		return nil, fmt.Errorf("SendMJSONEndpoint->HNR: %w", err)
 	}
@@ -39,6 +52,14 @@ func SendMJSONEndpoint(
 		req.Header.Set("Hashsha256", epSettings.Hash)
 	}
 
+	if epSettings.BatchID != "" {
+		req.Header.Set(batchid.Header, epSettings.BatchID)
+	}
+
+	if epSettings.Agent != nil {
+		identity.SetHeaders(req.Header, epSettings.Agent)
+	}
+
 	resp, err := epSettings.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("SendMJSONEndpoint->Do: %w", err)
@@ -48,6 +69,7 @@ func SendMJSONEndpoint(
 }
 
 // SendMJSONEndpointGRPC - main endpoint method.
+// The typed request is compressed by the gzip codec.
 func SendMJSONEndpointGRPC(
 	epSettings *bizmodels.EndpointSettings,
 ) (*pb.SenderResponse, error) {
@@ -71,12 +93,19 @@ func SendMJSONEndpointGRPC(
 		metd = metadata.Join(metd, metdH)
 	}
 
+	if epSettings.BatchID != "" {
+		metd.Set(batchid.MetadataKey, epSettings.BatchID)
+	}
+
+	if epSettings.Agent != nil {
+		metd = metadata.Join(metd, identity.MD(epSettings.Agent))
+	}
+
 	ctx1 := metadata.NewOutgoingContext(ctx, metd)
 
 	resp, err := epSettings.MicroServiceClient.Sender(
-		ctx1, &pb.SenderRequest{
-			Metrics: *epSettings.MetricsGRPC,
-		})
+		ctx1, epSettings.RequestGRPC,
+		grpc.UseCompressor(gzip.Name))
 	if err != nil {
 		return nil, fmt.Errorf("SendMJSONEndGRPC->Sende: %w", err)
 	}
@@ -0,0 +1,403 @@
+// Package streamsenderendpoint provides
+// a long-lived stream to send metric
+// batches to the server.
+package streamsenderendpoint
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/hash"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+// defWindow - batches sent
+// without waiting for an ack.
+const defWindow = 8
+
+// defMaxPending - unacknowledged batches kept
+// for resending, older ones are dropped
+// and counted.
+const defMaxPending = 256
+
+const ackTimeout = 60
+
+const streamIDSize = 16
+
+var errStreamClosed = errors.New("stream closed")
+
+var errAckTimeout = errors.New("ack timeout")
+
+var errSenderClosed = errors.New("sender closed")
+
+var errRetryLater = errors.New("rate limited, retry later")
+
+// StreamSender - sends batches over one stream.
+// Every batch gets a sequence number and is kept
+// until the server acknowledges it, after a
+// reconnect the unacknowledged batches are
+// resent and the server skips the applied ones.
+// A rate limited stream is reopened after
+// the delay asked by the server. The metadata
+// is sent when the stream is opened. One
+// caller at a time sends the queued batches,
+// the others only queue theirs, the mutex
+// is not held while a batch is sent.
+type StreamSender struct {
+	retryAt  time.Time
+	client   pb.MicroServiceClient
+	stream   pb.MicroService_StreamSenderClient
+	metad    metadata.MD
+	cancel   context.CancelFunc
+	cond     *sync.Cond
+	streamID string
+	key      string
+	pending  []*pb.Batch
+	mutex    sync.Mutex
+	nextSeq  uint64
+	gen      uint64
+	dropped  int64
+	sent     int
+	window   int
+	closed   bool
+	flushing bool
+}
+
+// NewStreamSender - to create an instance
+// of a sender object, the stream is opened
+// with the first batch.
+func NewStreamSender(client pb.MicroServiceClient,
+	metad metadata.MD,
+	key string,
+	window int,
+) (*StreamSender, error) {
+	if window <= 0 {
+		window = defWindow
+	}
+
+	streamID := make([]byte, streamIDSize)
+
+	_, err := rand.Read(streamID)
+	if err != nil {
+		return nil, fmt.Errorf("NewStreamSender->Read: %w", err)
+	}
+
+	sender := &StreamSender{
+		client:   client,
+		metad:    metad,
+		streamID: hex.EncodeToString(streamID),
+		key:      key,
+		window:   window,
+	}
+	sender.cond = sync.NewCond(&sender.mutex)
+
+	return sender, nil
+}
+
+// SendBatch - queues the metrics as a new batch
+// and sends queued batches while the window allows,
+// unless another call is sending them already.
+// On error the batch stays queued and is resent
+// by the next call.
+func (s *StreamSender) SendBatch(
+	metrics *apimodels.ArrMetrics,
+) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return errSenderClosed
+	}
+
+	s.nextSeq++
+
+	batch := &pb.Batch{
+		StreamId: s.streamID,
+		Seq:      s.nextSeq,
+		Metrics:  pbconv.MetricsToPB(metrics, time.Now()),
+	}
+
+	err := signBatch(batch, s.key)
+	if err != nil {
+		return fmt.Errorf("SendBatch->signBatch: %w", err)
+	}
+
+	s.pending = append(s.pending, batch)
+
+	if len(s.pending) > defMaxPending {
+		drop := len(s.pending) - defMaxPending
+		s.pending = s.pending[drop:]
+		s.sent = max(s.sent-drop, 0)
+		s.dropped += int64(drop)
+	}
+
+	if s.flushing {
+		return nil
+	}
+
+	if s.stream == nil {
+		if time.Now().Before(s.retryAt) {
+			return fmt.Errorf("SendBatch: %w", errRetryLater)
+		}
+
+		err = s.open()
+		if err != nil {
+			return fmt.Errorf("SendBatch->open: %w", err)
+		}
+	}
+
+	s.flushing = true
+
+	defer func() {
+		s.flushing = false
+		s.cond.Broadcast()
+	}()
+
+	return s.flush()
+}
+
+// TakeDropped - returns the number of batches
+// dropped since the previous call.
+func (s *StreamSender) TakeDropped() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dropped := s.dropped
+	s.dropped = 0
+
+	return dropped
+}
+
+// Close - closes the stream once the batch
+// being sent is sent, unacknowledged
+// batches are dropped.
+func (s *StreamSender) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+
+	for s.flushing {
+		s.cond.Wait()
+	}
+
+	if s.stream == nil {
+		return nil
+	}
+
+	err := s.stream.CloseSend()
+	s.reset()
+
+	if err != nil {
+		return fmt.Errorf("Close->CloseSend: %w", err)
+	}
+
+	return nil
+}
+
+// open - opens a new stream
+// and starts receiving acks.
+func (s *StreamSender) open() error {
+	ctx, cancel := context.WithCancel(
+		metadata.NewOutgoingContext(context.Background(),
+			s.metad))
+
+	stream, err := s.client.StreamSender(ctx,
+		grpc.UseCompressor(gzip.Name))
+	if err != nil {
+		cancel()
+
+		return fmt.Errorf("open->StreamSender: %w", err)
+	}
+
+	s.gen++
+	s.stream = stream
+	s.cancel = cancel
+	s.sent = 0
+
+	go s.receive(stream, s.gen)
+
+	return nil
+}
+
+// reset - forgets the current stream,
+// its receiver stops on the next ack.
+func (s *StreamSender) reset() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.gen++
+	s.stream = nil
+	s.cancel = nil
+	s.sent = 0
+	s.cond.Broadcast()
+}
+
+// flush - sends queued batches,
+// waiting for acks when the window is full.
+// The mutex is released while a batch is
+// sent, the batch counts as sent unless it
+// was acknowledged or dropped meanwhile.
+func (s *StreamSender) flush() error {
+	for s.sent < len(s.pending) {
+		if s.closed {
+			return errSenderClosed
+		}
+
+		err := s.waitWindow()
+		if err != nil {
+			return err
+		}
+
+		if s.sent >= len(s.pending) {
+			return nil
+		}
+
+		gen, stream := s.gen, s.stream
+		batch := s.pending[s.sent]
+
+		s.mutex.Unlock()
+		err = stream.Send(batch)
+		s.mutex.Lock()
+
+		if s.gen != gen {
+			return fmt.Errorf("flush: %w", errStreamClosed)
+		}
+
+		if err != nil {
+			s.reset()
+
+			return fmt.Errorf("flush->Send: %w", err)
+		}
+
+		if s.sent < len(s.pending) && s.pending[s.sent] == batch {
+			s.sent++
+		}
+	}
+
+	return nil
+}
+
+// waitWindow - waits until
+// the number of batches in flight
+// is below the window.
+func (s *StreamSender) waitWindow() error {
+	gen := s.gen
+	timedOut := false
+
+	timer := time.AfterFunc(ackTimeout*time.Second, func() {
+		s.mutex.Lock()
+		timedOut = true
+		s.cond.Broadcast()
+		s.mutex.Unlock()
+	})
+	defer timer.Stop()
+
+	for s.sent >= s.window {
+		if s.closed {
+			return errSenderClosed
+		}
+
+		if s.gen != gen {
+			return errStreamClosed
+		}
+
+		if timedOut {
+			return errAckTimeout
+		}
+
+		s.cond.Wait()
+	}
+
+	if s.gen != gen {
+		return errStreamClosed
+	}
+
+	return nil
+}
+
+// receive - removes acknowledged batches,
+// acks are cumulative.
+func (s *StreamSender) receive(
+	stream pb.MicroService_StreamSenderClient,
+	gen uint64,
+) {
+	for {
+		ack, err := stream.Recv()
+
+		s.mutex.Lock()
+
+		if s.gen != gen {
+			s.mutex.Unlock()
+
+			return
+		}
+
+		if err != nil {
+			fmt.Printf("StreamSender->Recv: %v\n", err)
+
+			wait, limited := ratelimit.FromError(err)
+			if limited {
+				s.retryAt = time.Now().Add(wait)
+			}
+
+			s.reset()
+			s.mutex.Unlock()
+
+			return
+		}
+
+		// the server rejected the batch for good.
+		if ack.GetError() != "" {
+			fmt.Println("StreamSender->ack: " + ack.GetError())
+
+			s.dropped++
+		}
+
+		acked := 0
+		for acked < len(s.pending) &&
+			s.pending[acked].GetSeq() <= ack.GetSeq() {
+			acked++
+		}
+
+		s.pending = s.pending[acked:]
+		s.sent = max(s.sent-acked, 0)
+		s.cond.Broadcast()
+		s.mutex.Unlock()
+	}
+}
+
+// signBatch - sets the hash of the batch
+// computed over its canonical JSON form.
+func signBatch(batch *pb.Batch, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	data, err := pbconv.BatchBytes(batch)
+	if err != nil {
+		return fmt.Errorf("signBatch->BatchBytes: %w", err)
+	}
+
+	tHash, err := hash.MakeHashSHA256(&data, key)
+	if err != nil {
+		return fmt.Errorf("signBatch->MakeHashSHA256: %w", err)
+	}
+
+	batch.Hash = hex.EncodeToString(tHash)
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package streamsenderendpoint_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/endpoints/streamsenderendpoint"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// blockedStream - stream whose sends
+// wait until it is released.
+type blockedStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	release chan struct{}
+	sent    chan *pb.Batch
+}
+
+func (s *blockedStream) Send(batch *pb.Batch) error {
+	select {
+	case s.sent <- batch:
+	default:
+	}
+
+	select {
+	case <-s.release:
+		return nil
+	case <-s.ctx.Done():
+		return io.EOF
+	}
+}
+
+func (s *blockedStream) Recv() (*pb.BatchAck, error) {
+	<-s.ctx.Done()
+
+	return nil, io.EOF
+}
+
+func (s *blockedStream) CloseSend() error {
+	return nil
+}
+
+// streamClient - opens the blocked stream.
+type streamClient struct {
+	pb.MicroServiceClient
+	stream *blockedStream
+}
+
+func (c *streamClient) StreamSender(
+	ctx context.Context,
+	_ ...grpc.CallOption,
+) (pb.MicroService_StreamSenderClient, error) {
+	c.stream.ctx = ctx
+
+	return c.stream, nil
+}
+
+func TestSendBatchBlocked(t *testing.T) {
+	t.Parallel()
+
+	stream := &blockedStream{
+		release: make(chan struct{}),
+		sent:    make(chan *pb.Batch, 1),
+	}
+
+	sender, err := streamsenderendpoint.NewStreamSender(
+		&streamClient{stream: stream}, metadata.MD{}, "", 0)
+	assert.NoError(t, err)
+
+	delta := int64(1)
+	data := &apimodels.ArrMetrics{
+		{ID: "Count", MType: "counter", Delta: &delta},
+	}
+
+	go func() {
+		_ = sender.SendBatch(data)
+	}()
+
+	<-stream.sent
+
+	// the first batch is being sent, the
+	// others are queued without waiting.
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for range 300 {
+			assert.NoError(t, sender.SendBatch(data))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "SendBatch waits for the stream")
+	}
+
+	assert.Equal(t, int64(301-256), sender.TakeDropped())
+	assert.Zero(t, sender.TakeDropped())
+
+	close(stream.release)
+	assert.NoError(t, sender.Close())
+}
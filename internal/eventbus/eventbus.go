@@ -0,0 +1,271 @@
+// Package eventbus provides
+// notifications about metric changes.
+package eventbus
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+)
+
+// defBuffer - events queued for
+// a subscriber before it is dropped.
+const defBuffer = 256
+
+// ErrOverflow - returned when the subscriber
+// did not keep up with the events.
+var ErrOverflow = errors.New("subscriber is too slow")
+
+// ErrNotBuffered - returned when the events
+// to resume from are no longer buffered.
+var ErrNotBuffered = errors.New("events are not buffered")
+
+// Event - a new metric value,
+// counters carry the total after the change.
+type Event struct {
+	Time   time.Time
+	Metric apimodels.Metrics
+	Seq    uint64
+}
+
+// Filter - selects the metrics of interest,
+// empty fields match everything.
+// Pattern is matched with path.Match.
+type Filter struct {
+	IDs     map[string]struct{}
+	Prefix  string
+	Pattern string
+	MType   string
+}
+
+// NewFilter - to create an instance
+// of a filter object.
+func NewFilter(ids []string, prefix, mtype string) *Filter {
+	filter := &Filter{Prefix: prefix, MType: mtype}
+
+	if len(ids) > 0 {
+		filter.IDs = make(map[string]struct{}, len(ids))
+
+		for _, id := range ids {
+			filter.IDs[id] = struct{}{}
+		}
+	}
+
+	return filter
+}
+
+// Match - checks whether the metric passes the filter.
+func (f *Filter) Match(metric *apimodels.Metrics) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.MType != "" && f.MType != metric.MType {
+		return false
+	}
+
+	if f.Prefix != "" &&
+		!strings.HasPrefix(metric.ID, f.Prefix) {
+		return false
+	}
+
+	if f.Pattern != "" {
+		ok, _ := path.Match(f.Pattern, metric.ID)
+		if !ok {
+			return false
+		}
+	}
+
+	if f.IDs != nil {
+		_, ok := f.IDs[metric.ID]
+
+		return ok
+	}
+
+	return true
+}
+
+// Subscription - receives the events
+// matching its filter.
+type Subscription struct {
+	bus    *Bus
+	filter *Filter
+	events chan Event
+	err    error
+	id     uint64
+	start  uint64
+}
+
+// Start - sequence of the last event
+// published before the subscription.
+func (s *Subscription) Start() uint64 {
+	return s.start
+}
+
+// Events - channel of events,
+// closed when the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err - reason the events channel was closed,
+// nil when closed by the subscriber.
+func (s *Subscription) Err() error {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	return s.err
+}
+
+// Close - stops receiving events.
+func (s *Subscription) Close() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	s.bus.remove(s, nil)
+}
+
+// Bus - delivers events to subscribers.
+// Publishing never blocks, a subscriber whose
+// buffer is full is dropped with ErrOverflow.
+// The latest events are kept in a ring buffer,
+// so subscribers can resume after a reconnect.
+type Bus struct {
+	subs    map[uint64]*Subscription
+	history []Event
+	mutex   sync.Mutex
+	seq     uint64
+	nextID  uint64
+	head    int
+	size    int
+}
+
+// NewBus - to create an instance
+// of a bus object keeping history events.
+func NewBus(history int) *Bus {
+	return &Bus{
+		subs:    make(map[uint64]*Subscription),
+		history: make([]Event, max(history, 0)),
+	}
+}
+
+// Subscribe - starts receiving events
+// published after the call.
+func (b *Bus) Subscribe(filter *Filter) *Subscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.subscribe(filter)
+}
+
+// SubscribeAfter - starts receiving events
+// and returns the buffered events published
+// after the sequence, so no event is lost
+// between them.
+func (b *Bus) SubscribeAfter(filter *Filter,
+	after uint64,
+) (*Subscription, []Event, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	oldest := b.seq - uint64(b.size) + 1
+
+	if after > b.seq || after+1 < oldest {
+		return nil, nil, ErrNotBuffered
+	}
+
+	missed := make([]Event, 0, b.seq-after)
+
+	for i := range b.size {
+		event := b.history[(b.head+i)%len(b.history)]
+
+		if event.Seq > after && filter.Match(&event.Metric) {
+			missed = append(missed, event)
+		}
+	}
+
+	return b.subscribe(filter), missed, nil
+}
+
+// subscribe - registers the subscription,
+// the caller holds the mutex.
+func (b *Bus) subscribe(filter *Filter) *Subscription {
+	b.nextID++
+
+	sub := &Subscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan Event, defBuffer),
+		id:     b.nextID,
+		start:  b.seq,
+	}
+
+	b.subs[sub.id] = sub
+
+	return sub
+}
+
+// Publish - notifies subscribers
+// about the new metric value.
+func (b *Bus) Publish(metric *apimodels.Metrics) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.seq++
+
+	event := Event{
+		Time:   time.Now(),
+		Metric: *metric,
+		Seq:    b.seq,
+	}
+
+	b.record(event)
+
+	for _, sub := range b.subs {
+		if !sub.filter.Match(metric) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub, ErrOverflow)
+		}
+	}
+}
+
+// record - adds the event to the history,
+// replacing the oldest one when it is full.
+func (b *Bus) record(event Event) {
+	if len(b.history) == 0 {
+		return
+	}
+
+	if b.size < len(b.history) {
+		b.history[(b.head+b.size)%len(b.history)] = event
+		b.size++
+
+		return
+	}
+
+	b.history[b.head] = event
+	b.head = (b.head + 1) % len(b.history)
+}
+
+// remove - closes the subscription,
+// the caller holds the mutex.
+func (b *Bus) remove(sub *Subscription, err error) {
+	_, ok := b.subs[sub.id]
+	if !ok {
+		return
+	}
+
+	delete(b.subs, sub.id)
+
+	sub.err = err
+	close(sub.events)
+}
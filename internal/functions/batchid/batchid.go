@@ -0,0 +1,82 @@
+// Package batchid provides identifiers
+// of the metric batches sent by agents,
+// so the server applies a batch only once.
+package batchid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Header - http header of the batch id.
+const Header = "X-Batch-ID"
+
+// MetadataKey - grpc metadata key of the batch id.
+const MetadataKey = "x-batch-id"
+
+// DuplicateHeader - http header set on the
+// response to a replayed batch.
+const DuplicateHeader = "X-Batch-Duplicate"
+
+// DuplicateKey - grpc header metadata key set
+// on the response to a replayed batch.
+const DuplicateKey = "x-batch-duplicate"
+
+// idBytes - random bytes of a generated id.
+const idBytes = 16
+
+// maxLen - maximum length of a received id.
+const maxLen = 64
+
+// ErrInvalid - returned for malformed ids.
+var ErrInvalid = errors.New("invalid batch id")
+
+// New - generates a random batch id.
+func New() (string, error) {
+	buf := make([]byte, idBytes)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("New->rand.Read: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Validate - checks the received batch id,
+// an empty id means the batch is not deduplicated.
+func Validate(id string) error {
+	if len(id) > maxLen {
+		return ErrInvalid
+	}
+
+	for _, sym := range id {
+		if !isIDSymbol(sym) {
+			return ErrInvalid
+		}
+	}
+
+	return nil
+}
+
+// FromMD - gets the batch id from grpc metadata.
+func FromMD(metad metadata.MD) string {
+	arr := metad.Get(MetadataKey)
+	if len(arr) == 0 {
+		return ""
+	}
+
+	return arr[0]
+}
+
+// isIDSymbol - letters, digits, '-' and '_'.
+func isIDSymbol(sym rune) bool {
+	return sym >= '0' && sym <= '9' ||
+		sym >= 'a' && sym <= 'z' ||
+		sym >= 'A' && sym <= 'Z' ||
+		sym == '-' || sym == '_'
+}
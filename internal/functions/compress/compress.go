@@ -7,6 +7,8 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
 )
 
 // DeflateCompress - produces deflate compression.
@@ -48,3 +50,26 @@ func DeflateDecompress(data io.Reader) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// DeflateDecompressLimit - produces deflate
+// decompression, failing with limit.ErrTooLarge
+// when the result exceeds maxSize bytes.
+func DeflateDecompressLimit(data io.Reader,
+	maxSize int64,
+) ([]byte, error) {
+	reader, err := gzip.NewReader(data)
+	if err != nil {
+		return nil, fmt.Errorf("DeflateDecompLim->NewR: %w", err)
+	}
+
+	defer reader.Close()
+
+	var buf bytes.Buffer
+
+	_, err = buf.ReadFrom(limit.NewReader(reader, maxSize))
+	if err != nil {
+		return nil, fmt.Errorf("DeflateDecompLim->Read: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
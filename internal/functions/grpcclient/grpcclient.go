@@ -0,0 +1,123 @@
+// Package grpcclient provides the
+// connection of the agent to the grpc server.
+package grpcclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+var errCA = errors.New("no CA certificates found")
+
+var errKeyPair = errors.New(
+	"client certificate and key must be set together")
+
+// New - creates the connection, the
+// server is dialed on the first request.
+func New(
+	cfg *bizmodels.GRPCClient,
+) (*grpc.ClientConn, error) {
+	opts, err := DialOptions(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("New->DialOptions: %w", err)
+	}
+
+	conn, err := grpc.NewClient(cfg.Addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("New->NewClient: %w", err)
+	}
+
+	return conn, nil
+}
+
+// DialOptions - returns the transport
+// credentials and keepalive options.
+func DialOptions(
+	cfg *bizmodels.GRPCClient,
+) ([]grpc.DialOption, error) {
+	creds := insecure.NewCredentials()
+
+	if UseTLS(cfg) {
+		tlsCfg, err := tlsConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("DialOptions->tlsConfig: %w", err)
+		}
+
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+	}
+
+	if cfg.Keepalive > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(
+			keepalive.ClientParameters{
+				Time:                cfg.Keepalive,
+				Timeout:             cfg.KeepaliveTimeout,
+				PermitWithoutStream: cfg.PermitWithoutStream,
+			}))
+	}
+
+	return opts, nil
+}
+
+// UseTLS - tells if the connection uses TLS.
+func UseTLS(cfg *bizmodels.GRPCClient) bool {
+	if cfg.TLS != nil {
+		return *cfg.TLS
+	}
+
+	return cfg.CAFile != "" || cfg.CertFile != ""
+}
+
+// tlsConfig - reads the CA
+// and the client certificate.
+func tlsConfig(
+	cfg *bizmodels.GRPCClient,
+) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tlsConfig->ReadFile: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tlsConfig: %w: %s",
+				errCA, cfg.CAFile)
+		}
+
+		tlsCfg.RootCAs = pool
+	}
+
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("tlsConfig: %w", errKeyPair)
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile,
+			cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tlsConfig->LoadKeyPair: %w", err)
+		}
+
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
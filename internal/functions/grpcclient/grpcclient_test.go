@@ -0,0 +1,169 @@
+package grpcclient_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/grpcclient"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const serverName = "metrics.internal"
+
+// writeCert - writes the self-signed
+// certificate and its key.
+func writeCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: serverName},
+		DNSNames:     []string{serverName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage: x509.KeyUsageDigitalSignature |
+			x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader,
+		tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	certPEM := pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	assert.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	assert.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+
+	return certPath, keyPath
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	certPath, keyPath := writeCert(t, t.TempDir())
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	assert.NoError(t, err)
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(
+		&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go server.Serve(listen)
+
+	defer server.Stop()
+
+	cfg := &bizmodels.GRPCClient{
+		Addr:       listen.Addr().String(),
+		CAFile:     certPath,
+		CertFile:   certPath,
+		KeyFile:    keyPath,
+		ServerName: serverName,
+		Keepalive:  time.Minute,
+	}
+
+	assert.NoError(t, check(cfg))
+
+	cfg.ServerName = ""
+	assert.Error(t, check(cfg))
+}
+
+// check - calls the health check.
+func check(cfg *bizmodels.GRPCClient) error {
+	conn, err := grpcclient.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx,
+		&healthpb.HealthCheckRequest{})
+
+	return err
+}
+
+func TestDialOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certPath, keyPath := writeCert(t, dir)
+
+	badCA := filepath.Join(dir, "bad.pem")
+	assert.NoError(t, os.WriteFile(badCA, []byte("x"), 0o600))
+
+	tlsOn, tlsOff := true, false
+
+	cases := []struct {
+		cfg bizmodels.GRPCClient
+		ok  bool
+	}{
+		{cfg: bizmodels.GRPCClient{}, ok: true},
+		{cfg: bizmodels.GRPCClient{TLS: &tlsOn}, ok: true},
+		{cfg: bizmodels.GRPCClient{
+			TLS: &tlsOff, CAFile: badCA,
+		}, ok: true},
+		{cfg: bizmodels.GRPCClient{CAFile: certPath}, ok: true},
+		{cfg: bizmodels.GRPCClient{CAFile: badCA}},
+		{cfg: bizmodels.GRPCClient{CAFile: dir + "/none"}},
+		{cfg: bizmodels.GRPCClient{CertFile: certPath}},
+		{cfg: bizmodels.GRPCClient{
+			CertFile: certPath, KeyFile: keyPath,
+		}, ok: true},
+	}
+
+	for _, tcase := range cases {
+		_, err := grpcclient.DialOptions(&tcase.cfg)
+		assert.Equal(t, tcase.ok, err == nil, tcase.cfg)
+	}
+
+	assert.False(t, grpcclient.UseTLS(&bizmodels.GRPCClient{}))
+	assert.False(t, grpcclient.UseTLS(&bizmodels.GRPCClient{
+		TLS: &tlsOff, CAFile: certPath,
+	}))
+}
@@ -5,6 +5,7 @@ package hash
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"hash"
 )
 
 // MakeHashSHA256 - caches data with key.
@@ -19,3 +20,9 @@ func MakeHashSHA256(dataMsg *[]byte,
 
 	return sign, nil
 }
+
+// NewHashSHA256 - returns a hash for computing
+// the signature of streamed data with key.
+func NewHashSHA256(key string) hash.Hash {
+	return hmac.New(sha256.New, []byte(key))
+}
@@ -0,0 +1,255 @@
+// Package identity provides the identity
+// of the agent and the attributes sent with
+// every batch, so the server can keep the
+// metrics of every agent apart.
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"google.golang.org/grpc/metadata"
+)
+
+// HeaderID - http header of the agent id.
+const HeaderID = "X-Agent-ID"
+
+// HeaderHostname - http header
+// of the agent hostname.
+const HeaderHostname = "X-Agent-Hostname"
+
+// HeaderVersion - http header
+// of the agent build version.
+const HeaderVersion = "X-Agent-Version"
+
+// HeaderOS - http header of the agent OS.
+const HeaderOS = "X-Agent-OS"
+
+// KeyID - grpc metadata key of the agent id.
+const KeyID = "x-agent-id"
+
+// KeyHostname - grpc metadata key
+// of the agent hostname.
+const KeyHostname = "x-agent-hostname"
+
+// KeyVersion - grpc metadata key
+// of the agent build version.
+const KeyVersion = "x-agent-version"
+
+// KeyOS - grpc metadata key of the agent OS.
+const KeyOS = "x-agent-os"
+
+// idBytes - random bytes of a generated id.
+const idBytes = 8
+
+// maxLen - maximum length of an id.
+const maxLen = 40
+
+// maxAttrLen - attributes are cut
+// to this length.
+const maxAttrLen = 255
+
+// fileMode - permissions of the id file.
+const fileMode = 0o600
+
+// dirMode - permissions of the
+// directory of the id file.
+const dirMode = 0o750
+
+// ErrInvalid - returned for malformed ids.
+var ErrInvalid = errors.New("invalid agent id")
+
+// New - generates a random agent id.
+func New() (string, error) {
+	buf := make([]byte, idBytes)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("New->rand.Read: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Validate - checks the agent id, letters
+// and digits only so the id can be part of
+// a metric name. An empty id means the
+// agent is not identified.
+func Validate(id string) error {
+	if len(id) > maxLen {
+		return ErrInvalid
+	}
+
+	for _, sym := range id {
+		if !isIDSymbol(sym) {
+			return ErrInvalid
+		}
+	}
+
+	return nil
+}
+
+// Load - reads the agent id from the file,
+// a new id is generated and saved when
+// the file does not exist, so the agent
+// keeps its id between restarts.
+func Load(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.TrimSpace(string(data))
+
+		err = Validate(id)
+		if err != nil || id == "" {
+			return "", fmt.Errorf("Load: %w: %s", ErrInvalid, path)
+		}
+
+		return id, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("Load->ReadFile: %w", err)
+	}
+
+	id, err := New()
+	if err != nil {
+		return "", fmt.Errorf("Load->New: %w", err)
+	}
+
+	err = save(path, id)
+	if err != nil {
+		return "", fmt.Errorf("Load->save: %w", err)
+	}
+
+	return id, nil
+}
+
+// save - writes the id to a temporary
+// file and renames it.
+func save(path, id string) error {
+	err := os.MkdirAll(filepath.Dir(path), dirMode)
+	if err != nil {
+		return fmt.Errorf("save->MkdirAll: %w", err)
+	}
+
+	tmp := path + ".tmp"
+
+	err = os.WriteFile(tmp, []byte(id+"\n"), fileMode)
+	if err != nil {
+		return fmt.Errorf("save->WriteFile: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("save->Rename: %w", err)
+	}
+
+	return nil
+}
+
+// SetHeaders - sets the http headers
+// of the agent attributes.
+func SetHeaders(
+	header http.Header,
+	agent *bizmodels.AgentInfo,
+) {
+	set := func(key, value string) {
+		if value != "" {
+			header.Set(key, value)
+		}
+	}
+
+	set(HeaderID, agent.ID)
+	set(HeaderHostname, agent.Hostname)
+	set(HeaderVersion, agent.Version)
+	set(HeaderOS, agent.OS)
+}
+
+// MD - returns the grpc metadata
+// of the agent attributes.
+func MD(agent *bizmodels.AgentInfo) metadata.MD {
+	metad := metadata.MD{}
+
+	set := func(key, value string) {
+		if value != "" {
+			metad.Set(key, value)
+		}
+	}
+
+	set(KeyID, agent.ID)
+	set(KeyHostname, agent.Hostname)
+	set(KeyVersion, agent.Version)
+	set(KeyOS, agent.OS)
+
+	return metad
+}
+
+// FromRequest - returns the agent
+// attributes of the http request.
+func FromRequest(
+	req *http.Request,
+) (bizmodels.AgentInfo, error) {
+	return parse(func(header, _ string) string {
+		return req.Header.Get(header)
+	})
+}
+
+// FromContext - returns the agent
+// attributes of the grpc request.
+func FromContext(
+	ctx context.Context,
+) (bizmodels.AgentInfo, error) {
+	metad, _ := metadata.FromIncomingContext(ctx)
+
+	return parse(func(_, key string) string {
+		arr := metad.Get(key)
+		if len(arr) == 0 {
+			return ""
+		}
+
+		return arr[0]
+	})
+}
+
+// parse - reads and checks the attributes
+// by the http header and the metadata key.
+func parse(
+	get func(header, key string) string,
+) (bizmodels.AgentInfo, error) {
+	agent := bizmodels.AgentInfo{
+		ID:       get(HeaderID, KeyID),
+		Hostname: cut(get(HeaderHostname, KeyHostname)),
+		Version:  cut(get(HeaderVersion, KeyVersion)),
+		OS:       cut(get(HeaderOS, KeyOS)),
+	}
+
+	err := Validate(agent.ID)
+	if err != nil {
+		return bizmodels.AgentInfo{}, err
+	}
+
+	return agent, nil
+}
+
+// cut - limits the attribute length.
+func cut(value string) string {
+	if len(value) > maxAttrLen {
+		return value[:maxAttrLen]
+	}
+
+	return value
+}
+
+// isIDSymbol - letters and digits.
+func isIDSymbol(sym rune) bool {
+	return sym >= '0' && sym <= '9' ||
+		sym >= 'a' && sym <= 'z' ||
+		sym >= 'A' && sym <= 'Z'
+}
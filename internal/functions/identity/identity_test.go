@@ -0,0 +1,88 @@
+package identity_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	id, err := identity.New()
+	assert.NoError(t, err)
+	assert.NoError(t, identity.Validate(id))
+	assert.NoError(t, identity.Validate(""))
+
+	for _, bad := range []string{
+		"a/b", "a_b", "a-b", strings.Repeat("a", 41),
+	} {
+		assert.ErrorIs(t, identity.Validate(bad),
+			identity.ErrInvalid, bad)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "agent", "id")
+
+	id, err := identity.Load(path)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	again, err := identity.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	err = os.WriteFile(path, []byte("a/b\n"), 0o600)
+	assert.NoError(t, err)
+
+	_, err = identity.Load(path)
+	assert.ErrorIs(t, err, identity.ErrInvalid)
+}
+
+func TestAttributes(t *testing.T) {
+	t.Parallel()
+
+	agent := bizmodels.AgentInfo{
+		ID:       "a1",
+		Hostname: "host",
+		Version:  "v1.0.0",
+		OS:       "linux",
+	}
+
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodPost, "/", nil)
+	assert.NoError(t, err)
+
+	identity.SetHeaders(req.Header, &agent)
+
+	got, err := identity.FromRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, agent, got)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		identity.MD(&agent))
+
+	got, err = identity.FromContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, agent, got)
+
+	got, err = identity.FromContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, bizmodels.AgentInfo{}, got)
+
+	req.Header.Set(identity.HeaderID, "a/b")
+
+	_, err = identity.FromRequest(req)
+	assert.ErrorIs(t, err, identity.ErrInvalid)
+}
@@ -0,0 +1,72 @@
+// Package jsonstream provides functions
+// for streaming decoding of metric arrays.
+package jsonstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+)
+
+// ErrEmpty - returned when the stream has no data.
+var ErrEmpty = errors.New("data is empty")
+
+// ErrNotArray - returned when the stream
+// does not contain a json array.
+var ErrNotArray = errors.New("data is not an array")
+
+// ErrTrailingData - returned when the stream
+// contains data after the array.
+var ErrTrailingData = errors.New("data after array")
+
+// DecodeMetrics - decodes the metric array element
+// by element and passes each metric to handle,
+// so the whole body never has to be buffered.
+func DecodeMetrics(reader io.Reader,
+	handle func(metric *apimodels.Metrics) error,
+) error {
+	dec := json.NewDecoder(reader)
+
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return ErrEmpty
+	}
+
+	if err != nil {
+		return fmt.Errorf("DecodeMetrics->Token: %w", err)
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok || delim != '[' {
+		return ErrNotArray
+	}
+
+	for dec.More() {
+		metric := apimodels.Metrics{}
+
+		err = dec.Decode(&metric)
+		if err != nil {
+			return fmt.Errorf("DecodeMetrics->Decode: %w", err)
+		}
+
+		err = handle(&metric)
+		if err != nil {
+			return fmt.Errorf("DecodeMetrics->handle: %w", err)
+		}
+	}
+
+	_, err = dec.Token()
+	if err != nil {
+		return fmt.Errorf("DecodeMetrics->Token: %w", err)
+	}
+
+	_, err = dec.Token()
+	if !errors.Is(err, io.EOF) {
+		return ErrTrailingData
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+// Package limit provides functions
+// working with size-limited readers.
+package limit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrTooLarge - returned when the data exceeds the limit.
+var ErrTooLarge = errors.New("data exceeds size limit")
+
+// Reader - reader that fails with ErrTooLarge
+// instead of silently truncating the data.
+type Reader struct {
+	r    io.Reader
+	err  error
+	left int64
+}
+
+// NewReader - wraps the reader with the limit.
+// A non-positive maxSize disables the limit.
+func NewReader(reader io.Reader, maxSize int64) io.Reader {
+	if maxSize <= 0 {
+		return reader
+	}
+
+	return &Reader{r: reader, left: maxSize}
+}
+
+// Read - reads at most the remaining limit,
+// one extra byte is used to detect overflow.
+func (l *Reader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+
+	n, err := l.r.Read(p)
+	if int64(n) <= l.left {
+		l.left -= int64(n)
+		l.err = err
+
+		return n, err //nolint:wrapcheck
+	}
+
+	n = int(l.left)
+	l.left = 0
+	l.err = ErrTooLarge
+
+	return n, l.err
+}
+
+// IsTooLarge - checks whether the error
+// was caused by exceeding a size limit.
+func IsTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+
+	return errors.Is(err, ErrTooLarge) ||
+		errors.As(err, &maxErr)
+}
@@ -0,0 +1,147 @@
+// Package pbconv provides functions
+// for converting metrics between
+// api models and typed protobuf messages.
+package pbconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MetricToPB - converts the api metric
+// into the protobuf message.
+func MetricToPB(metric *apimodels.Metrics,
+	tstamp *timestamppb.Timestamp,
+) *pb.Metric {
+	res := &pb.Metric{Id: metric.ID, Timestamp: tstamp}
+
+	switch {
+	case metric.MType == bizmodels.GaugeName &&
+		metric.Value != nil:
+		res.Value = &pb.Metric_Gauge{Gauge: *metric.Value}
+	case metric.MType == bizmodels.CounterName &&
+		metric.Delta != nil:
+		res.Value = &pb.Metric_Counter{Counter: *metric.Delta}
+	}
+
+	return res
+}
+
+// MetricFromPB - converts the protobuf
+// message into the api metric.
+func MetricFromPB(metric *pb.Metric) *apimodels.Metrics {
+	res := &apimodels.Metrics{ID: metric.GetId()}
+
+	switch val := metric.GetValue().(type) {
+	case *pb.Metric_Gauge:
+		res.MType = bizmodels.GaugeName
+		res.Value = &val.Gauge
+	case *pb.Metric_Counter:
+		res.MType = bizmodels.CounterName
+		res.Delta = &val.Counter
+	}
+
+	return res
+}
+
+// MetricsToPB - converts the api metrics
+// into protobuf messages with one timestamp.
+func MetricsToPB(arr *apimodels.ArrMetrics,
+	tstamp time.Time,
+) []*pb.Metric {
+	res := make([]*pb.Metric, 0, len(*arr))
+	pbTime := timestamppb.New(tstamp)
+
+	for i := range *arr {
+		res = append(res, MetricToPB(&(*arr)[i], pbTime))
+	}
+
+	return res
+}
+
+// signedMetric - canonical form of the
+// metric covered by the hash.
+type signedMetric struct {
+	Gauge     *float64          `json:"gauge,omitempty"`
+	Counter   *int64            `json:"counter,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ID        string            `json:"id"`
+	Timestamp string            `json:"timestamp,omitempty"`
+}
+
+// signedBatch - canonical form of the
+// batch covered by the hash.
+type signedBatch struct {
+	StreamID string         `json:"streamId,omitempty"`
+	Metrics  []signedMetric `json:"metrics"`
+	Seq      uint64         `json:"seq,omitempty"`
+}
+
+// BatchBytes - returns the JSON form of the
+// batch the hash is computed over. Unlike
+// the protobuf encoding it does not depend on
+// the library version, the hash is left out.
+func BatchBytes(batch *pb.Batch) ([]byte, error) {
+	data, err := json.Marshal(&signedBatch{
+		StreamID: batch.GetStreamId(),
+		Seq:      batch.GetSeq(),
+		Metrics:  signedMetrics(batch.GetMetrics()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("BatchBytes->Marshal: %w", err)
+	}
+
+	return data, nil
+}
+
+// RequestBytes - returns the JSON form of
+// the metrics of the request the hash
+// is computed over.
+func RequestBytes(
+	req *pb.SenderRequest,
+) ([]byte, error) {
+	data, err := json.Marshal(&signedBatch{
+		Metrics: signedMetrics(req.GetMetrics()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RequestBytes->Marshal: %w", err)
+	}
+
+	return data, nil
+}
+
+// signedMetrics - converts the metrics into
+// their canonical form, map keys are sorted
+// by the encoder.
+func signedMetrics(metrics []*pb.Metric) []signedMetric {
+	res := make([]signedMetric, 0, len(metrics))
+
+	for _, metric := range metrics {
+		signed := signedMetric{
+			ID:     metric.GetId(),
+			Labels: metric.GetLabels(),
+		}
+
+		switch val := metric.GetValue().(type) {
+		case *pb.Metric_Gauge:
+			signed.Gauge = &val.Gauge
+		case *pb.Metric_Counter:
+			signed.Counter = &val.Counter
+		}
+
+		if metric.GetTimestamp() != nil {
+			signed.Timestamp = metric.GetTimestamp().
+				AsTime().Format(time.RFC3339Nano)
+		}
+
+		res = append(res, signed)
+	}
+
+	return res
+}
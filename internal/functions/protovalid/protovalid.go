@@ -0,0 +1,684 @@
+// Package protovalid provides functions
+// for checking buf.validate constraints
+// declared in the service protos.
+//
+// Only the rules used by the protos are
+// supported: required fields and oneofs,
+// string length and pattern, double and
+// integer bounds, repeated and map sizes,
+// nested messages. Check reports the other
+// rules, so they fail the server at start
+// instead of being ignored.
+package protovalid
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dmitrovia/collector-metrics/pkg/buf/validate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrViolation - returned when
+// the message breaks its constraints.
+var ErrViolation = errors.New("constraint violation")
+
+// ErrUnsupported - returned by Check
+// for the rules Validate does not check.
+var ErrUnsupported = errors.New("unsupported constraint")
+
+//nolint:gochecknoglobals
+var patterns sync.Map
+
+// kindRules - the rules of the kinds
+// of the single values.
+//
+//nolint:gochecknoglobals
+var kindRules = map[protoreflect.Kind]protoreflect.Name{
+	protoreflect.StringKind: "string",
+	protoreflect.DoubleKind: "double",
+	protoreflect.Int64Kind:  "int64",
+	protoreflect.Uint64Kind: "uint64",
+	protoreflect.Int32Kind:  "int32",
+	protoreflect.Uint32Kind: "uint32",
+}
+
+// supported - the checked fields
+// of the rules of every type.
+//
+//nolint:gochecknoglobals
+var supported = map[protoreflect.Name][]protoreflect.Name{
+	"string":   {"min_len", "max_len", "pattern"},
+	"double":   {"finite", "gt", "gte", "lt", "lte"},
+	"int64":    {"gt", "gte", "lt", "lte"},
+	"uint64":   {"gt", "gte", "lt", "lte"},
+	"int32":    {"gt", "gte", "lt", "lte"},
+	"uint32":   {"gt", "gte", "lt", "lte"},
+	"repeated": {"min_items", "max_items", "items"},
+	"map":      {"min_pairs", "max_pairs", "keys", "values"},
+}
+
+// violations - collects constraint violations.
+type violations []string
+
+func (v *violations) add(path string, msg string) {
+	*v = append(*v, path+": "+msg)
+}
+
+// Validate - checks the message constraints.
+func Validate(msg proto.Message) error {
+	viol := make(violations, 0)
+
+	validateMessage(msg.ProtoReflect(), "", &viol)
+
+	if len(viol) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s",
+		ErrViolation, strings.Join(viol, "; "))
+}
+
+// Check - checks that the messages of the
+// files declare only the supported rules.
+func Check(files ...protoreflect.FileDescriptor) error {
+	for _, file := range files {
+		err := checkMessages(file.Messages())
+		if err != nil {
+			return fmt.Errorf("Check: %s: %w", file.Path(), err)
+		}
+	}
+
+	return nil
+}
+
+// checkMessages - checks the messages
+// and the nested ones.
+func checkMessages(
+	msgs protoreflect.MessageDescriptors,
+) error {
+	for i := range msgs.Len() {
+		err := checkMessage(msgs.Get(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkMessage - checks the rules
+// of the message, its oneofs and fields.
+func checkMessage(
+	desc protoreflect.MessageDescriptor,
+) error {
+	msgRules, _ := proto.GetExtension(desc.Options(),
+		validate.E_Message).(*validate.MessageConstraints)
+
+	err := onlyRules(msgRules, desc.FullName())
+	if err != nil {
+		return err
+	}
+
+	oneofs := desc.Oneofs()
+	for i := range oneofs.Len() {
+		oneof := oneofs.Get(i)
+
+		rules, _ := proto.GetExtension(oneof.Options(),
+			validate.E_Oneof).(*validate.OneofConstraints)
+
+		err := onlyRules(rules, oneof.FullName(), "required")
+		if err != nil {
+			return err
+		}
+	}
+
+	fields := desc.Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+
+		rules, _ := proto.GetExtension(field.Options(),
+			validate.E_Field).(*validate.FieldConstraints)
+
+		err := checkRules(field, rules, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return checkMessages(desc.Messages())
+}
+
+// checkRules - checks the rules of the field,
+// single tells the rules are of a repeated
+// item or of a map key or value.
+func checkRules(field protoreflect.FieldDescriptor,
+	rules *validate.FieldConstraints,
+	single bool,
+) error {
+	var err error
+
+	rules.ProtoReflect().Range(
+		func(desc protoreflect.FieldDescriptor,
+			val protoreflect.Value,
+		) bool {
+			err = checkRule(field, desc.Name(), val, single)
+
+			return err == nil
+		})
+
+	return err
+}
+
+// checkRule - checks that the rules
+// match the field and are supported.
+func checkRule(field protoreflect.FieldDescriptor,
+	name protoreflect.Name,
+	val protoreflect.Value,
+	single bool,
+) error {
+	if name == "required" {
+		return nil
+	}
+
+	if name != ruleName(field, single) {
+		return fmt.Errorf("%w: %s on %s",
+			ErrUnsupported, name, field.FullName())
+	}
+
+	var err error
+
+	val.Message().Range(
+		func(desc protoreflect.FieldDescriptor,
+			sub protoreflect.Value,
+		) bool {
+			err = checkTypeRule(field, name, desc.Name(), sub)
+
+			return err == nil
+		})
+
+	return err
+}
+
+// checkTypeRule - checks the field
+// of the rules of the type.
+func checkTypeRule(field protoreflect.FieldDescriptor,
+	rule, name protoreflect.Name,
+	val protoreflect.Value,
+) error {
+	items, _ := val.Interface().(protoreflect.Message)
+
+	switch {
+	case rule == "repeated" && name == "items":
+		return checkRules(field, toRules(items), true)
+	case rule == "map" && name == "keys":
+		return checkRules(field.MapKey(), toRules(items), true)
+	case rule == "map" && name == "values":
+		return checkRules(field.MapValue(), toRules(items), true)
+	case slices.Contains(supported[rule], name):
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s.%s on %s",
+		ErrUnsupported, rule, name, field.FullName())
+}
+
+// toRules - returns the field rules
+// of the repeated items or map entries.
+func toRules(
+	msg protoreflect.Message,
+) *validate.FieldConstraints {
+	if msg == nil {
+		return nil
+	}
+
+	rules, _ := msg.Interface().(*validate.FieldConstraints)
+
+	return rules
+}
+
+// ruleName - the name of the rules
+// of the field type.
+func ruleName(
+	field protoreflect.FieldDescriptor,
+	single bool,
+) protoreflect.Name {
+	switch {
+	case !single && field.IsList():
+		return "repeated"
+	case !single && field.IsMap():
+		return "map"
+	default:
+		return kindRules[field.Kind()]
+	}
+}
+
+// onlyRules - checks that only
+// the allowed rules are set.
+func onlyRules(rules proto.Message,
+	owner protoreflect.FullName,
+	allowed ...protoreflect.Name,
+) error {
+	var err error
+
+	rules.ProtoReflect().Range(
+		func(desc protoreflect.FieldDescriptor,
+			_ protoreflect.Value,
+		) bool {
+			if slices.Contains(allowed, desc.Name()) {
+				return true
+			}
+
+			err = fmt.Errorf("%w: %s on %s",
+				ErrUnsupported, desc.Name(), owner)
+
+			return false
+		})
+
+	return err
+}
+
+// validateMessage - checks oneofs and fields.
+func validateMessage(msg protoreflect.Message,
+	path string,
+	viol *violations,
+) {
+	desc := msg.Descriptor()
+
+	oneofs := desc.Oneofs()
+	for i := range oneofs.Len() {
+		oneof := oneofs.Get(i)
+
+		rules, _ := proto.GetExtension(oneof.Options(),
+			validate.E_Oneof).(*validate.OneofConstraints)
+		if rules.GetRequired() && msg.WhichOneof(oneof) == nil {
+			viol.add(path+string(oneof.Name()),
+				"exactly one field is required")
+		}
+	}
+
+	fields := desc.Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+
+		rules, _ := proto.GetExtension(field.Options(),
+			validate.E_Field).(*validate.FieldConstraints)
+
+		validateField(msg, field, rules,
+			path+string(field.Name()), viol)
+	}
+}
+
+// validateField - checks the field value,
+// unset fields with presence are skipped.
+func validateField(msg protoreflect.Message,
+	field protoreflect.FieldDescriptor,
+	rules *validate.FieldConstraints,
+	path string,
+	viol *violations,
+) {
+	if rules.GetRequired() && !msg.Has(field) {
+		viol.add(path, "value is required")
+
+		return
+	}
+
+	switch {
+	case field.IsList():
+		validateList(msg.Get(field).List(), field,
+			rules.GetRepeated(), path, viol)
+	case field.IsMap():
+		validateMap(msg.Get(field).Map(), field,
+			rules.GetMap(), path, viol)
+	case field.HasPresence() && !msg.Has(field):
+		return
+	default:
+		validateValue(msg.Get(field), field, rules, path, viol)
+	}
+}
+
+// validateList - checks repeated field rules.
+func validateList(list protoreflect.List,
+	field protoreflect.FieldDescriptor,
+	rules *validate.RepeatedRules,
+	path string,
+	viol *violations,
+) {
+	size := uint64(list.Len())
+
+	if rules != nil && rules.MinItems != nil &&
+		size < rules.GetMinItems() {
+		viol.add(path, "value must contain at least "+
+			strconv.FormatUint(rules.GetMinItems(), 10)+" item(s)")
+	}
+
+	if rules != nil && rules.MaxItems != nil &&
+		size > rules.GetMaxItems() {
+		viol.add(path, "value must contain no more than "+
+			strconv.FormatUint(rules.GetMaxItems(), 10)+" item(s)")
+	}
+
+	for i := range list.Len() {
+		validateValue(list.Get(i), field, rules.GetItems(),
+			path+"["+strconv.Itoa(i)+"]", viol)
+	}
+}
+
+// validateMap - checks map field rules.
+func validateMap(pairs protoreflect.Map,
+	field protoreflect.FieldDescriptor,
+	rules *validate.MapRules,
+	path string,
+	viol *violations,
+) {
+	size := uint64(pairs.Len())
+
+	if rules != nil && rules.MinPairs != nil &&
+		size < rules.GetMinPairs() {
+		viol.add(path, "map must be at least "+
+			strconv.FormatUint(rules.GetMinPairs(), 10)+
+			" entries")
+	}
+
+	if rules != nil && rules.MaxPairs != nil &&
+		size > rules.GetMaxPairs() {
+		viol.add(path, "map must be at most "+
+			strconv.FormatUint(rules.GetMaxPairs(), 10)+
+			" entries")
+	}
+
+	pairs.Range(func(key protoreflect.MapKey,
+		val protoreflect.Value,
+	) bool {
+		keyPath := path + "[" + key.String() + "]"
+
+		validateValue(key.Value(), field.MapKey(),
+			rules.GetKeys(), keyPath, viol)
+		validateValue(val, field.MapValue(),
+			rules.GetValues(), keyPath, viol)
+
+		return true
+	})
+}
+
+// validateValue - checks a single value
+// according to its kind.
+func validateValue(val protoreflect.Value,
+	field protoreflect.FieldDescriptor,
+	rules *validate.FieldConstraints,
+	path string,
+	viol *violations,
+) {
+	//nolint:exhaustive
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		validateString(val.String(), rules.GetString_(),
+			path, viol)
+	case protoreflect.DoubleKind:
+		validateDouble(val.Float(), rules.GetDouble(),
+			path, viol)
+	case protoreflect.Int64Kind:
+		validateInt64(val.Int(), rules.GetInt64(), path, viol)
+	case protoreflect.Uint64Kind:
+		validateUint64(val.Uint(), rules.GetUint64(),
+			path, viol)
+	case protoreflect.Int32Kind:
+		validateInt32(int32(val.Int()), rules.GetInt32(),
+			path, viol)
+	case protoreflect.Uint32Kind:
+		validateUint32(uint32(val.Uint()), rules.GetUint32(),
+			path, viol)
+	case protoreflect.MessageKind:
+		validateMessage(val.Message(), path+".", viol)
+	}
+}
+
+// validateString - checks string rules.
+func validateString(val string,
+	rules *validate.StringRules,
+	path string,
+	viol *violations,
+) {
+	if rules == nil {
+		return
+	}
+
+	size := uint64(utf8.RuneCountInString(val))
+
+	if rules.MinLen != nil && size < rules.GetMinLen() {
+		viol.add(path, "value length must be at least "+
+			strconv.FormatUint(rules.GetMinLen(), 10)+
+			" characters")
+	}
+
+	if rules.MaxLen != nil && size > rules.GetMaxLen() {
+		viol.add(path, "value length must be at most "+
+			strconv.FormatUint(rules.GetMaxLen(), 10)+
+			" characters")
+	}
+
+	if rules.Pattern != nil {
+		re, err := compilePattern(rules.GetPattern())
+		if err != nil {
+			viol.add(path, "invalid pattern")
+
+			return
+		}
+
+		if !re.MatchString(val) {
+			viol.add(path, "value does not match regex pattern `"+
+				rules.GetPattern()+"`")
+		}
+	}
+}
+
+// validateDouble - checks double rules.
+func validateDouble(val float64,
+	rules *validate.DoubleRules,
+	path string,
+	viol *violations,
+) {
+	if rules.GetFinite() &&
+		(math.IsNaN(val) || math.IsInf(val, 0)) {
+		viol.add(path, "value must be finite")
+
+		return
+	}
+
+	var bnd bounds[float64]
+
+	switch low := rules.GetGreaterThan().(type) {
+	case *validate.DoubleRules_Gt:
+		bnd.setLow(low.Gt, true)
+	case *validate.DoubleRules_Gte:
+		bnd.setLow(low.Gte, false)
+	}
+
+	switch high := rules.GetLessThan().(type) {
+	case *validate.DoubleRules_Lt:
+		bnd.setHigh(high.Lt, true)
+	case *validate.DoubleRules_Lte:
+		bnd.setHigh(high.Lte, false)
+	}
+
+	bnd.check(val, path, viol)
+}
+
+// validateInt64 - checks int64 rules.
+func validateInt64(val int64,
+	rules *validate.Int64Rules,
+	path string,
+	viol *violations,
+) {
+	var bnd bounds[int64]
+
+	switch low := rules.GetGreaterThan().(type) {
+	case *validate.Int64Rules_Gt:
+		bnd.setLow(low.Gt, true)
+	case *validate.Int64Rules_Gte:
+		bnd.setLow(low.Gte, false)
+	}
+
+	switch high := rules.GetLessThan().(type) {
+	case *validate.Int64Rules_Lt:
+		bnd.setHigh(high.Lt, true)
+	case *validate.Int64Rules_Lte:
+		bnd.setHigh(high.Lte, false)
+	}
+
+	bnd.check(val, path, viol)
+}
+
+// validateUint64 - checks uint64 rules.
+func validateUint64(val uint64,
+	rules *validate.UInt64Rules,
+	path string,
+	viol *violations,
+) {
+	var bnd bounds[uint64]
+
+	switch low := rules.GetGreaterThan().(type) {
+	case *validate.UInt64Rules_Gt:
+		bnd.setLow(low.Gt, true)
+	case *validate.UInt64Rules_Gte:
+		bnd.setLow(low.Gte, false)
+	}
+
+	switch high := rules.GetLessThan().(type) {
+	case *validate.UInt64Rules_Lt:
+		bnd.setHigh(high.Lt, true)
+	case *validate.UInt64Rules_Lte:
+		bnd.setHigh(high.Lte, false)
+	}
+
+	bnd.check(val, path, viol)
+}
+
+// validateInt32 - checks int32 rules.
+func validateInt32(val int32,
+	rules *validate.Int32Rules,
+	path string,
+	viol *violations,
+) {
+	var bnd bounds[int32]
+
+	switch low := rules.GetGreaterThan().(type) {
+	case *validate.Int32Rules_Gt:
+		bnd.setLow(low.Gt, true)
+	case *validate.Int32Rules_Gte:
+		bnd.setLow(low.Gte, false)
+	}
+
+	switch high := rules.GetLessThan().(type) {
+	case *validate.Int32Rules_Lt:
+		bnd.setHigh(high.Lt, true)
+	case *validate.Int32Rules_Lte:
+		bnd.setHigh(high.Lte, false)
+	}
+
+	bnd.check(val, path, viol)
+}
+
+// validateUint32 - checks uint32 rules.
+func validateUint32(val uint32,
+	rules *validate.UInt32Rules,
+	path string,
+	viol *violations,
+) {
+	var bnd bounds[uint32]
+
+	switch low := rules.GetGreaterThan().(type) {
+	case *validate.UInt32Rules_Gt:
+		bnd.setLow(low.Gt, true)
+	case *validate.UInt32Rules_Gte:
+		bnd.setLow(low.Gte, false)
+	}
+
+	switch high := rules.GetLessThan().(type) {
+	case *validate.UInt32Rules_Lt:
+		bnd.setHigh(high.Lt, true)
+	case *validate.UInt32Rules_Lte:
+		bnd.setHigh(high.Lte, false)
+	}
+
+	bnd.check(val, path, viol)
+}
+
+// number - kinds with bounds rules.
+type number interface {
+	int32 | int64 | uint32 | uint64 | float64
+}
+
+// bounds - lower and upper bounds of a number.
+type bounds[T number] struct {
+	low      T
+	high     T
+	hasLow   bool
+	hasHigh  bool
+	lowExcl  bool
+	highExcl bool
+}
+
+func (b *bounds[T]) setLow(low T, excl bool) {
+	b.low, b.hasLow, b.lowExcl = low, true, excl
+}
+
+func (b *bounds[T]) setHigh(high T, excl bool) {
+	b.high, b.hasHigh, b.highExcl = high, true, excl
+}
+
+// check - checks the value against the bounds.
+func (b *bounds[T]) check(val T,
+	path string,
+	viol *violations,
+) {
+	if b.hasLow &&
+		(val < b.low || (b.lowExcl && val == b.low)) {
+		viol.add(path, fmt.Sprintf("value must be greater than "+
+			orEqual(b.lowExcl)+"%v", b.low))
+	}
+
+	if b.hasHigh &&
+		(val > b.high || (b.highExcl && val == b.high)) {
+		viol.add(path, fmt.Sprintf("value must be less than "+
+			orEqual(b.highExcl)+"%v", b.high))
+	}
+}
+
+// orEqual - completes the message of inclusive bounds.
+func orEqual(excl bool) string {
+	if excl {
+		return ""
+	}
+
+	return "or equal to "
+}
+
+// compilePattern - compiles the pattern once.
+func compilePattern(
+	pattern string,
+) (*regexp.Regexp, error) {
+	cached, ok := patterns.Load(pattern)
+	if ok {
+		re, _ := cached.(*regexp.Regexp)
+
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compilePattern: %w", err)
+	}
+
+	patterns.Store(pattern, re)
+
+	return re, nil
+}
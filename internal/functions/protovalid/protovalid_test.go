@@ -0,0 +1,164 @@
+package protovalid_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/protovalid"
+	"github.com/dmitrovia/collector-metrics/pkg/buf/validate"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type testData struct {
+	req   *pb.SenderRequest
+	tn    string
+	exerr string
+}
+
+func gauge(id string, val float64) *pb.Metric {
+	return &pb.Metric{
+		Id:    id,
+		Value: &pb.Metric_Gauge{Gauge: val},
+	}
+}
+
+func getTestData() *[]testData {
+	labels := make(map[string]string, 1)
+	labels[""] = "value"
+
+	return &[]testData{
+		{
+			tn: "1",
+			req: &pb.SenderRequest{Metrics: []*pb.Metric{
+				gauge("A", 1),
+			}},
+		},
+		{
+			tn: "2", exerr: "metrics: value must contain at least",
+			req: &pb.SenderRequest{},
+		},
+		{
+			tn: "3", exerr: "metrics[0].id: value length must be",
+			req: &pb.SenderRequest{Metrics: []*pb.Metric{
+				gauge("", 1),
+			}},
+		},
+		{
+			tn: "4", exerr: "metrics[0].gauge: value must be finite",
+			req: &pb.SenderRequest{Metrics: []*pb.Metric{
+				gauge("A", math.NaN()),
+			}},
+		},
+		{
+			tn: "5", exerr: "metrics[0].value: exactly one field",
+			req: &pb.SenderRequest{Metrics: []*pb.Metric{{Id: "A"}}},
+		},
+		{
+			tn: "6", exerr: "metrics[0].labels[]: value length",
+			req: &pb.SenderRequest{Metrics: []*pb.Metric{{
+				Id:     "A",
+				Value:  &pb.Metric_Counter{Counter: 1},
+				Labels: labels,
+			}}},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Helper()
+	t.Parallel()
+
+	for _, test := range *getTestData() {
+		t.Run(test.tn, func(tobj *testing.T) {
+			tobj.Parallel()
+
+			err := protovalid.Validate(test.req)
+			if test.exerr == "" {
+				assert.NoError(tobj, err)
+
+				return
+			}
+
+			assert.ErrorIs(tobj, err, protovalid.ErrViolation)
+			assert.True(tobj,
+				strings.Contains(err.Error(), test.exerr), err)
+		})
+	}
+}
+
+// ruleFile - describes a message with
+// the field of the type and rules.
+func ruleFile(t *testing.T,
+	kind descriptorpb.FieldDescriptorProto_Type,
+	rules *validate.FieldConstraints,
+) protoreflect.FileDescriptor {
+	t.Helper()
+
+	opts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(opts, validate.E_Field, rules)
+
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:    proto.String("value"),
+		Number:  proto.Int32(1),
+		Type:    kind.Enum(),
+		Label:   label.Enum(),
+		Options: opts,
+	}
+	desc := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/rules.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Rules"),
+			Field: []*descriptorpb.FieldDescriptorProto{field},
+		}},
+	}
+
+	file, err := protodesc.NewFile(desc,
+		protoregistry.GlobalFiles)
+	assert.NoError(t, err)
+
+	return file
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, protovalid.Check(
+		pb.File_microservice_v2_metric_proto,
+		pb.File_microservice_v2_microservice_grpc_proto))
+
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	dbl := descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	stringRules := func(
+		rules *validate.StringRules,
+	) *validate.FieldConstraints {
+		return &validate.FieldConstraints{
+			Type: &validate.FieldConstraints_String_{
+				String_: rules,
+			},
+		}
+	}
+	minLen := stringRules(
+		&validate.StringRules{MinLen: proto.Uint64(1)})
+	prefix := stringRules(
+		&validate.StringRules{Prefix: proto.String("a")})
+
+	assert.NoError(t,
+		protovalid.Check(ruleFile(t, str, minLen)))
+	assert.ErrorIs(t,
+		protovalid.Check(ruleFile(t, str, prefix)),
+		protovalid.ErrUnsupported)
+	assert.ErrorIs(t,
+		protovalid.Check(ruleFile(t, dbl, minLen)),
+		protovalid.ErrUnsupported)
+}
@@ -0,0 +1,117 @@
+// Package source identifies the client
+// that sent the metrics, by the address
+// reported by the agent, the peer one or
+// the verified client certificate.
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Header - http header of the agent address.
+const Header = "X-Real-IP"
+
+// MetadataKey - grpc metadata key
+// of the agent address.
+const MetadataKey = "x-real-ip"
+
+// unknown - source of a request
+// without any address.
+const unknown = "unknown"
+
+// FromRequest - returns the source
+// of the http request.
+func FromRequest(req *http.Request) string {
+	realIP := req.Header.Get(Header)
+	if realIP != "" {
+		return realIP
+	}
+
+	return PeerFromRequest(req)
+}
+
+// FromContext - returns the source
+// of the grpc request.
+func FromContext(ctx context.Context) string {
+	metad, _ := metadata.FromIncomingContext(ctx)
+
+	arr := metad.Get(MetadataKey)
+	if len(arr) > 0 && arr[0] != "" {
+		return arr[0]
+	}
+
+	return PeerFromContext(ctx)
+}
+
+// PeerFromRequest - returns the address
+// the http request came from.
+func PeerFromRequest(req *http.Request) string {
+	return host(req.RemoteAddr)
+}
+
+// PeerFromContext - returns the address
+// the grpc request came from.
+func PeerFromContext(ctx context.Context) string {
+	pee, ok := peer.FromContext(ctx)
+	if !ok || pee.Addr == nil {
+		return unknown
+	}
+
+	return host(pee.Addr.String())
+}
+
+// CertFromRequest - returns the common name
+// of the verified client certificate of the
+// http request, empty without one.
+func CertFromRequest(req *http.Request) string {
+	return commonName(req.TLS)
+}
+
+// CertFromContext - returns the common name
+// of the verified client certificate of the
+// grpc request, empty without one.
+func CertFromContext(ctx context.Context) string {
+	pee, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	info, ok := pee.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ""
+	}
+
+	return commonName(&info.State)
+}
+
+// commonName - returns the common name of
+// the leaf of the first verified chain.
+func commonName(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 ||
+		len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return state.VerifiedChains[0][0].Subject.CommonName
+}
+
+// host - strips the port of the address.
+func host(addr string) string {
+	if addr == "" {
+		return unknown
+	}
+
+	hst, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return hst
+}
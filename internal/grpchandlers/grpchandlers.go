@@ -1,6 +1,7 @@
 package grpchandlers
 
 import (
+	"bytes"
 	"context"
 	"crypto/hmac"
 	"encoding/hex"
@@ -8,12 +9,19 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/dmitrovia/collector-metrics/internal/functions/batchid"
 	"github.com/dmitrovia/collector-metrics/internal/functions/hash"
-	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/functions/jsonstream"
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -28,29 +36,150 @@ type MicroserviceServer struct {
 	Serv   service.Service
 }
 
+// Sender - saves the received metrics and responds
+// with the accepted and rejected ones, followed by
+// all metrics known to the server unless only
+// the summary is requested.
 func (s *MicroserviceServer) Sender(
 	ctx context.Context,
 	req *pb.SenderRequest,
 ) (*pb.SenderResponse, error) {
-	response := &pb.SenderResponse{}
-
 	metad, _ := metadata.FromIncomingContext(ctx)
 
-	err := getReqData(req, &metad, s.Params, s.Serv)
+	agent, err := identity.FromContext(ctx)
 	if err != nil {
-		fmt.Println("Sender->getReqData: %w", err)
+		return nil, status.Error(codes.InvalidArgument,
+			err.Error())
+	}
 
-		return nil, status.Errorf(codes.Unknown, "getReqData")
+	opts := &ingest.Options{
+		Agent:  agent,
+		Source: ratelimit.ContextKey(ctx, s.Params.RateKey),
+		Atomic: req.GetAtomic(),
+	}
+
+	result, err := applyBatch(ctx, opts,
+		func() (*apimodels.IngestResult, error) {
+			return getReqData(req, &metad, s.Params, s.Serv, opts)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.SenderResponse{
+		Accepted:  uint32(result.Accepted),
+		Duplicate: result.Duplicate,
+	}
+
+	for _, rej := range result.Rejected {
+		response.Rejected = append(response.Rejected,
+			&pb.RejectedMetric{
+				Index:  uint32(rej.Index),
+				Id:     rej.ID,
+				Type:   rej.MType,
+				Reason: rej.Reason,
+			})
+	}
+
+	if req.GetSummaryOnly() {
+		return response, nil
 	}
 
 	err = writeResp(s.Serv, response)
 	if err != nil {
-		fmt.Println("SetMetricsJSONHandler->writeResp: %w", err)
+		fmt.Printf("SetMetricsJSONHandler->writeResp: %v\n", err)
 
-		return nil, status.Errorf(codes.Unknown, "writeResp")
+		return nil, status.Errorf(codes.Internal, "writeResp")
 	}
 
-	return nil, status.Errorf(codes.OK, "Success")
+	return response, nil
+}
+
+// applyBatch - applies the metrics once per
+// batch id, a replayed batch gets an empty
+// result marked as duplicate. The rejected
+// metrics of an atomic batch are returned
+// as the status details.
+func applyBatch(
+	ctx context.Context,
+	opts *ingest.Options,
+	apply func() (*apimodels.IngestResult, error),
+) (*apimodels.IngestResult, error) {
+	metad, _ := metadata.FromIncomingContext(ctx)
+	batchID := batchid.FromMD(metad)
+
+	err := batchid.Validate(batchID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument,
+			err.Error())
+	}
+
+	opts.BatchID = batchID
+
+	result, err := apply()
+	if errors.Is(err, ingest.ErrRejected) {
+		return nil, rejectedStatus(result)
+	}
+
+	if errors.Is(err, quota.ErrBatchTooLarge) {
+		return nil, status.Error(codes.ResourceExhausted,
+			err.Error())
+	}
+
+	if errors.Is(err, ingest.ErrStorage) {
+		fmt.Printf("applyBatch->apply: %v\n", err)
+
+		return nil, status.Error(codes.Internal, "storage error")
+	}
+
+	if err != nil {
+		fmt.Printf("applyBatch->apply: %v\n", err)
+
+		return nil, status.Errorf(codes.Unknown, "getReqData")
+	}
+
+	if result.Duplicate {
+		setDuplicateHeader(ctx)
+	}
+
+	return result, nil
+}
+
+// rejectedStatus - describes the rejected
+// metrics as field violations.
+func rejectedStatus(result *apimodels.IngestResult) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation,
+		0, len(result.Rejected))
+
+	for _, rej := range result.Rejected {
+		violations = append(violations,
+			&errdetails.BadRequest_FieldViolation{
+				Field:       fmt.Sprintf("metrics[%d]", rej.Index),
+				Description: rej.Reason,
+			})
+	}
+
+	stat := status.New(codes.InvalidArgument,
+		ingest.ErrRejected.Error())
+
+	detailed, err := stat.WithDetails(&errdetails.BadRequest{
+		FieldViolations: violations,
+	})
+	if err != nil {
+		return stat.Err()
+	}
+
+	return detailed.Err()
+}
+
+// setDuplicateHeader - marks the response
+// to a replayed batch.
+func setDuplicateHeader(ctx context.Context) {
+	err := grpc.SetHeader(ctx,
+		metadata.Pairs(batchid.DuplicateKey, "true"))
+	if err != nil {
+		fmt.Printf("setDuplicateHeader->SetHeader: %v\n", err)
+	}
 }
 
 // writeResp - writes the response
@@ -77,15 +206,14 @@ func writeResp(
 }
 
 // getReqData - receives metrics
-// from the request and validates it.
+// from the request and applies them.
 func getReqData(
 	req *pb.SenderRequest,
 	metad *metadata.MD,
 	params *bizmodels.InitParams,
 	serv service.Service,
-) error {
-	var results apimodels.ArrMetrics
-
+	opts *ingest.Options,
+) (*apimodels.IngestResult, error) {
 	Hashsha256 := ""
 	arrh := metad.Get("Hashsha256")
 
@@ -96,25 +224,29 @@ func getReqData(
 	err := checkHash(&req.Metrics,
 		Hashsha256, params.Key)
 	if err != nil {
-		return fmt.Errorf("getReqData->checkHash: %w", err)
+		return nil, fmt.Errorf("getReqData->checkHash: %w", err)
 	}
 
-	err = json.Unmarshal(req.GetMetrics(), &results)
+	arr := make(apimodels.ArrMetrics, 0)
+
+	err = jsonstream.DecodeMetrics(
+		bytes.NewReader(req.GetMetrics()),
+		func(metric *apimodels.Metrics) error {
+			arr = append(arr, *metric)
+
+			return nil
+		})
 	if err != nil {
-		return fmt.Errorf("getReqData->json.Unmarshal: %w", err)
+		return nil,
+			fmt.Errorf("getReqData->DecodeMetrics: %w", err)
 	}
 
-	for _, res := range results {
-		isValid := isValidJSONMetric(&res)
-		if isValid {
-			err = addValidMetric(&res, serv)
-			if err != nil {
-				fmt.Println(err)
-			}
-		}
+	result, err := ingest.Apply(serv, arr, opts)
+	if err != nil {
+		return result, fmt.Errorf("getReqData->Apply: %w", err)
 	}
 
-	return nil
+	return result, nil
 }
 
 // checkHash - checks the received
@@ -144,42 +276,3 @@ func checkHash(dataReq *[]byte,
 
 	return nil
 }
-
-// addValidMetric - adds the validated
-// metric to the service.
-func addValidMetric(res *apimodels.Metrics,
-	serv service.Service,
-) error {
-	if res.MType == bizmodels.GaugeName {
-		err := serv.AddGauge(res.ID, *res.Value)
-		if err != nil {
-			return fmt.Errorf("addValidMetric->AddGauge: %w", err)
-		}
-	} else if res.MType == bizmodels.CounterName {
-		_, err := serv.AddCounter(res.ID, *res.Delta,
-			false)
-		if err != nil {
-			return fmt.Errorf("addValidMetric->AddCounter: %w", err)
-		}
-	}
-
-	return nil
-}
-
-// isValidJSONMetric - for metric validation.
-func isValidJSONMetric(metric *apimodels.Metrics,
-) bool {
-	var pattern string
-
-	pattern = "^[0-9a-zA-Z/ ]{1,40}$"
-	res, _ := validate.IsMatchesTemplate(metric.ID, pattern)
-
-	if !res {
-		return false
-	}
-
-	pattern = "^" + bizmodels.MetricsPattern + "$"
-	res, _ = validate.IsMatchesTemplate(metric.MType, pattern)
-
-	return res
-}
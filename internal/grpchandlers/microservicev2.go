@@ -0,0 +1,148 @@
+package grpchandlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	pbv2 "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MicroserviceServerV2 - serves the typed
+// protobuf api, requests are validated
+// by the interceptor before reaching it.
+type MicroserviceServerV2 struct {
+	pbv2.UnimplementedMicroServiceServer
+
+	Params  *bizmodels.InitParams
+	Serv    service.Service
+	Streams *StreamRegistry
+}
+
+// Sender - saves the received metrics and responds
+// with the accepted and rejected ones, followed by
+// all metrics known to the server unless only
+// the summary is requested. A batch replayed
+// with the same id is not applied again.
+func (s *MicroserviceServerV2) Sender(
+	ctx context.Context,
+	req *pbv2.SenderRequest,
+) (*pbv2.SenderResponse, error) {
+	metad, _ := metadata.FromIncomingContext(ctx)
+
+	agent, err := identity.FromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument,
+			err.Error())
+	}
+
+	opts := &ingest.Options{
+		Agent:  agent,
+		Source: ratelimit.ContextKey(ctx, s.Params.RateKey),
+		Atomic: req.GetAtomic(),
+	}
+
+	result, err := applyBatch(ctx, opts,
+		func() (*apimodels.IngestResult, error) {
+			return getReqDataV2(req, &metad, s.Params, s.Serv,
+				opts)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pbv2.SenderResponse{
+		Accepted:  uint32(result.Accepted),
+		Duplicate: result.Duplicate,
+	}
+
+	for _, rej := range result.Rejected {
+		response.Rejected = append(response.Rejected,
+			&pbv2.RejectedMetric{
+				Index:  uint32(rej.Index),
+				Id:     rej.ID,
+				Type:   rej.MType,
+				Reason: rej.Reason,
+			})
+	}
+
+	if req.GetSummaryOnly() {
+		return response, nil
+	}
+
+	arr, err := s.Serv.GetAllMetricsAPI()
+	if err != nil {
+		fmt.Printf("SenderV2->GetAllMetricsAPI: %v\n", err)
+
+		return nil, status.Errorf(codes.Internal,
+			"GetAllMetricsAPI")
+	}
+
+	response.Metrics = pbconv.MetricsToPB(arr, time.Now())
+
+	return response, nil
+}
+
+// getReqDataV2 - checks the request hash
+// and applies the received metrics.
+// The hash is computed over the
+// canonical JSON form of the metrics.
+func getReqDataV2(
+	req *pbv2.SenderRequest,
+	metad *metadata.MD,
+	params *bizmodels.InitParams,
+	serv service.Service,
+	opts *ingest.Options,
+) (*apimodels.IngestResult, error) {
+	Hashsha256 := ""
+	arrh := metad.Get("Hashsha256")
+
+	if arrh != nil {
+		Hashsha256 = arrh[0]
+	}
+
+	if params.Key != "" && Hashsha256 != "" {
+		data, err := pbconv.RequestBytes(req)
+		if err != nil {
+			return nil,
+				fmt.Errorf("getReqDataV2->RequestBytes: %w", err)
+		}
+
+		err = checkHash(&data, Hashsha256, params.Key)
+		if err != nil {
+			return nil,
+				fmt.Errorf("getReqDataV2->checkHash: %w", err)
+		}
+	}
+
+	result, err := ingest.Apply(serv,
+		metricsFromPB(req.GetMetrics()), opts)
+	if err != nil {
+		return result, fmt.Errorf("getReqDataV2->Apply: %w", err)
+	}
+
+	return result, nil
+}
+
+// metricsFromPB - converts the received metrics.
+func metricsFromPB(
+	metrics []*pbv2.Metric,
+) apimodels.ArrMetrics {
+	arr := make(apimodels.ArrMetrics, 0, len(metrics))
+
+	for _, metric := range metrics {
+		arr = append(arr, *pbconv.MetricFromPB(metric))
+	}
+
+	return arr
+}
@@ -0,0 +1,93 @@
+package grpchandlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/batchid"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestSenderBatchReplay(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	client := newClient(t, dse)
+	req := &pb.SenderRequest{Metrics: []*pb.Metric{{
+		Id:    "Count",
+		Value: &pb.Metric_Counter{Counter: 2},
+	}}}
+
+	ctx := metadata.AppendToOutgoingContext(
+		context.Background(), batchid.MetadataKey, "batch-1")
+
+	for _, duplicate := range []string{"", "true"} {
+		var header metadata.MD
+
+		_, err := client.Sender(ctx, req, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Equal(t, duplicate,
+			first(header.Get(batchid.DuplicateKey)))
+	}
+
+	value, err := dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+}
+
+func TestSenderAtomic(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	client := newClient(t, dse)
+	req := &pb.SenderRequest{
+		Metrics: []*pb.Metric{
+			{Id: "Count", Value: &pb.Metric_Counter{Counter: 2}},
+			{Id: "Bad*", Value: &pb.Metric_Gauge{Gauge: 1}},
+		},
+		Atomic: true,
+	}
+
+	_, err := client.Sender(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	details := status.Convert(err).Details()
+	assert.Len(t, details, 1)
+
+	badReq, ok := details[0].(*errdetails.BadRequest)
+	assert.True(t, ok)
+	assert.Equal(t, "metrics[1]",
+		badReq.GetFieldViolations()[0].GetField())
+
+	req.Atomic = false
+	req.SummaryOnly = true
+
+	resp, err := client.Sender(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), resp.GetAccepted())
+	assert.Len(t, resp.GetRejected(), 1)
+	assert.Empty(t, resp.GetMetrics())
+}
+
+func first(arr []string) string {
+	if len(arr) == 0 {
+		return ""
+	}
+
+	return arr[0]
+}
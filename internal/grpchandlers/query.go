@@ -0,0 +1,210 @@
+package grpchandlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dmitrovia/collector-metrics/internal/eventbus"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/storage"
+	pbv2 "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defPageSize = 100
+
+var errPageToken = errors.New("invalid page token")
+
+var errMetricType = errors.New("unknown metric type")
+
+// GetMetric - returns the metric by type and id.
+func (s *MicroserviceServerV2) GetMetric(
+	_ context.Context,
+	req *pbv2.GetMetricRequest,
+) (*pbv2.GetMetricResponse, error) {
+	metric, err := s.getMetric(req.GetType(), req.GetId())
+	if isMissing(err) {
+		return nil, status.Errorf(codes.NotFound,
+			"metric not found")
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Internal,
+			"storage error")
+	}
+
+	return &pbv2.GetMetricResponse{
+		Metric: pbconv.MetricToPB(metric, timestamppb.Now()),
+	}, nil
+}
+
+// GetMetrics - returns the metrics by keys,
+// unknown keys are reported as missing.
+func (s *MicroserviceServerV2) GetMetrics(
+	_ context.Context,
+	req *pbv2.GetMetricsRequest,
+) (*pbv2.GetMetricsResponse, error) {
+	response := &pbv2.GetMetricsResponse{}
+	now := timestamppb.Now()
+
+	for _, key := range req.GetKeys() {
+		metric, err := s.getMetric(key.GetType(), key.GetId())
+		if isMissing(err) {
+			response.Missing = append(response.Missing, key)
+
+			continue
+		}
+
+		if err != nil {
+			return nil, status.Error(codes.Internal,
+				"storage error")
+		}
+
+		response.Metrics = append(response.Metrics,
+			pbconv.MetricToPB(metric, now))
+	}
+
+	return response, nil
+}
+
+// ListMetrics - returns one page of the metrics
+// ordered by type and id. The page token holds
+// the last returned key, so pages stay consistent
+// when metrics are added between requests.
+func (s *MicroserviceServerV2) ListMetrics(
+	_ context.Context,
+	req *pbv2.ListMetricsRequest,
+) (*pbv2.ListMetricsResponse, error) {
+	after, err := decodePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument,
+			err.Error())
+	}
+
+	arr, err := s.Serv.GetAllMetricsAPI()
+	if err != nil {
+		fmt.Printf("ListMetrics->GetAllMetricsAPI: %v\n", err)
+
+		return nil, status.Errorf(codes.Internal,
+			"GetAllMetricsAPI")
+	}
+
+	filter := eventbus.NewFilter(nil,
+		req.GetPrefix(), req.GetType())
+	page := make([]*apimodels.Metrics, 0, len(*arr))
+
+	for i := range *arr {
+		metric := &(*arr)[i]
+
+		if filter.Match(metric) &&
+			(after == nil || compareKeys(metric, after) > 0) {
+			page = append(page, metric)
+		}
+	}
+
+	slices.SortFunc(page, compareKeys)
+
+	size := int(req.GetPageSize())
+	if size == 0 {
+		size = defPageSize
+	}
+
+	response := &pbv2.ListMetricsResponse{}
+
+	if len(page) > size {
+		page = page[:size]
+		response.NextPageToken = encodePageToken(page[size-1])
+	}
+
+	now := timestamppb.Now()
+
+	for _, metric := range page {
+		response.Metrics = append(response.Metrics,
+			pbconv.MetricToPB(metric, now))
+	}
+
+	return response, nil
+}
+
+// getMetric - gets the metric value
+// from the service.
+func (s *MicroserviceServerV2) getMetric(
+	mtype, id string,
+) (*apimodels.Metrics, error) {
+	metric := &apimodels.Metrics{ID: id, MType: mtype}
+
+	switch mtype {
+	case bizmodels.GaugeName:
+		value, err := s.Serv.GetValueGM(id)
+		if err != nil {
+			return nil, fmt.Errorf("getMetric->GetValueGM: %w", err)
+		}
+
+		metric.Value = &value
+	case bizmodels.CounterName:
+		delta, err := s.Serv.GetValueCM(id)
+		if err != nil {
+			return nil, fmt.Errorf("getMetric->GetValueCM: %w", err)
+		}
+
+		metric.Delta = &delta
+	default:
+		return nil, errMetricType
+	}
+
+	return metric, nil
+}
+
+// isMissing - tells if the metric is not
+// stored, unlike a failure of the storage.
+func isMissing(err error) bool {
+	return errors.Is(err, storage.ErrNotFound) ||
+		errors.Is(err, errMetricType)
+}
+
+// compareKeys - orders metrics by type and id.
+func compareKeys(left, right *apimodels.Metrics) int {
+	res := strings.Compare(left.MType, right.MType)
+	if res != 0 {
+		return res
+	}
+
+	return strings.Compare(left.ID, right.ID)
+}
+
+// encodePageToken - encodes the key
+// of the last metric of the page.
+func encodePageToken(metric *apimodels.Metrics) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(metric.MType + "/" + metric.ID))
+}
+
+// decodePageToken - decodes the key
+// of the last metric of the previous page.
+func decodePageToken(
+	token string,
+) (*apimodels.Metrics, error) {
+	if token == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errPageToken
+	}
+
+	mtype, id, ok := strings.Cut(string(data), "/")
+	if !ok {
+		return nil, errPageToken
+	}
+
+	return &apimodels.Metrics{ID: id, MType: mtype}, nil
+}
@@ -0,0 +1,114 @@
+package grpchandlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/grpchandlers"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	client := newClient(t, dse)
+	ctx := context.Background()
+
+	for _, name := range []string{"C", "A", "D", "B"} {
+		assert.NoError(t, dse.AddGauge(name, 1))
+	}
+
+	_, err := dse.AddCounter("A", 2, false)
+	assert.NoError(t, err)
+
+	resp, err := client.GetMetric(ctx, &pb.GetMetricRequest{
+		Type: "counter", Id: "A",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), resp.GetMetric().GetCounter())
+
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{
+		Type: "counter", Id: "B",
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	batch, err := client.GetMetrics(ctx, &pb.GetMetricsRequest{
+		Keys: []*pb.MetricKey{
+			{Type: "gauge", Id: "B"},
+			{Type: "counter", Id: "B"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, batch.GetMetrics(), 1)
+	assert.Len(t, batch.GetMissing(), 1)
+
+	ids := make([]string, 0)
+	token := ""
+
+	for {
+		req := &pb.ListMetricsRequest{
+			Type:      "gauge",
+			PageSize:  3,
+			PageToken: token,
+		}
+
+		page, err := client.ListMetrics(ctx, req)
+		assert.NoError(t, err)
+
+		for _, metric := range page.GetMetrics() {
+			ids = append(ids, metric.GetId())
+		}
+
+		token = page.GetNextPageToken()
+		if token == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"A", "B", "C", "D"}, ids)
+}
+
+func TestQueryGateway(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	assert.NoError(t, dse.AddGauge("HeapAlloc/avg", 1.5))
+
+	hand := &grpchandlers.MicroserviceServerV2{}
+	hand.Params = &bizmodels.InitParams{}
+	hand.Serv = dse
+
+	mux := runtime.NewServeMux()
+	assert.NoError(t, pb.RegisterMicroServiceHandlerServer(
+		context.Background(), mux, hand))
+
+	cases := map[string]int{
+		"/v2/metrics/gauge/HeapAlloc/avg": http.StatusOK,
+		"/v2/metrics/gauge/HeapAlloc":     http.StatusNotFound,
+	}
+
+	for path, code := range cases {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+
+		mux.ServeHTTP(rec, req)
+		assert.Equal(t, code, rec.Code, path)
+	}
+}
@@ -0,0 +1,258 @@
+package grpchandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
+	"github.com/dmitrovia/collector-metrics/internal/functions/protovalid"
+	"github.com/dmitrovia/collector-metrics/internal/functions/source"
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+	pbv2 "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defStreamTTL - how long the last sequence
+// of an idle stream is kept for resumption.
+const defStreamTTL = time.Hour
+
+// streamKeySeparator - joins the owner and
+// the id of the stream in the registry.
+const streamKeySeparator = "/"
+
+var errUnsigned = errors.New("batch is not signed")
+
+// streamEntry - state of one agent stream.
+type streamEntry struct {
+	seen  time.Time
+	mutex sync.Mutex
+	last  uint64
+}
+
+// StreamRegistry - keeps the last applied
+// sequence of every stream, so batches resent
+// after a reconnect are not applied twice.
+type StreamRegistry struct {
+	entries map[string]*streamEntry
+	swept   time.Time
+	mutex   sync.Mutex
+	ttl     time.Duration
+}
+
+// NewStreamRegistry - to create an instance
+// of a registry object.
+func NewStreamRegistry(ttl time.Duration) *StreamRegistry {
+	if ttl <= 0 {
+		ttl = defStreamTTL
+	}
+
+	return &StreamRegistry{
+		entries: make(map[string]*streamEntry),
+		swept:   time.Now(),
+		ttl:     ttl,
+	}
+}
+
+// acquire - returns the locked stream entry,
+// idle entries are evicted along the way.
+func (r *StreamRegistry) acquire(
+	streamID string,
+) *streamEntry {
+	r.mutex.Lock()
+
+	now := time.Now()
+
+	if now.Sub(r.swept) > r.ttl {
+		for id, entry := range r.entries {
+			if entry.mutex.TryLock() {
+				if now.Sub(entry.seen) > r.ttl {
+					delete(r.entries, id)
+				}
+
+				entry.mutex.Unlock()
+			}
+		}
+
+		r.swept = now
+	}
+
+	entry, ok := r.entries[streamID]
+	if !ok {
+		entry = &streamEntry{}
+		r.entries[streamID] = entry
+	}
+
+	entry.seen = now
+	r.mutex.Unlock()
+
+	entry.mutex.Lock()
+
+	return entry
+}
+
+// StreamSender - receives metric batches
+// over a long-lived stream and acknowledges
+// each of them in order.
+func (s *MicroserviceServerV2) StreamSender(
+	stream pbv2.MicroService_StreamSenderServer,
+) error {
+	agent, err := identity.FromContext(stream.Context())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts := &ingest.Options{
+		Agent: agent,
+		Source: ratelimit.ContextKey(stream.Context(),
+			s.Params.RateKey),
+	}
+	owner := streamOwner(stream.Context(), &agent)
+
+	for {
+		batch, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("StreamSender->Recv: %w", err)
+		}
+
+		ack, err := s.handleBatch(batch, owner, opts)
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(ack)
+		if err != nil {
+			return fmt.Errorf("StreamSender->Send: %w", err)
+		}
+	}
+}
+
+// streamOwner - returns the agent the streams
+// are bound to, so a client cannot touch the
+// state of the streams of another one. Agents
+// without an id are told apart by the peer
+// address, which cannot be set by the client.
+func streamOwner(
+	ctx context.Context,
+	agent *bizmodels.AgentInfo,
+) string {
+	if agent.ID != "" {
+		return agent.ID
+	}
+
+	return source.PeerFromContext(ctx)
+}
+
+// handleBatch - applies the batch once
+// and builds its acknowledgement.
+// Invalid batches are acknowledged as rejected,
+// so the agent does not resend them forever.
+// Only a batch with a valid hash advances
+// the sequence of the stream of the owner.
+// A storage failure ends the stream without
+// advancing it, the agent resends the batch
+// after reconnecting.
+func (s *MicroserviceServerV2) handleBatch(
+	batch *pbv2.Batch,
+	owner string,
+	opts *ingest.Options,
+) (*pbv2.BatchAck, error) {
+	ack := &pbv2.BatchAck{
+		StreamId: batch.GetStreamId(),
+		Seq:      batch.GetSeq(),
+	}
+
+	err := protovalid.Validate(batch)
+	if err != nil {
+		ack.Rejected = uint32(len(batch.GetMetrics()))
+		ack.Error = err.Error()
+
+		return ack, nil
+	}
+
+	err = checkBatchHash(batch, s.Params.Key)
+	if err != nil {
+		ack.Rejected = uint32(len(batch.GetMetrics()))
+		ack.Error = err.Error()
+
+		return ack, nil
+	}
+
+	streamKey := owner + streamKeySeparator +
+		batch.GetStreamId()
+
+	entry := s.Streams.acquire(streamKey)
+	defer entry.mutex.Unlock()
+
+	if batch.GetSeq() <= entry.last {
+		ack.Duplicate = true
+
+		return ack, nil
+	}
+
+	// the batch id makes the metrics of
+	// the batch applied in one call.
+	batchOpts := *opts
+	batchOpts.BatchID = streamKey + streamKeySeparator +
+		strconv.FormatUint(batch.GetSeq(), 10)
+
+	result, err := ingest.Apply(s.Serv,
+		metricsFromPB(batch.GetMetrics()), &batchOpts)
+
+	switch {
+	case errors.Is(err, ingest.ErrStorage):
+		fmt.Printf("handleBatch->Apply: %v\n", err)
+
+		return nil, status.Error(codes.Unavailable,
+			ingest.ErrStorage.Error())
+	case err != nil:
+		ack.Rejected = uint32(len(batch.GetMetrics()))
+		ack.Error = err.Error()
+	case result.Duplicate:
+		ack.Duplicate = true
+	default:
+		ack.Accepted = uint32(result.Accepted)
+		ack.Rejected = uint32(len(result.Rejected))
+	}
+
+	entry.last = batch.GetSeq()
+
+	return ack, nil
+}
+
+// checkBatchHash - checks the batch hash,
+// computed over its canonical JSON form.
+// With a key batches must be signed.
+func checkBatchHash(batch *pbv2.Batch, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	if batch.GetHash() == "" {
+		return errUnsigned
+	}
+
+	data, err := pbconv.BatchBytes(batch)
+	if err != nil {
+		return fmt.Errorf("checkBatchHash->BatchBytes: %w", err)
+	}
+
+	err = checkHash(&data, batch.GetHash(), key)
+	if err != nil {
+		return fmt.Errorf("checkBatchHash->checkHash: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package grpchandlers_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/hash"
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
+	"github.com/dmitrovia/collector-metrics/internal/grpchandlers"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1 << 20
+
+const streamID = "agent1"
+
+func newClient(tobj *testing.T,
+	dse service.Service,
+) pb.MicroServiceClient {
+	tobj.Helper()
+
+	return newKeyClient(tobj, dse, "")
+}
+
+// newKeyClient - connects to the server
+// checking the hashes with the key.
+func newKeyClient(tobj *testing.T,
+	dse service.Service,
+	key string,
+) pb.MicroServiceClient {
+	tobj.Helper()
+
+	listen := bufconn.Listen(bufSize)
+	server := grpc.NewServer()
+
+	hand := &grpchandlers.MicroserviceServerV2{}
+	hand.Params = &bizmodels.InitParams{Key: key}
+	hand.Serv = dse
+	hand.Streams = grpchandlers.NewStreamRegistry(time.Minute)
+
+	pb.RegisterMicroServiceServer(server, hand)
+
+	go func() {
+		_ = server.Serve(listen)
+	}()
+
+	tobj.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(
+			func(context.Context, string) (net.Conn, error) {
+				return listen.Dial()
+			}),
+		grpc.WithTransportCredentials(
+			insecure.NewCredentials()))
+	assert.NoError(tobj, err)
+
+	tobj.Cleanup(func() { conn.Close() })
+
+	return pb.NewMicroServiceClient(conn)
+}
+
+func counterBatch(seq uint64, delta int64) *pb.Batch {
+	return &pb.Batch{
+		StreamId: streamID,
+		Seq:      seq,
+		Metrics: []*pb.Metric{{
+			Id:    "Count",
+			Value: &pb.Metric_Counter{Counter: delta},
+		}},
+	}
+}
+
+func sendBatch(tobj *testing.T,
+	stream pb.MicroService_StreamSenderClient,
+	batch *pb.Batch,
+) *pb.BatchAck {
+	tobj.Helper()
+
+	assert.NoError(tobj, stream.Send(batch))
+
+	ack, err := stream.Recv()
+	assert.NoError(tobj, err)
+
+	return ack
+}
+
+func TestStreamSender(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	client := newClient(t, dse)
+
+	stream, err := client.StreamSender(context.Background())
+	assert.NoError(t, err)
+
+	ack := sendBatch(t, stream, counterBatch(1, 5))
+	assert.Equal(t, uint64(1), ack.GetSeq())
+	assert.Equal(t, uint32(1), ack.GetAccepted())
+
+	ack = sendBatch(t, stream,
+		&pb.Batch{StreamId: streamID, Seq: 2})
+	assert.Equal(t, uint32(0), ack.GetAccepted())
+	assert.NotEmpty(t, ack.GetError())
+
+	assert.NoError(t, stream.CloseSend())
+
+	// reconnect and resend the applied batch
+	stream, err = client.StreamSender(context.Background())
+	assert.NoError(t, err)
+
+	ack = sendBatch(t, stream, counterBatch(1, 5))
+	assert.True(t, ack.GetDuplicate())
+	assert.Equal(t, uint32(0), ack.GetAccepted())
+
+	ack = sendBatch(t, stream, counterBatch(3, 2))
+	assert.False(t, ack.GetDuplicate())
+	assert.Equal(t, uint32(1), ack.GetAccepted())
+
+	assert.NoError(t, stream.CloseSend())
+
+	value, err := dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), value)
+}
+
+// signBatch - sets the hash of the batch.
+func signBatch(tobj *testing.T,
+	batch *pb.Batch,
+	key string,
+) {
+	tobj.Helper()
+
+	data, err := pbconv.BatchBytes(batch)
+	assert.NoError(tobj, err)
+
+	sum, err := hash.MakeHashSHA256(&data, key)
+	assert.NoError(tobj, err)
+
+	batch.Hash = hex.EncodeToString(sum)
+}
+
+// agentStream - opens the stream of the agent.
+func agentStream(tobj *testing.T,
+	client pb.MicroServiceClient,
+	agent string,
+) pb.MicroService_StreamSenderClient {
+	tobj.Helper()
+
+	ctx := metadata.AppendToOutgoingContext(
+		context.Background(), identity.KeyID, agent)
+
+	stream, err := client.StreamSender(ctx)
+	assert.NoError(tobj, err)
+
+	tobj.Cleanup(func() { _ = stream.CloseSend() })
+
+	return stream
+}
+
+func TestStreamSenderForgedSeq(t *testing.T) {
+	t.Parallel()
+
+	const key = "streamkey"
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	client := newKeyClient(t, dse, key)
+
+	forged := counterBatch(1000, 100)
+	forged.Hash = hex.EncodeToString([]byte("forged"))
+
+	stream := agentStream(t, client, "a1")
+
+	ack := sendBatch(t, stream, forged)
+	assert.NotEmpty(t, ack.GetError())
+	assert.False(t, ack.GetDuplicate())
+
+	// another agent using the same stream id
+	other := counterBatch(500, 1)
+	signBatch(t, other, key)
+
+	ack = sendBatch(t, agentStream(t, client, "a2"), other)
+	assert.Equal(t, uint32(1), ack.GetAccepted())
+
+	valid := counterBatch(1, 5)
+	signBatch(t, valid, key)
+
+	ack = sendBatch(t, stream, valid)
+	assert.False(t, ack.GetDuplicate())
+	assert.Equal(t, uint32(1), ack.GetAccepted())
+
+	// unsigned batches are rejected with a key
+	ack = sendBatch(t, stream, counterBatch(2, 7))
+	assert.NotEmpty(t, ack.GetError())
+	assert.Equal(t, uint32(0), ack.GetAccepted())
+
+	value, err := dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), value)
+}
+
+var errDown = errors.New("storage is down")
+
+// failingService - service whose batches
+// fail until it is repaired.
+type failingService struct {
+	service.Service
+	down atomic.Bool
+}
+
+func (s *failingService) AddBatch(id string,
+	gms map[string]bizmodels.Gauge,
+	cms map[string]bizmodels.Counter,
+) (bool, error) {
+	if s.down.Load() {
+		return false, errDown
+	}
+
+	return s.Service.AddBatch(id, gms, cms)
+}
+
+func TestStreamSenderStorageFailure(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	serv := &failingService{Service: dse}
+	serv.down.Store(true)
+
+	client := newClient(t, serv)
+
+	stream := agentStream(t, client, "a1")
+	assert.NoError(t, stream.Send(counterBatch(1, 5)))
+
+	_, err := stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// the batch is resent after a reconnect
+	serv.down.Store(false)
+
+	ack := sendBatch(t, agentStream(t, client, "a1"),
+		counterBatch(1, 5))
+	assert.False(t, ack.GetDuplicate())
+	assert.Equal(t, uint32(1), ack.GetAccepted())
+
+	value, err := dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+}
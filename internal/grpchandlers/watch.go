@@ -0,0 +1,81 @@
+package grpchandlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dmitrovia/collector-metrics/internal/eventbus"
+	"github.com/dmitrovia/collector-metrics/internal/functions/pbconv"
+	pbv2 "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Watch - streams the current values of
+// the matching metrics and then their changes.
+// The subscription starts before the current
+// values are read, so no change is missed.
+func (s *MicroserviceServerV2) Watch(
+	req *pbv2.WatchRequest,
+	stream pbv2.MicroService_WatchServer,
+) error {
+	filter := eventbus.NewFilter(req.GetIds(),
+		req.GetPrefix(), req.GetType())
+
+	sub := s.Serv.Subscribe(filter)
+	defer sub.Close()
+
+	arr, err := s.Serv.GetAllMetricsAPI()
+	if err != nil {
+		fmt.Printf("Watch->GetAllMetricsAPI: %v\n", err)
+
+		return status.Errorf(codes.Internal, "GetAllMetricsAPI")
+	}
+
+	now := timestamppb.Now()
+
+	for i := range *arr {
+		if !filter.Match(&(*arr)[i]) {
+			continue
+		}
+
+		err = stream.Send(&pbv2.MetricEvent{
+			Snapshot: true,
+			Metric:   pbconv.MetricToPB(&(*arr)[i], now),
+		})
+		if err != nil {
+			return fmt.Errorf("Watch->Send: %w", err)
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return watchEndStatus(sub.Err())
+			}
+
+			err = stream.Send(&pbv2.MetricEvent{
+				Seq: event.Seq,
+				Metric: pbconv.MetricToPB(&event.Metric,
+					timestamppb.New(event.Time)),
+			})
+			if err != nil {
+				return fmt.Errorf("Watch->Send: %w", err)
+			}
+		}
+	}
+}
+
+// watchEndStatus - converts the reason
+// the subscription ended into a status.
+func watchEndStatus(err error) error {
+	if errors.Is(err, eventbus.ErrOverflow) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	return status.Error(codes.Unavailable, "watch ended")
+}
@@ -0,0 +1,59 @@
+package grpchandlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	client := newClient(t, dse)
+
+	assert.NoError(t, dse.AddGauge("Alloc", 1))
+	assert.NoError(t, dse.AddGauge("Other", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &pb.WatchRequest{
+		Ids: []string{"Alloc", "Count"},
+	})
+	assert.NoError(t, err)
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.True(t, event.GetSnapshot())
+	assert.Equal(t, "Alloc", event.GetMetric().GetId())
+
+	assert.NoError(t, dse.AddGauge("Other", 2))
+	assert.NoError(t, dse.AddMetrics(
+		map[string]bizmodels.Gauge{},
+		map[string]bizmodels.Counter{
+			"Count": {Name: "Count", Value: 3},
+		}))
+
+	_, err = dse.AddCounter("Count", 4, false)
+	assert.NoError(t, err)
+
+	event, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.False(t, event.GetSnapshot())
+	assert.Equal(t, "Count", event.GetMetric().GetId())
+	assert.Equal(t, int64(3), event.GetMetric().GetCounter())
+
+	event, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), event.GetMetric().GetCounter())
+}
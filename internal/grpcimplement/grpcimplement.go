@@ -1,26 +1,44 @@
 package grpcimplement
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/dmitrovia/collector-metrics/internal/Interceptors/decompressinterceptor"
 	"github.com/dmitrovia/collector-metrics/internal/Interceptors/decryptinterceptor"
+	"github.com/dmitrovia/collector-metrics/internal/Interceptors/ratelimitinterceptor"
+	"github.com/dmitrovia/collector-metrics/internal/Interceptors/validateinterceptor"
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/functions/protovalid"
+	"github.com/dmitrovia/collector-metrics/internal/functions/source"
 	"github.com/dmitrovia/collector-metrics/internal/grpchandlers"
 	"github.com/dmitrovia/collector-metrics/internal/logger"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/bodylimitmid"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/nodeadlinemid"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/ratelimitmid"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
 	si "github.com/dmitrovia/collector-metrics/internal/serverimplement"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v1"
+	pbv2 "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -35,6 +53,16 @@ const wTimeout = 60
 
 const iTimeout = 60
 
+const gatewayUpdatesRoute = "/v1/updates"
+
+const gatewayWatchRoute = "/v2/watch"
+
+var errPeerCert = errors.New(
+	"grpc server certificate does not match")
+
+var errClientCA = errors.New(
+	"no client CA certificates found")
+
 // RunServer - starts the server.
 func RunGRPCServer(grpcServer *grpc.Server,
 	params *bizmodels.InitParams,
@@ -57,6 +85,13 @@ func RunGRPCServer(grpcServer *grpc.Server,
 
 	pb.RegisterMicroServiceServer(grpcServer, hand)
 
+	handV2 := &grpchandlers.MicroserviceServerV2{}
+	handV2.Params = params
+	handV2.Serv = dse
+	handV2.Streams = grpchandlers.NewStreamRegistry(0)
+
+	pbv2.RegisterMicroServiceServer(grpcServer, handV2)
+
 	err = grpcServer.Serve(listen)
 	if err != nil {
 		log.Printf("RunGRPCServer->Serve: %s\n", err)
@@ -71,7 +106,8 @@ func InitiateServer(
 	mser *service.DS,
 	server *http.Server,
 ) error {
-	mux := runtime.NewServeMux()
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headerMatcher))
 
 	hand := &grpchandlers.MicroserviceServer{}
 	hand.Params = par
@@ -85,9 +121,40 @@ func InitiateServer(
 		return fmt.Errorf("InitiateServer->Register: %w", err)
 	}
 
+	creds, err := gatewayCreds(par)
+	if err != nil {
+		return fmt.Errorf("InitiateServer->gatewayCreds: %w", err)
+	}
+
+	// v2 is proxied through the gRPC server,
+	// so requests pass the validation interceptor.
+	err = pbv2.RegisterMicroServiceHandlerFromEndpoint(
+		context.Background(),
+		mux,
+		"localhost:"+par.GRPCPort,
+		[]grpc.DialOption{grpc.WithTransportCredentials(creds)})
+	if err != nil {
+		return fmt.Errorf("InitiateServer->RegisterV2: %w", err)
+	}
+
+	limits := par.GetBodyLimits(gatewayUpdatesRoute)
+	gateway := bodylimitmid.BodyLimitMiddleware(limits)(mux)
+
+	// v1 is served in process and
+	// does not pass the interceptors.
+	limiter := ratelimit.NewSet(par).Get(gatewayUpdatesRoute)
+
+	root := http.NewServeMux()
+	root.Handle("/", gateway)
+	root.Handle(gatewayUpdatesRoute,
+		ratelimitmid.RateLimitMiddleware(limiter,
+			par.RateKey)(gateway))
+	root.Handle(gatewayWatchRoute,
+		nodeadlinemid.NoDeadlineMiddleware(gateway))
+
 	*server = http.Server{
 		Addr:         par.PORT,
-		Handler:      mux,
+		Handler:      root,
 		ErrorLog:     nil,
 		ReadTimeout:  rTimeout * time.Second,
 		WriteTimeout: wTimeout * time.Second,
@@ -109,6 +176,117 @@ func InitiateServer(
 	return nil
 }
 
+// gatewayCreds - returns the credentials the
+// gateway dials the grpc server with. With TLS
+// the server must present its own certificate.
+func gatewayCreds(
+	par *bizmodels.InitParams,
+) (credentials.TransportCredentials, error) {
+	if par.GRPCCertFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(par.GRPCCertFile,
+		par.GRPCKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("gatewayCreds->LoadPair: %w", err)
+	}
+
+	// the certificate is pinned, its
+	// host names are not checked.
+	//nolint:gosec
+	return credentials.NewTLS(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(
+			raw [][]byte, _ [][]*x509.Certificate,
+		) error {
+			if len(raw) == 0 ||
+				!bytes.Equal(raw[0], cert.Certificate[0]) {
+				return errPeerCert
+			}
+
+			return nil
+		},
+	}), nil
+}
+
+// serverCreds - returns the TLS option of the
+// grpc server, none without a certificate.
+// With the client CA the certificates the
+// agents present are verified, the agents
+// without one are still served.
+func serverCreds(
+	par *bizmodels.InitParams,
+) ([]grpc.ServerOption, error) {
+	if par.GRPCCertFile == "" && par.GRPCKeyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(par.GRPCCertFile,
+		par.GRPCKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("serverCreds->LoadPair: %w", err)
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if par.GRPCClientCAFile != "" {
+		pem, err := os.ReadFile(par.GRPCClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("serverCreds->ReadFile: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("serverCreds: %w: %s",
+				errClientCA, par.GRPCClientCAFile)
+		}
+
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return []grpc.ServerOption{
+		grpc.Creds(credentials.NewTLS(tlsCfg)),
+	}, nil
+}
+
+// headerMatcher - passes the agent address
+// and attributes to the grpc methods as well.
+func headerMatcher(key string) (string, bool) {
+	switch mdKey := strings.ToLower(key); mdKey {
+	case source.MetadataKey, identity.KeyID,
+		identity.KeyHostname, identity.KeyVersion,
+		identity.KeyOS:
+		return mdKey, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// MaxRecvMsgSize - returns the largest compressed
+// body limit, methods with lower limits are
+// checked by the interceptors.
+func MaxRecvMsgSize(params *bizmodels.InitParams) int {
+	const defMaxRecvMsgSize = 4 << 20
+
+	maxSize := params.DefBodyLimits.MaxCompressed
+
+	for _, limits := range params.BodyLimits {
+		maxSize = max(maxSize, limits.MaxCompressed)
+	}
+
+	if maxSize <= 0 {
+		return defMaxRecvMsgSize
+	}
+
+	return int(maxSize)
+}
+
 //nolint:funlen
 func ServerProcess() error {
 	var (
@@ -129,12 +307,40 @@ func ServerProcess() error {
 	logger.DoInfoLog("Build date: "+buildDate, zlog)
 	logger.DoInfoLog("Build commit: "+buildCommit, zlog)
 
-	interceptors := make([]grpc.ServerOption, 0)
+	// the validator checks only some rules,
+	// the others must not be ignored.
+	err = protovalid.Check(
+		pbv2.File_microservice_v2_metric_proto,
+		pbv2.File_microservice_v2_microservice_grpc_proto)
+	if err != nil {
+		return fmt.Errorf("protovalid.Check: %w", err)
+	}
+
+	interceptors, err := serverCreds(params)
+	if err != nil {
+		return fmt.Errorf("serverCreds: %w", err)
+	}
+
+	limiters := ratelimit.NewSet(params)
+	limited := []string{
+		pb.MicroService_Sender_FullMethodName,
+		pbv2.MicroService_Sender_FullMethodName,
+		pbv2.MicroService_StreamSender_FullMethodName,
+	}
 
 	interceptors = append(interceptors,
+		grpc.MaxRecvMsgSize(MaxRecvMsgSize(params)),
 		grpc.ChainUnaryInterceptor(
+			ratelimitinterceptor.RateLimitInterceptor(
+				limiters, params.RateKey, limited),
 			decryptinterceptor.DecryptInterceptor(params),
-			decompressinterceptor.DecompressInterceptor(),
+			decompressinterceptor.DecompressInterceptor(params),
+			validateinterceptor.ValidateInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			ratelimitinterceptor.StreamInterceptor(
+				limiters, params.RateKey, limited),
+			validateinterceptor.StreamInterceptor(),
 		))
 	grpcServer := grpc.NewServer(interceptors...)
 
@@ -15,6 +15,7 @@ import (
 	"github.com/dmitrovia/collector-metrics/internal/logger"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/gzipcompressmiddleware"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/loggermiddleware"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
 	"github.com/gorilla/mux"
@@ -42,7 +43,9 @@ func initiate(
 
 	defaultMux := mux.Methods(http.MethodGet).Subrouter()
 	defaultMux.HandleFunc("/", hDefault.DefaultHandler)
-	defaultMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	defaultMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(
+			bizmodels.BodyLimits{}),
 		loggermiddleware.RequestLogger(zapLogger))
 
 	return nil
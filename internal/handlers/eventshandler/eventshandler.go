@@ -0,0 +1,266 @@
+// Package eventshandler provides handler
+// to stream metric changes as Server-Sent Events.
+package eventshandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/eventbus"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+)
+
+// retryMillis - reconnect delay advised to clients.
+const retryMillis = 3000
+
+// snapshotEvent - event name of the current values.
+const snapshotEvent = "snapshot"
+
+// metricEvent - event name of the metric changes.
+const metricEvent = "metric"
+
+// errorEvent - event name sent before
+// the stream is ended by the server.
+const errorEvent = "error"
+
+var errEventID = errors.New("invalid Last-Event-ID")
+
+var errMetricType = errors.New("unknown metric type")
+
+var errNamePattern = errors.New("invalid name pattern")
+
+// EventsHandler - describing the handler.
+type EventsHandler struct {
+	serv      service.Service
+	done      chan struct{}
+	closeOnce sync.Once
+	heartbeat time.Duration
+}
+
+// NewEventsHandler - to create an instance
+// of a handler object.
+func NewEventsHandler(
+	s service.Service,
+	heartbeat time.Duration,
+) *EventsHandler {
+	return &EventsHandler{
+		serv:      s,
+		done:      make(chan struct{}),
+		heartbeat: heartbeat,
+	}
+}
+
+// Close - ends the open streams, the server
+// shutdown does not wait for them otherwise.
+func (h *EventsHandler) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
+
+// EventsHandler - main handler method.
+// Streams the current values of the matching
+// metrics and then their changes. Every event
+// has an id, a client reconnecting with
+// Last-Event-ID gets the missed events from
+// the server buffer, or a new snapshot when
+// they are no longer buffered.
+func (h *EventsHandler) EventsHandler(
+	writer http.ResponseWriter,
+	req *http.Request,
+) {
+	filter, err := getFilter(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	sub, missed, snapshot, err := h.subscribe(req, filter)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+	defer sub.Close()
+
+	ctrl := http.NewResponseController(writer)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+	fmt.Fprintf(writer, "retry: %d\n\n", retryMillis)
+
+	err = h.writeStart(writer, sub, missed, snapshot, filter)
+	if err != nil {
+		fmt.Println("EventsHandler->writeStart: %w", err)
+
+		return
+	}
+
+	h.stream(writer, ctrl, req, sub)
+}
+
+// subscribe - starts the subscription, resuming
+// after Last-Event-ID when it is buffered.
+func (h *EventsHandler) subscribe(
+	req *http.Request,
+	filter *eventbus.Filter,
+) (*eventbus.Subscription, []eventbus.Event, bool, error) {
+	lastID := req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		return h.serv.Subscribe(filter), nil, true, nil
+	}
+
+	after, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return nil, nil, false, errEventID
+	}
+
+	sub, missed, err := h.serv.SubscribeAfter(filter, after)
+	if err != nil {
+		return h.serv.Subscribe(filter), nil, true, nil
+	}
+
+	return sub, missed, false, nil
+}
+
+// writeStart - writes the snapshot
+// or the missed events.
+func (h *EventsHandler) writeStart(
+	writer http.ResponseWriter,
+	sub *eventbus.Subscription,
+	missed []eventbus.Event,
+	snapshot bool,
+	filter *eventbus.Filter,
+) error {
+	if !snapshot {
+		for i := range missed {
+			err := writeEvent(writer, metricEvent,
+				missed[i].Seq, &missed[i].Metric)
+			if err != nil {
+				return fmt.Errorf("writeStart->writeEvent: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	arr, err := h.serv.GetAllMetricsAPI()
+	if err != nil {
+		return fmt.Errorf("writeStart->GetAllMetricsAPI: %w", err)
+	}
+
+	for i := range *arr {
+		if !filter.Match(&(*arr)[i]) {
+			continue
+		}
+
+		err = writeEvent(writer, snapshotEvent,
+			sub.Start(), &(*arr)[i])
+		if err != nil {
+			return fmt.Errorf("writeStart->writeEvent: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// stream - writes the events and heartbeats
+// until the client leaves or the
+// subscription ends.
+func (h *EventsHandler) stream(
+	writer http.ResponseWriter,
+	ctrl *http.ResponseController,
+	req *http.Request,
+	sub *eventbus.Subscription,
+) {
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		err := ctrl.Flush()
+		if err != nil {
+			fmt.Println("EventsHandler->Flush: %w", err)
+
+			return
+		}
+
+		select {
+		case <-req.Context().Done():
+			return
+		case <-h.done:
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprint(writer, ": heartbeat\n\n")
+		case event, ok := <-sub.Events():
+			if !ok {
+				fmt.Fprintf(writer, "event: %s\ndata: %s\n\n",
+					errorEvent, sub.Err())
+				_ = ctrl.Flush()
+
+				return
+			}
+
+			err = writeEvent(writer, metricEvent,
+				event.Seq, &event.Metric)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent - writes the metric as one event.
+func writeEvent(
+	writer http.ResponseWriter,
+	name string,
+	seq uint64,
+	metric *apimodels.Metrics,
+) error {
+	data, err := json.Marshal(metric)
+	if err != nil {
+		return fmt.Errorf("writeEvent->Marshal: %w", err)
+	}
+
+	_, err = fmt.Fprintf(writer,
+		"id: %d\nevent: %s\ndata: %s\n\n", seq, name, data)
+	if err != nil {
+		return fmt.Errorf("writeEvent->Fprintf: %w", err)
+	}
+
+	return nil
+}
+
+// getFilter - builds the filter from the
+// type and name pattern query parameters.
+func getFilter(
+	req *http.Request,
+) (*eventbus.Filter, error) {
+	query := req.URL.Query()
+	mtype := query.Get("type")
+	pattern := query.Get("name")
+
+	if mtype != "" && mtype != bizmodels.GaugeName &&
+		mtype != bizmodels.CounterName {
+		return nil, errMetricType
+	}
+
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return nil, errNamePattern
+	}
+
+	filter := eventbus.NewFilter(nil, "", mtype)
+	filter.Pattern = pattern
+
+	return filter, nil
+}
@@ -0,0 +1,206 @@
+package eventshandler_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/handlers/eventshandler"
+	"github.com/dmitrovia/collector-metrics/internal/logger"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/gzipcompressmiddleware"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/loggermiddleware"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const limit int64 = 1 << 20
+
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+func newServer(
+	t *testing.T,
+	dse *service.DS,
+) *httptest.Server {
+	t.Helper()
+
+	zapLogger, err := logger.Initialize("info")
+	assert.NoError(t, err)
+
+	hEvents := eventshandler.NewEventsHandler(dse,
+		time.Hour)
+
+	router := mux.NewRouter()
+	getEventsMux := router.Methods(http.MethodGet).Subrouter()
+	getEventsMux.HandleFunc("/events", hEvents.EventsHandler)
+	getEventsMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(
+			bizmodels.BodyLimits{
+				MaxCompressed: limit, MaxDecompressed: limit,
+			}),
+		loggermiddleware.RequestLogger(zapLogger))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// open - starts the stream, the gzip encoding
+// is requested explicitly, so the events are
+// only readable when every one is flushed.
+func open(
+	ctx context.Context,
+	t *testing.T,
+	url, lastID string,
+) *bufio.Reader {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodGet, url, nil)
+	assert.NoError(t, err)
+
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "gzip",
+		resp.Header.Get("Content-Encoding"))
+
+	zipReader, err := gzip.NewReader(resp.Body)
+	assert.NoError(t, err)
+
+	return bufio.NewReader(zipReader)
+}
+
+// next - reads the next event,
+// skipping the retry field.
+func next(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+
+	event := sseEvent{}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		if line == "" {
+			if event.name != "" {
+				return event
+			}
+
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ": ")
+
+		switch field {
+		case "id":
+			event.id = value
+		case "event":
+			event.name = value
+		case "data":
+			event.data = value
+		}
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	server := newServer(t, dse)
+
+	assert.NoError(t, dse.AddGauge("Alloc", 1))
+	assert.NoError(t, dse.AddGauge("Other", 1))
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Second)
+	defer cancel()
+
+	url := server.URL + "/events?type=gauge&name=A*"
+	reader := open(ctx, t, url, "")
+
+	event := next(t, reader)
+	assert.Equal(t, "snapshot", event.name)
+	assert.Equal(t, "2", event.id)
+	assert.Contains(t, event.data, `"id":"Alloc"`)
+
+	assert.NoError(t, dse.AddGauge("Other", 2))
+	assert.NoError(t, dse.AddGauge("Alloc", 2))
+	assert.NoError(t, dse.AddGauge("Alloc", 3))
+
+	event = next(t, reader)
+	assert.Equal(t, "metric", event.name)
+	assert.Equal(t, "4", event.id)
+
+	reader = open(ctx, t, url, "4")
+
+	event = next(t, reader)
+	assert.Equal(t, "metric", event.name)
+	assert.Equal(t, "5", event.id)
+	assert.Contains(t, event.data, `"value":3`)
+
+	resp, err := http.Get(server.URL + "/events?name=[")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEventsHandlerResumeUnwatched(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	server := newServer(t, dse)
+
+	assert.NoError(t, dse.AddGauge("Alloc", 1))
+
+	// written while no client is connected
+	assert.NoError(t, dse.AddMetrics(
+		map[string]bizmodels.Gauge{},
+		map[string]bizmodels.Counter{
+			"Count": {Name: "Count", Value: 3},
+		}))
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Second)
+	defer cancel()
+
+	reader := open(ctx, t, server.URL+"/events", "1")
+
+	event := next(t, reader)
+	assert.Equal(t, "metric", event.name)
+	assert.Equal(t, "2", event.id)
+	assert.Contains(t, event.data, `"id":"Count"`)
+	assert.Contains(t, event.data, `"delta":3`)
+}
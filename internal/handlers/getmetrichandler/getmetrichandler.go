@@ -7,8 +7,8 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	"github.com/gorilla/mux"
 )
@@ -49,7 +49,8 @@ func (h *GetMetricHandler) GetMetricHandler(
 	valMetr = &validMetric{}
 	getReqData(req, valMetr)
 
-	isValid := isValidMetric(valMetr, writer)
+	isValid := isValidMetric(valMetr, writer,
+		h.serv.Pipeline())
 	if !isValid {
 		return
 	}
@@ -79,25 +80,21 @@ func getReqData(r *http.Request, metric *validMetric) {
 	metric.mtype = mux.Vars(r)["metric_type"]
 }
 
-// isValidMetric - for metric validation.
+// isValidMetric - for metric validation,
+// the name is checked against the id
+// pattern of the service pipeline.
 func isValidMetric(
 	metric *validMetric,
 	writer http.ResponseWriter,
+	pipe *pipeline.Pipeline,
 ) bool {
-	var pattern string
-	pattern = "^[0-9a-zA-Z/ ]{1,40}$"
-	res, _ := validate.IsMatchesTemplate(metric.mname, pattern)
-
-	if !res {
+	if !pipe.ValidID(metric.mname) {
 		writer.WriteHeader(http.StatusNotFound)
 
 		return false
 	}
 
-	pattern = "^" + bizmodels.MetricsPattern + "$"
-	res, _ = validate.IsMatchesTemplate(metric.mtype, pattern)
-
-	if !res {
+	if !pipeline.ValidType(metric.mtype) {
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return false
@@ -9,9 +9,10 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 )
 
@@ -38,12 +39,18 @@ func (h *GetMetricJSONHandler) GetMetricJSONHandler(
 
 	met, err := getReqDataJSON(req)
 	if err != nil {
+		if limit.IsTooLarge(err) {
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return
 	}
 
-	isValid := isValidMetric(met)
+	isValid := isValidMetric(met, h.serv.Pipeline())
 	if !isValid {
 		writer.WriteHeader(http.StatusNotFound)
 
@@ -86,21 +93,14 @@ func getReqDataJSON(req *http.Request,
 	return &result, nil
 }
 
-// isValidMetric - for metric validation.
+// isValidMetric - for metric validation,
+// the name is checked against the id
+// pattern of the service pipeline.
 func isValidMetric(metric *apimodels.Metrics,
+	pipe *pipeline.Pipeline,
 ) bool {
-	var pattern string
-	pattern = "^[0-9a-zA-Z/ ]{1,40}$"
-	res, _ := validate.IsMatchesTemplate(metric.ID, pattern)
-
-	if !res {
-		return false
-	}
-
-	pattern = "^" + bizmodels.MetricsPattern + "$"
-	res, _ = validate.IsMatchesTemplate(metric.MType, pattern)
-
-	return res
+	return pipe.ValidID(metric.ID) &&
+		pipeline.ValidType(metric.MType)
 }
 
 // writeAns - writes the response
@@ -3,8 +3,6 @@ package getmetricjsonhandler_test
 import (
 	"bytes"
 	"context"
-	"database/sql"
-	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,9 +17,9 @@ import (
 	"github.com/dmitrovia/collector-metrics/internal/logger"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/gzipcompressmiddleware"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/loggermiddleware"
-	"github.com/dmitrovia/collector-metrics/internal/migrator"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/serverimplement"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	"github.com/dmitrovia/collector-metrics/internal/storage/dbrepository"
 	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
@@ -46,11 +44,6 @@ var errRuntimeCaller = errors.New("errRuntimeCaller")
 
 var errP = errors.New("path is not valid")
 
-const migrationsDir = "db/migrations"
-
-//go:embed db/migrations/*.sql
-var MigrationsFS embed.FS
-
 type testData struct {
 	tn     string
 	mt     string
@@ -233,13 +226,15 @@ func initiate(
 	getMJSONMux := mux.Methods(http.MethodPost).Subrouter()
 	getMJSONMux.HandleFunc("/value/",
 		hJSONGet.GetMetricJSONHandler)
-	getMJSONMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	getMJSONMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(
+			bizmodels.BodyLimits{}),
 		loggermiddleware.RequestLogger(zapLogger))
 
 	if isMemRepo {
 		LoadFile(dse, "test3.txt")
 	} else {
-		_ = UseMigrations(params)
+		_ = serverimplement.UseMigrations(params)
 
 		LoadFile(dse, "test2.txt")
 	}
@@ -249,32 +244,6 @@ func initiate(
 	return nil
 }
 
-func UseMigrations(par *bizmodels.InitParams) error {
-	if par.DatabaseDSN == "" {
-		return nil
-	}
-
-	migrator, err := migrator.MustGetNewMigrator(
-		MigrationsFS, migrationsDir)
-	if err != nil {
-		return fmt.Errorf("useMigrations->MustGet: %w", err)
-	}
-
-	conn, err := sql.Open("postgres", par.DatabaseDSN)
-	if err != nil {
-		return fmt.Errorf("useMigrations->sql.Open: %w", err)
-	}
-
-	defer conn.Close()
-
-	err = migrator.ApplyMigrations(conn)
-	if err != nil {
-		return fmt.Errorf("useMigrations->ApplyMig: %w", err)
-	}
-
-	return nil
-}
-
 func TestGetMetricJSONHandler(t *testing.T) {
 	t.Helper()
 	t.Parallel()
@@ -0,0 +1,51 @@
+// Package pipelinestatshandler provides handler
+// displays the metrics dropped by the
+// ingestion pipeline in json format.
+package pipelinestatshandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+)
+
+// PipelineStatsHandler - describing the handler.
+type PipelineStatsHandler struct {
+	serv service.Service
+}
+
+// NewPipelineStatsHandler - to create an instance
+// of a handler object.
+func NewPipelineStatsHandler(
+	s service.Service,
+) *PipelineStatsHandler {
+	return &PipelineStatsHandler{serv: s}
+}
+
+// PipelineStatsHandler - main handler method.
+// Responds with the dropped metrics per rule.
+func (h *PipelineStatsHandler) PipelineStatsHandler(
+	writer http.ResponseWriter, _ *http.Request,
+) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	marshal, err := json.Marshal(apimodels.PipelineStats{
+		Dropped: h.serv.Pipeline().Stats(),
+	})
+	if err != nil {
+		fmt.Println("PipelineStatsHandler->Marshal: %w", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write(marshal)
+	if err != nil {
+		fmt.Println("PipelineStatsHandler->Write: %w", err)
+	}
+}
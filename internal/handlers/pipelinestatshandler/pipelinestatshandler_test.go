@@ -0,0 +1,56 @@
+package pipelinestatshandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/handlers/pipelinestatshandler"
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const url string = "http://localhost:8080/pipeline/stats"
+
+func TestPipelineStatsHandler(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	value := 1.0
+
+	_, err := ingest.Apply(dse, apimodels.ArrMetrics{
+		{ID: "Bad*", MType: "gauge", Value: &value},
+		{ID: "Alloc", MType: "hist"},
+	}, &ingest.Options{})
+	require.NoError(t, err)
+
+	hStats := pipelinestatshandler.NewPipelineStatsHandler(dse)
+
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	newr := httptest.NewRecorder()
+	hStats.PipelineStatsHandler(newr, req)
+	assert.Equal(t, http.StatusOK, newr.Code)
+
+	stats := apimodels.PipelineStats{}
+
+	err = json.Unmarshal(newr.Body.Bytes(), &stats)
+	require.NoError(t, err)
+	dropped := stats.Dropped
+	assert.Equal(t, uint64(1), dropped[pipeline.RuleID])
+	assert.Equal(t, uint64(1), dropped[pipeline.RuleType])
+	assert.Equal(t, uint64(0), dropped[pipeline.RuleValue])
+}
@@ -0,0 +1,49 @@
+// Package quotausagehandler provides handler
+// displays the ingestion limits and the
+// usage per source in json format.
+package quotausagehandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dmitrovia/collector-metrics/internal/service"
+)
+
+// QuotaUsageHandler - describing the handler.
+type QuotaUsageHandler struct {
+	serv service.Service
+}
+
+// NewQuotaUsageHandler - to create an instance
+// of a handler object.
+func NewQuotaUsageHandler(
+	s service.Service,
+) *QuotaUsageHandler {
+	return &QuotaUsageHandler{serv: s}
+}
+
+// QuotaUsageHandler - main handler method.
+// Responds with the limits, the number
+// of series and the usage per source.
+func (h *QuotaUsageHandler) QuotaUsageHandler(
+	writer http.ResponseWriter, _ *http.Request,
+) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	marshal, err := json.Marshal(h.serv.Quota().Usage())
+	if err != nil {
+		fmt.Println("QuotaUsageHandler->Marshal: %w", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write(marshal)
+	if err != nil {
+		fmt.Println("QuotaUsageHandler->Write: %w", err)
+	}
+}
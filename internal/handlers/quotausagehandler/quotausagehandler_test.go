@@ -0,0 +1,59 @@
+package quotausagehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/handlers/quotausagehandler"
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const url string = "http://localhost:8080/quota/usage"
+
+func TestQuotaUsageHandler(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	dse.SetQuotaLimits(bizmodels.QuotaLimits{MaxNewSeries: 1})
+
+	delta := int64(1)
+
+	_, err := ingest.Apply(dse, apimodels.ArrMetrics{
+		{ID: "A", MType: "counter", Delta: &delta},
+		{ID: "B", MType: "counter", Delta: &delta},
+	}, &ingest.Options{Source: "10.0.0.1"})
+	require.NoError(t, err)
+
+	hUsage := quotausagehandler.NewQuotaUsageHandler(dse)
+
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	newr := httptest.NewRecorder()
+	hUsage.QuotaUsageHandler(newr, req)
+	assert.Equal(t, http.StatusOK, newr.Code)
+
+	usage := apimodels.QuotaUsage{}
+
+	err = json.Unmarshal(newr.Body.Bytes(), &usage)
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.Series)
+	assert.Equal(t, 1, usage.Limits.MaxNewSeries)
+	assert.Equal(t, apimodels.SourceUsage{
+		Series: 1, NewSeries: 1, Rejected: 1,
+	}, usage.Sources["10.0.0.1"])
+}
@@ -1,3 +0,0 @@
-DROP TABLE gauges;
-
-DROP TABLE counters;
\ No newline at end of file
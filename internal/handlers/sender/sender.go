@@ -11,11 +11,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/dmitrovia/collector-metrics/internal/functions/batchid"
 	"github.com/dmitrovia/collector-metrics/internal/functions/hash"
-	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/functions/jsonstream"
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 )
 
@@ -25,8 +32,6 @@ type Sender struct {
 	params *bizmodels.InitParams
 }
 
-var errGetReqDataJSON = errors.New("data is empty")
-
 var errHashDoesNotMatch = errors.New("hash does not match")
 
 // NewSenderHandler - to create an instance
@@ -39,47 +44,129 @@ func NewSenderHandler(
 }
 
 // SenderHandler - main handler method.
+// Responds with the accepted and rejected
+// metrics of the batch. The atomic query
+// parameter rejects the whole batch when any
+// metric is rejected, summary leaves out
+// the metrics stored on the server.
 func (h *Sender) SenderHandler(
 	writer http.ResponseWriter, req *http.Request,
 ) {
 	writer.Header().Set("Content-Type", "application/json")
 
-	err := getReqData(h, req)
+	opts, err := getOptions(req, h.params.RateKey)
+	if err != nil {
+		fmt.Printf("SetMetricsJSONHandler->getOptions: %v\n", err)
+		writer.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := getReqData(h, req, opts)
+	if errors.Is(err, ingest.ErrRejected) {
+		err = writeResp(writer, http.StatusBadRequest, result)
+		if err != nil {
+			fmt.Printf("SetMetricsJSONHandler->writeResp: %v\n", err)
+		}
+
+		return
+	}
+
 	if err != nil {
-		fmt.Println("SetMetricsJSONHandler->getReqData: %w", err)
+		fmt.Printf("SetMetricsJSONHandler->getReqData: %v\n", err)
+
+		if limit.IsTooLarge(err) ||
+			errors.Is(err, quota.ErrBatchTooLarge) {
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		if errors.Is(err, ingest.ErrStorage) {
+			writer.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return
 	}
 
-	err = writeResp(writer, h)
+	if result.Duplicate {
+		writer.Header().Set(batchid.DuplicateHeader, "true")
+	}
+
+	if !opts.Summary {
+		result.Metrics, err = h.serv.GetAllMetricsAPI()
+		if err != nil {
+			fmt.Printf("SetMetricsJSONHandler->GetAll: %v\n", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	err = writeResp(writer, http.StatusOK, result)
 	if err != nil {
-		fmt.Println("SetMetricsJSONHandler->writeResp: %w", err)
+		fmt.Printf("SetMetricsJSONHandler->writeResp: %v\n", err)
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return
 	}
 }
 
-// writeResp - writes the response
+// getOptions - gets the ingestion options
+// from the query parameters and the
+// agent attributes from the headers, the
+// source is keyed like the rate limits.
+func getOptions(
+	req *http.Request,
+	rateKey string,
+) (*ingest.Options, error) {
+	agent, err := identity.FromRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("getOptions->FromRequest: %w", err)
+	}
+
+	opts := &ingest.Options{
+		Agent:  agent,
+		Source: ratelimit.RequestKey(req, rateKey),
+	}
+	query := req.URL.Query()
+
+	for name, dst := range map[string]*bool{
+		"atomic":  &opts.Atomic,
+		"summary": &opts.Summary,
+	} {
+		if !query.Has(name) {
+			continue
+		}
+
+		value, err := strconv.ParseBool(query.Get(name))
+		if err != nil {
+			return nil, fmt.Errorf("getOptions->ParseBool: %w", err)
+		}
+
+		*dst = value
+	}
+
+	return opts, nil
+}
+
+// writeResp - writes the result
 // in json format to the response body.
-// First, the metrics are obtained
-// from the service and encrypted.
 func writeResp(
 	writer http.ResponseWriter,
-	handler *Sender,
+	code int,
+	result *apimodels.IngestResult,
 ) error {
-	arr, err := handler.serv.GetAllMetricsAPI()
-	if err != nil {
-		return fmt.Errorf("writeResp->GetAllMetricsAPI: %w", err)
-	}
-
-	marshal, err := json.Marshal(arr)
+	marshal, err := json.Marshal(result)
 	if err != nil {
 		return fmt.Errorf("writeResp->Marshal: %w", err)
 	}
 
-	writer.WriteHeader(http.StatusOK)
+	writer.WriteHeader(code)
 
 	_, err = writer.Write(marshal)
 	if err != nil {
@@ -90,53 +177,63 @@ func writeResp(
 }
 
 // getReqData - receives metrics
-// from the request and validates it.
+// from the request and applies them.
+// The body is decoded as a stream and signed
+// on the fly, metrics are applied only after
+// the hash has been checked. A batch replayed
+// with the same id is not applied again.
 func getReqData(
 	handler *Sender,
 	req *http.Request,
-) error {
-	var results apimodels.ArrMetrics
+	opts *ingest.Options,
+) (*apimodels.IngestResult, error) {
+	defer req.Body.Close()
 
-	bodyD, err := io.ReadAll(req.Body)
-	if err != nil {
-		defer req.Body.Close()
+	batchID := req.Header.Get(batchid.Header)
 
-		return fmt.Errorf("getReqData: %w", err)
+	err := batchid.Validate(batchID)
+	if err != nil {
+		return nil, fmt.Errorf("getReqData->Validate: %w", err)
 	}
 
-	defer req.Body.Close()
+	results := make(apimodels.ArrMetrics, 0)
+	sign := hash.NewHashSHA256(handler.params.Key)
+
+	err = jsonstream.DecodeMetrics(
+		io.TeeReader(req.Body, sign),
+		func(metric *apimodels.Metrics) error {
+			results = append(results, *metric)
 
-	if len(bodyD) == 0 {
-		return fmt.Errorf("getReqData: %w", errGetReqDataJSON)
+			return nil
+		})
+	if err != nil {
+		return nil,
+			fmt.Errorf("getReqData->DecodeMetrics: %w", err)
 	}
 
-	err = checkHash(&bodyD,
+	err = checkHash(sign.Sum(nil),
 		req.Header.Get("Hashsha256"), handler.params.Key)
 	if err != nil {
-		return fmt.Errorf("getReqData->checkHash: %w", err)
+		return nil, fmt.Errorf("getReqData->checkHash: %w", err)
 	}
 
-	err = json.Unmarshal(bodyD, &results)
-	if err != nil {
-		return fmt.Errorf("getReqData->json.Unmarshal: %w", err)
+	opts.BatchID = batchID
+
+	result, err := ingest.Apply(handler.serv, results, opts)
+	if errors.Is(err, ingest.ErrRejected) {
+		return result, ingest.ErrRejected
 	}
 
-	for _, res := range results {
-		isValid := isValidJSONMetric(&res)
-		if isValid {
-			err = addValidMetric(&res, handler)
-			if err != nil {
-				fmt.Println(err)
-			}
-		}
+	if err != nil {
+		return nil, fmt.Errorf("getReqData->Apply: %w", err)
 	}
 
-	return nil
+	return result, nil
 }
 
 // checkHash - checks the received
 // hash in the request with the current one.
-func checkHash(dataReq *[]byte,
+func checkHash(tHash []byte,
 	hashReq string,
 	key string,
 ) error {
@@ -144,12 +241,6 @@ func checkHash(dataReq *[]byte,
 		return nil
 	}
 
-	tHash, err := hash.MakeHashSHA256(dataReq,
-		key)
-	if err != nil {
-		return fmt.Errorf("checkHash->MakeHashSHA256: %w", err)
-	}
-
 	decoded, err := hex.DecodeString(hashReq)
 	if err != nil {
 		return fmt.Errorf("checkHash->DecodeString: %w", err)
@@ -161,42 +252,3 @@ func checkHash(dataReq *[]byte,
 
 	return nil
 }
-
-// addValidMetric - adds the validated
-// metric to the service.
-func addValidMetric(res *apimodels.Metrics,
-	handler *Sender,
-) error {
-	if res.MType == bizmodels.GaugeName {
-		err := handler.serv.AddGauge(res.ID, *res.Value)
-		if err != nil {
-			return fmt.Errorf("addValidMetric->AddGauge: %w", err)
-		}
-	} else if res.MType == bizmodels.CounterName {
-		_, err := handler.serv.AddCounter(res.ID, *res.Delta,
-			false)
-		if err != nil {
-			return fmt.Errorf("addValidMetric->AddCounter: %w", err)
-		}
-	}
-
-	return nil
-}
-
-// isValidJSONMetric - for metric validation.
-func isValidJSONMetric(metric *apimodels.Metrics,
-) bool {
-	var pattern string
-
-	pattern = "^[0-9a-zA-Z/ ]{1,40}$"
-	res, _ := validate.IsMatchesTemplate(metric.ID, pattern)
-
-	if !res {
-		return false
-	}
-
-	pattern = "^" + bizmodels.MetricsPattern + "$"
-	res, _ = validate.IsMatchesTemplate(metric.MType, pattern)
-
-	return res
-}
@@ -3,8 +3,6 @@ package sender_test
 import (
 	"bytes"
 	"context"
-	"database/sql"
-	"embed"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -19,6 +17,7 @@ import (
 	"time"
 
 	"github.com/dmitrovia/collector-metrics/internal/functions/asymcrypto"
+	"github.com/dmitrovia/collector-metrics/internal/functions/batchid"
 	"github.com/dmitrovia/collector-metrics/internal/functions/compress"
 	"github.com/dmitrovia/collector-metrics/internal/functions/hash"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/sender"
@@ -26,9 +25,9 @@ import (
 	"github.com/dmitrovia/collector-metrics/internal/middleware/decryptmid"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/gzipcompressmiddleware"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/loggermiddleware"
-	"github.com/dmitrovia/collector-metrics/internal/migrator"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/serverimplement"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	"github.com/dmitrovia/collector-metrics/internal/storage/dbrepository"
 	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
@@ -49,11 +48,6 @@ var errPath = errors.New("path is not valid")
 
 var errResponse = errors.New("error response")
 
-const migrationsDir = "db/migrations"
-
-//go:embed db/migrations/*.sql
-var MigrationsFS embed.FS
-
 type testData struct {
 	tn       string
 	exbody   string
@@ -132,32 +126,6 @@ func getTestData() *[]testData {
 	}
 }
 
-func UseMigrations(par *bizmodels.InitParams) error {
-	if par.DatabaseDSN == "" {
-		return nil
-	}
-
-	migrator, err := migrator.MustGetNewMigrator(
-		MigrationsFS, migrationsDir)
-	if err != nil {
-		return fmt.Errorf("useMigrations->MustGetNewMi: %w", err)
-	}
-
-	conn, err := sql.Open("postgres", par.DatabaseDSN)
-	if err != nil {
-		return fmt.Errorf("useMigrations->sql.Open: %w", err)
-	}
-
-	defer conn.Close()
-
-	err = migrator.ApplyMigrations(conn)
-	if err != nil {
-		return fmt.Errorf("useMigrations->ApplyMigra: %w", err)
-	}
-
-	return nil
-}
-
 func setHandlerParams(params *bizmodels.InitParams) error {
 	params.
 		ValidateAddrPattern = "^[a-zA-Z/ ]{1,100}:[0-9]{1,10}$"
@@ -237,14 +205,15 @@ func initiate(
 		hJSONSets.SenderHandler)
 	setMsJSONMux.Use(
 		decryptmid.DecryptMiddleware(*params),
-		gzipcompressmiddleware.GzipMiddleware(),
+		gzipcompressmiddleware.GzipMiddleware(
+			params.GetBodyLimits("/updates/")),
 		loggermiddleware.RequestLogger(zapLogger))
 
 	settings.ContentType = "application/json"
 	settings.Encoding = "gzip"
 	settings.URL = url + "/updates/"
 
-	_ = UseMigrations(params)
+	_ = serverimplement.UseMigrations(params)
 
 	if !isMemRepo {
 		// for coverage
@@ -272,7 +241,7 @@ func initiate(
 func parseResponse(
 	buf *bytes.Buffer,
 ) ([]viewData, error) {
-	var result apimodels.ArrMetrics
+	var result apimodels.IngestResult
 
 	vdata := make([]viewData, 0)
 
@@ -288,7 +257,11 @@ func parseResponse(
 		return nil, err
 	}
 
-	for _, metric := range result {
+	if result.Metrics == nil {
+		return vdata, nil
+	}
+
+	for _, metric := range *result.Metrics {
 		temp := &viewData{}
 
 		temp.id = metric.ID
@@ -453,3 +426,162 @@ func randomString(n int) string {
 
 	return string(b)
 }
+
+func TestSenderBodyTooLarge(t *testing.T) {
+	t.Helper()
+	t.Parallel()
+
+	params := &bizmodels.InitParams{}
+	params.DefBodyLimits = bizmodels.BodyLimits{
+		MaxCompressed:   1 << 20,
+		MaxDecompressed: 64,
+	}
+	settings := &bizmodels.EndpointSettings{}
+	mux := mux.NewRouter()
+
+	err := initiate(mux, params, settings, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := &testData{gauges: []bizmodels.Gauge{
+		{Name: "Gauge1", Value: 1}, {Name: "Gauge2", Value: 2},
+		{Name: "Gauge3", Value: 3}, {Name: "Gauge4", Value: 4},
+	}}
+
+	metricMarshall, err := json.Marshal(getDataSend(test))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metricCompress, err := compress.DeflateCompress(
+		metricMarshall)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodPost, settings.URL,
+		bytes.NewReader(metricCompress))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Encoding", settings.Encoding)
+	req.Header.Set("Content-Type", settings.ContentType)
+
+	newr := httptest.NewRecorder()
+	mux.ServeHTTP(newr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge,
+		newr.Code, "Response code didn't match expected")
+}
+
+func TestSenderBatchReplay(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	handler := sender.NewSenderHandler(dse,
+		&bizmodels.InitParams{})
+	body := `[{"id":"PollCount","type":"counter","delta":5}]`
+
+	send := func(id string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(
+			context.Background(), http.MethodPost,
+			url+"/updates/", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set(batchid.Header, id)
+
+		newr := httptest.NewRecorder()
+		handler.SenderHandler(newr, req)
+
+		return newr
+	}
+
+	first := send("batch-1")
+	assert.Equal(t, stok, first.Code)
+	assert.Empty(t,
+		first.Header().Get(batchid.DuplicateHeader))
+
+	replay := send("batch-1")
+	assert.Equal(t, stok, replay.Code)
+	assert.Equal(t, "true",
+		replay.Header().Get(batchid.DuplicateHeader))
+
+	value, err := dse.GetValueCM("PollCount")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	assert.Equal(t, stok, send("batch-2").Code)
+	assert.Equal(t, bdreq, send("batch/3").Code)
+
+	value, err = dse.GetValueCM("PollCount")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), value)
+}
+
+func TestSenderIngestResult(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	handler := sender.NewSenderHandler(dse,
+		&bizmodels.InitParams{})
+	body := `[{"id":"Count","type":"counter","delta":2},` +
+		`{"id":"Alloc","type":"gauge"},` +
+		`{"id":"Bad*","type":"gauge","value":1}]`
+
+	send := func(query string) (int, *apimodels.IngestResult) {
+		req, err := http.NewRequestWithContext(
+			context.Background(), http.MethodPost,
+			url+"/updates/"+query, bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		newr := httptest.NewRecorder()
+		handler.SenderHandler(newr, req)
+
+		result := &apimodels.IngestResult{}
+		err = json.Unmarshal(newr.Body.Bytes(), result)
+		assert.NoError(t, err)
+
+		return newr.Code, result
+	}
+
+	code, result := send("?atomic=true")
+	assert.Equal(t, bdreq, code)
+	assert.Equal(t, 0, result.Accepted)
+	assert.Len(t, result.Rejected, 2)
+
+	_, err := dse.GetValueCM("Count")
+	assert.Error(t, err)
+
+	code, result = send("?summary=1")
+	assert.Equal(t, stok, code)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Nil(t, result.Metrics)
+	assert.Equal(t, []apimodels.RejectedMetric{
+		{
+			ID: "Alloc", MType: "gauge",
+			Reason: "missing value", Index: 1,
+		},
+		{
+			ID: "Bad*", MType: "gauge",
+			Reason: "invalid id", Index: 2,
+		},
+	}, result.Rejected)
+
+	code, result = send("")
+	assert.Equal(t, stok, code)
+	assert.NotNil(t, result.Metrics)
+	assert.Len(t, *result.Metrics, 1)
+}
@@ -8,14 +8,19 @@ import (
 	"strconv"
 
 	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	"github.com/gorilla/mux"
 )
 
 // SetMetricHandler - describing the handler.
 type SetMetricHandler struct {
-	serv service.Service
+	serv   service.Service
+	params *bizmodels.InitParams
 }
 
 // validMetric - object for storing the received metric.
@@ -31,8 +36,9 @@ type validMetric struct {
 // of a handler object.
 func NewSetMetricHandler(
 	serv service.Service,
+	par *bizmodels.InitParams,
 ) *SetMetricHandler {
-	return &SetMetricHandler{serv: serv}
+	return &SetMetricHandler{serv: serv, params: par}
 }
 
 // SetMetricHandler - main handler method.
@@ -47,11 +53,22 @@ func (h *SetMetricHandler) SetMetricHandler(
 
 	getReqData(req, valm)
 
-	isValid := isValidMetric(req, valm, writer)
+	isValid := isValidMetric(req, valm, writer,
+		h.serv.Pipeline())
 	if !isValid {
 		return
 	}
 
+	reason, _ := h.serv.Quota().Admit(
+		ratelimit.RequestKey(req, h.params.RateKey),
+		quota.Key(valm.mtype, valm.mname))
+	if reason != "" {
+		fmt.Println("SetMetricHandler->Admit:", reason)
+		writer.WriteHeader(http.StatusTooManyRequests)
+
+		return
+	}
+
 	addMetricToMemStore(h, valm)
 
 	writer.WriteHeader(http.StatusOK)
@@ -90,10 +107,14 @@ func addMetricToMemStore(
 }
 
 // isValidMetric - for metric validation.
+// The metric is checked and relabeled by
+// the pipeline of the service, an invalid
+// name is responded with not found.
 func isValidMetric(
 	r *http.Request,
 	metric *validMetric,
 	writer http.ResponseWriter,
+	pipe *pipeline.Pipeline,
 ) bool {
 	if !validate.IsMethodPost(r.Method) {
 		writer.WriteHeader(http.StatusMethodNotAllowed)
@@ -101,31 +122,29 @@ func isValidMetric(
 		return false
 	}
 
-	var pattern string
+	met := &apimodels.Metrics{
+		ID: metric.mname, MType: metric.mtype,
+	}
 
-	pattern = "^[0-9a-zA-Z/ ]{1,40}$"
-	res, _ := validate.IsMatchesTemplate(metric.mname, pattern)
+	if isValidMeticValue(metric) {
+		met.Value = &metric.mvalueFloat
+		met.Delta = &metric.mvalueInt
+	}
 
-	if !res {
+	drop := pipe.Process(met)
+	if drop != nil && drop.Rule == pipeline.RuleID {
 		writer.WriteHeader(http.StatusNotFound)
 
 		return false
 	}
 
-	pattern = "^" + bizmodels.MetricsPattern + "$"
-	res, _ = validate.IsMatchesTemplate(metric.mtype, pattern)
-
-	if !res {
+	if drop != nil {
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return false
 	}
 
-	if !isValidMeticValue(metric) {
-		writer.WriteHeader(http.StatusBadRequest)
-
-		return false
-	}
+	metric.mname = met.ID
 
 	return true
 }
@@ -88,7 +88,7 @@ func getTestData() *[]testData {
 		},
 		{
 			meth: post, tn: "13",
-			mt: bizmodels.CounterName, mn: "_Name123_",
+			mt: bizmodels.CounterName, mn: "Name-123!",
 			mv: "1", expcod: nfnd, exbody: "",
 		},
 		{
@@ -105,7 +105,7 @@ func initiate(router *mux.Router,
 	MemoryService := service.NewMemoryService(memStorage,
 		time.Duration(5))
 	handler := setmetrichandler.NewSetMetricHandler(
-		MemoryService)
+		MemoryService, &bizmodels.InitParams{})
 
 	router.HandleFunc(
 		"/update/{metric_type}/{metric_name}/{metric_value}",
@@ -9,21 +9,27 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 )
 
 // SetMJSONHandler - describing the handler.
 type SetMJSONHandler struct {
-	serv service.Service
+	serv   service.Service
+	params *bizmodels.InitParams
 }
 
 var errGetReqDataJSON = errors.New("data is empty")
 
 // validMetric - object for storing the received metric.
 type validMetric struct {
+	value       *float64
+	delta       *int64
 	mtype       string
 	mname       string
 	mvalueFloat float64
@@ -32,8 +38,10 @@ type validMetric struct {
 
 // NewSetMJH - to create an instance
 // of a handler object.
-func NewSetMJH(s service.Service) *SetMJSONHandler {
-	return &SetMJSONHandler{serv: s}
+func NewSetMJH(s service.Service,
+	par *bizmodels.InitParams,
+) *SetMJSONHandler {
+	return &SetMJSONHandler{serv: s, params: par}
 }
 
 // SetMJSONHandler - main handler method.
@@ -48,16 +56,33 @@ func (h *SetMJSONHandler) SetMJSONHandler(
 	err := getReqJSONData(req, valm)
 	if err != nil {
 		fmt.Println("SetMJSONHandler->getReqJSONData: %w", err)
+
+		if limit.IsTooLarge(err) {
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return
 	}
 
-	isValid := isValidM(valm, writer)
+	isValid := isValidM(valm, writer, h.serv.Pipeline())
 	if !isValid {
 		return
 	}
 
+	reason, _ := h.serv.Quota().Admit(
+		ratelimit.RequestKey(req, h.params.RateKey),
+		quota.Key(valm.mtype, valm.mname))
+	if reason != "" {
+		fmt.Println("SetMJSONHandler->Admit:", reason)
+		writer.WriteHeader(http.StatusTooManyRequests)
+
+		return
+	}
+
 	addMetricToMemStore(h, valm)
 	dataMarshal := formResponeBody(valm)
 
@@ -127,6 +152,9 @@ func getReqJSONData(
 	metric.mname = result.ID
 	metric.mtype = result.MType
 
+	metric.value = result.Value
+	metric.delta = result.Delta
+
 	if result.Value != nil {
 		metric.mvalueFloat = *result.Value
 	}
@@ -155,28 +183,34 @@ func addMetricToMemStore(
 }
 
 // isValidM - for metric validation.
+// The metric is checked and relabeled by
+// the pipeline of the service, an invalid
+// name is responded with not found.
 func isValidM(metric *validMetric,
 	writer http.ResponseWriter,
+	pipe *pipeline.Pipeline,
 ) bool {
-	var pattern string
-
-	pattern = "^[0-9a-zA-Z/ ]{1,40}$"
-	res, _ := validate.IsMatchesTemplate(metric.mname, pattern)
+	met := &apimodels.Metrics{
+		ID:    metric.mname,
+		MType: metric.mtype,
+		Value: metric.value,
+		Delta: metric.delta,
+	}
 
-	if !res {
+	drop := pipe.Process(met)
+	if drop != nil && drop.Rule == pipeline.RuleID {
 		writer.WriteHeader(http.StatusNotFound)
 
 		return false
 	}
 
-	pattern = "^" + bizmodels.MetricsPattern + "$"
-	res, _ = validate.IsMatchesTemplate(metric.mtype, pattern)
-
-	if !res {
+	if drop != nil {
 		writer.WriteHeader(http.StatusBadRequest)
 
 		return false
 	}
 
-	return res
+	metric.mname = met.ID
+
+	return true
 }
@@ -96,7 +96,7 @@ func getTestData() *[]testData {
 		},
 		{
 			meth: post, tn: "11",
-			mt: bizmodels.CounterName, mn: "_Name123_",
+			mt: bizmodels.CounterName, mn: "Name-123!",
 			value: 1, expcod: nfnd, exbody: "",
 		},
 		{
@@ -121,7 +121,8 @@ func initiate(
 	MemoryService := service.NewMemoryService(memStorage,
 		time.Duration(5))
 
-	hJSONSet := setmetricjsonhandler.NewSetMJH(MemoryService)
+	hJSONSet := setmetricjsonhandler.NewSetMJH(MemoryService,
+		&bizmodels.InitParams{})
 
 	zapLogger, err := logger.Initialize("info")
 	if err != nil {
@@ -132,7 +133,9 @@ func initiate(
 	setMJSONMux.HandleFunc(
 		"/update/",
 		hJSONSet.SetMJSONHandler)
-	setMJSONMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	setMJSONMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(
+			bizmodels.BodyLimits{}),
 		loggermiddleware.RequestLogger(zapLogger))
 
 	// for coverage
@@ -0,0 +1,261 @@
+// Package ingest applies metric batches
+// and reports the outcome of every metric.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+)
+
+const reasonStorage = "storage error"
+
+// batchKeySeparator - joins the source and
+// the batch id, which never contains it.
+const batchKeySeparator = "/"
+
+// ErrRejected - returned when an atomic
+// batch has rejected metrics.
+var ErrRejected = errors.New("batch has rejected metrics")
+
+// ErrStorage - returned when the batch
+// fails to be stored.
+var ErrStorage = errors.New("storage error")
+
+// Options - how the batch is applied.
+// Atomic rejects the whole batch when any metric
+// is rejected, Summary leaves out the metrics
+// stored on the server from the response.
+// Source identifies the agent for the quotas,
+// it is keyed like the rate limits,
+// Agent holds the attributes the agent sent,
+// its id labels the names by the pipeline.
+// BatchID applies the batch once, its
+// metrics are added in one repository call,
+// the ids of different sources never clash.
+type Options struct {
+	Agent   bizmodels.AgentInfo
+	Source  string
+	BatchID string
+	Atomic  bool
+	Summary bool
+}
+
+// check - runs the metric through the
+// pipeline and the quota of the service,
+// returns the reason the metric is dropped
+// and whether a new series was reserved.
+func check(
+	serv service.Service,
+	opts *Options,
+	metric *apimodels.Metrics,
+) (string, bool) {
+	drop := serv.Pipeline().ProcessFrom(metric,
+		opts.Agent.ID)
+	if drop != nil {
+		return drop.Reason, false
+	}
+
+	return serv.Quota().Admit(opts.Source,
+		quota.Key(metric.MType, metric.ID))
+}
+
+// Apply - adds the metrics to the service.
+// The metrics are checked and relabeled
+// by the pipeline of the service and
+// admitted by its quota, a batch exceeding
+// the size limit is not applied at all.
+// Every metric is applied on its own and the
+// failed ones are reported as rejected.
+// An atomic batch is applied in one repository
+// call and only when no metric is rejected,
+// otherwise ErrRejected is returned with
+// the result listing the rejected metrics.
+// A batch with an id is applied in one
+// repository call together with its mark,
+// a replayed one is reported as duplicate.
+func Apply(
+	serv service.Service,
+	arr apimodels.ArrMetrics,
+	opts *Options,
+) (*apimodels.IngestResult, error) {
+	err := serv.Quota().CheckBatch(opts.Source, len(arr))
+	if err != nil {
+		return nil, fmt.Errorf("Apply->CheckBatch: %w", err)
+	}
+
+	result := &apimodels.IngestResult{
+		Rejected: make([]apimodels.RejectedMetric, 0),
+	}
+
+	if opts.Atomic || opts.BatchID != "" {
+		return applyBatch(serv, arr, opts, result)
+	}
+
+	for i := range arr {
+		metric := arr[i]
+
+		reason, reserved := check(serv, opts, &metric)
+		if reason == "" {
+			err := addMetric(serv, &metric)
+			if err != nil {
+				fmt.Printf("Apply->addMetric: %v\n", err)
+
+				reason = reasonStorage
+			}
+		}
+
+		if reason != "" {
+			if reserved {
+				serv.Quota().Forget(opts.Source,
+					quota.Key(metric.MType, metric.ID))
+			}
+
+			result.Rejected = append(result.Rejected,
+				reject(&arr[i], i, reason))
+
+			continue
+		}
+
+		result.Accepted++
+	}
+
+	return result, nil
+}
+
+// applyBatch - checks all metrics first
+// and applies the accepted ones together.
+// The series reserved for metrics that
+// are not applied are released.
+func applyBatch(
+	serv service.Service,
+	arr apimodels.ArrMetrics,
+	opts *Options,
+	result *apimodels.IngestResult,
+) (*apimodels.IngestResult, error) {
+	gauges := make(map[string]bizmodels.Gauge)
+	counters := make(map[string]bizmodels.Counter)
+	reserved := make([]string, 0)
+
+	forget := func() {
+		for _, key := range reserved {
+			serv.Quota().Forget(opts.Source, key)
+		}
+	}
+
+	for i := range arr {
+		metric := arr[i]
+		key := quota.Key(metric.MType, metric.ID)
+
+		reason, isNew := check(serv, opts, &metric)
+		if reason != "" {
+			if isNew {
+				serv.Quota().Forget(opts.Source, key)
+			}
+
+			result.Rejected = append(result.Rejected,
+				reject(&arr[i], i, reason))
+
+			continue
+		}
+
+		if isNew {
+			reserved = append(reserved, key)
+		}
+
+		// a batch may repeat a metric, counters
+		// add up and the last gauge value wins.
+		if metric.MType == bizmodels.GaugeName {
+			gauges[metric.ID] = bizmodels.Gauge{
+				Name: metric.ID, Value: *metric.Value,
+			}
+
+			continue
+		}
+
+		counters[metric.ID] = bizmodels.Counter{
+			Name:  metric.ID,
+			Value: counters[metric.ID].Value + *metric.Delta,
+		}
+	}
+
+	if opts.Atomic && len(result.Rejected) > 0 {
+		forget()
+
+		return result, ErrRejected
+	}
+
+	applied, err := serv.AddBatch(batchKey(opts),
+		gauges, counters)
+	if err != nil {
+		forget()
+
+		return nil, fmt.Errorf("applyBatch->AddBatch: %w: %w",
+			ErrStorage, err)
+	}
+
+	if !applied {
+		forget()
+
+		return &apimodels.IngestResult{
+			Rejected:  make([]apimodels.RejectedMetric, 0),
+			Duplicate: true,
+		}, nil
+	}
+
+	result.Accepted = len(arr) - len(result.Rejected)
+
+	return result, nil
+}
+
+// addMetric - adds the checked
+// metric to the service.
+func addMetric(
+	serv service.Service,
+	metric *apimodels.Metrics,
+) error {
+	if metric.MType == bizmodels.GaugeName {
+		err := serv.AddGauge(metric.ID, *metric.Value)
+		if err != nil {
+			return fmt.Errorf("addMetric->AddGauge: %w", err)
+		}
+
+		return nil
+	}
+
+	_, err := serv.AddCounter(metric.ID, *metric.Delta, false)
+	if err != nil {
+		return fmt.Errorf("addMetric->AddCounter: %w", err)
+	}
+
+	return nil
+}
+
+// reject - describes the rejected metric.
+func reject(
+	metric *apimodels.Metrics,
+	index int,
+	reason string,
+) apimodels.RejectedMetric {
+	return apimodels.RejectedMetric{
+		ID:     metric.ID,
+		MType:  metric.MType,
+		Reason: reason,
+		Index:  index,
+	}
+}
+
+// batchKey - returns the key the batch is
+// deduplicated by, the id of the source
+// and the batch id of the agent.
+func batchKey(opts *Options) string {
+	if opts.BatchID == "" {
+		return ""
+	}
+
+	return opts.Source + batchKeySeparator + opts.BatchID
+}
@@ -0,0 +1,167 @@
+package ingest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/ingest"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/dmitrovia/collector-metrics/internal/service"
+	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	atomic := &ingest.Options{Atomic: true}
+	delta := int64(2)
+	value := 1.5
+
+	arr := apimodels.ArrMetrics{
+		{ID: "Count", MType: "counter", Delta: &delta},
+		{ID: "Count", MType: "counter", Delta: &delta},
+		{ID: "Alloc", MType: "gauge", Value: &value},
+	}
+
+	result, err := ingest.Apply(dse, arr, atomic)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Accepted)
+	assert.Empty(t, result.Rejected)
+
+	count, err := dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	arr = append(arr,
+		apimodels.Metrics{ID: "X", MType: "hist"})
+
+	result, err = ingest.Apply(dse, arr, atomic)
+	assert.ErrorIs(t, err, ingest.ErrRejected)
+	assert.Equal(t, 3, result.Rejected[0].Index)
+
+	count, err = dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	result, err = ingest.Apply(dse, arr, &ingest.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Accepted)
+	assert.Equal(t, "invalid type", result.Rejected[0].Reason)
+}
+
+func TestApplyQuota(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	dse.SetQuotaLimits(bizmodels.QuotaLimits{
+		MaxSeries: 2, MaxBatch: 3,
+	})
+
+	value := 1.0
+	opts := &ingest.Options{Source: "agent", Atomic: true}
+	arr := apimodels.ArrMetrics{
+		{ID: "A", MType: "gauge", Value: &value},
+		{ID: "B", MType: "gauge", Value: &value},
+		{ID: "C", MType: "gauge", Value: &value},
+	}
+
+	result, err := ingest.Apply(dse, arr, opts)
+	assert.ErrorIs(t, err, ingest.ErrRejected)
+	assert.Equal(t, "series limit", result.Rejected[0].Reason)
+	assert.Equal(t, 0, dse.Quota().Usage().Series)
+
+	_, err = ingest.Apply(dse, append(arr, arr[0]), opts)
+	assert.ErrorIs(t, err, quota.ErrBatchTooLarge)
+
+	opts.Atomic = false
+
+	result, err = ingest.Apply(dse, arr, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Accepted)
+	assert.Equal(t, 2, result.Rejected[0].Index)
+}
+
+func TestApplyAgent(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	pipe, err := pipeline.New(&bizmodels.PipelineConfig{
+		AgentPolicy: pipeline.AgentSeries,
+	})
+	assert.NoError(t, err)
+
+	dse := service.NewMemoryService(mem, time.Second)
+	dse.SetPipeline(pipe)
+
+	delta := int64(1)
+	arr := apimodels.ArrMetrics{
+		{ID: "PollCount", MType: "counter", Delta: &delta},
+	}
+
+	for _, agent := range []string{"a1", "a2", "a2"} {
+		_, err = ingest.Apply(dse, arr, &ingest.Options{
+			Agent: bizmodels.AgentInfo{ID: agent},
+		})
+		assert.NoError(t, err)
+	}
+
+	count, err := dse.GetValueCM("PollCount/a1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	count, err = dse.GetValueCM("PollCount/a2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestApplyReplay(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryrepository.MemoryRepository{}
+	mem.Init()
+
+	dse := service.NewMemoryService(mem, time.Second)
+	dse.SetBatchWindow(time.Minute)
+
+	delta := int64(2)
+	opts := &ingest.Options{BatchID: "b1"}
+	arr := apimodels.ArrMetrics{
+		{ID: "Count", MType: "counter", Delta: &delta},
+		{ID: "X", MType: "hist"},
+	}
+
+	result, err := ingest.Apply(dse, arr, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Len(t, result.Rejected, 1)
+	assert.False(t, result.Duplicate)
+
+	result, err = ingest.Apply(dse, arr, opts)
+	assert.NoError(t, err)
+	assert.True(t, result.Duplicate)
+	assert.Zero(t, result.Accepted)
+
+	// the same id sent by another source.
+	opts.Source = "10.0.0.2"
+
+	result, err = ingest.Apply(dse, arr, opts)
+	assert.NoError(t, err)
+	assert.False(t, result.Duplicate)
+
+	count, err := dse.GetValueCM("Count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
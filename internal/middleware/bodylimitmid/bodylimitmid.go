@@ -0,0 +1,42 @@
+// Package bodylimitmid
+// implements middleware
+// to limit the size of the request body.
+package bodylimitmid
+
+import (
+	"net/http"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// BodyLimitMiddleware - main middleware method.
+// Rejects requests whose declared length exceeds
+// the compressed limit and caps the body reader
+// so that chunked requests fail while being read.
+func BodyLimitMiddleware(
+	limits bizmodels.BodyLimits,
+) func(http.Handler) http.Handler {
+	handler := func(hand http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(
+				writer http.ResponseWriter, req *http.Request,
+			) {
+				if limits.MaxCompressed > 0 {
+					if req.ContentLength > limits.MaxCompressed {
+						writer.WriteHeader(
+							http.StatusRequestEntityTooLarge)
+
+						return
+					}
+
+					req.Body = http.MaxBytesReader(writer,
+						req.Body, limits.MaxCompressed)
+				}
+
+				hand.ServeHTTP(writer, req)
+			},
+		)
+	}
+
+	return handler
+}
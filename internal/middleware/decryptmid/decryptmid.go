@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/dmitrovia/collector-metrics/internal/functions/asymcrypto"
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
 )
 
@@ -22,6 +23,14 @@ func DecryptMiddleware(
 				bodyD, err := io.ReadAll(req.Body)
 				if err != nil {
 					defer req.Body.Close()
+
+					if limit.IsTooLarge(err) {
+						writer.WriteHeader(
+							http.StatusRequestEntityTooLarge)
+
+						return
+					}
+
 					writer.WriteHeader(http.StatusInternalServerError)
 					fmt.Println("DecryptMiddleware->ReadAll %w", err)
 
@@ -10,6 +10,9 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/limit"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
 )
 
 const maxCode int = 300
@@ -49,6 +52,24 @@ func (c *compressWriter) WriteHeader(statusCode int) {
 	c.w.WriteHeader(statusCode)
 }
 
+// Flush - sends the compressed data written
+// so far, so streaming responses are not
+// held in the gzip buffer.
+func (c *compressWriter) Flush() {
+	err := c.zw.Flush()
+	if err != nil {
+		return
+	}
+
+	_ = http.NewResponseController(c.w).Flush()
+}
+
+// Unwrap - gives http.ResponseController
+// access to the underlying writer.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.w
+}
+
 func (c *compressWriter) Close() error {
 	err := c.zw.Close()
 	if err != nil {
@@ -61,10 +82,14 @@ func (c *compressWriter) Close() error {
 type compressReader struct {
 	r  io.ReadCloser
 	zr *gzip.Reader
+	lr io.Reader
 }
 
+// newCompressReader - the decompressed
+// stream is limited to maxSize bytes.
 func newCompressReader(
 	reader io.ReadCloser,
+	maxSize int64,
 ) (*compressReader, error) {
 	zipReader, err := gzip.NewReader(reader)
 	if err != nil {
@@ -74,11 +99,12 @@ func newCompressReader(
 	return &compressReader{
 		r:  reader,
 		zr: zipReader,
+		lr: limit.NewReader(zipReader, maxSize),
 	}, nil
 }
 
 func (c compressReader) Read(p []byte) (int, error) {
-	n, err := c.zr.Read(p)
+	n, err := c.lr.Read(p)
 
 	return n, err
 }
@@ -97,7 +123,9 @@ func (c *compressReader) Close() error {
 }
 
 // GzipMiddleware - main middleware method.
-func GzipMiddleware() func(http.Handler) http.Handler {
+func GzipMiddleware(
+	limits bizmodels.BodyLimits,
+) func(http.Handler) http.Handler {
 	handler := func(hand http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(
@@ -129,9 +157,10 @@ func GzipMiddleware() func(http.Handler) http.Handler {
 
 				sendsGzip := strings.Contains(contentEncoding, "gzip")
 				if sendsGzip {
-					compressReader, err := newCompressReader(req.Body)
+					compressReader, err := newCompressReader(
+						req.Body, limits.MaxDecompressed)
 					if err != nil {
-						writer.WriteHeader(http.StatusInternalServerError)
+						writer.WriteHeader(statusByError(err))
 						fmt.Println(" %w", err)
 
 						return
@@ -150,3 +179,13 @@ func GzipMiddleware() func(http.Handler) http.Handler {
 
 	return handler
 }
+
+// statusByError - returns the response
+// code for the request body read error.
+func statusByError(err error) int {
+	if limit.IsTooLarge(err) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusInternalServerError
+}
@@ -46,6 +46,20 @@ func (r *loggingResponseWriter) WriteHeader(
 	r.responseData.status = statusCode
 }
 
+// Flush - passes the flush to the wrapped
+// writer for streaming responses.
+func (r *loggingResponseWriter) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap - gives http.ResponseController
+// access to the underlying writer.
+//
+//nolint:lll
+func (r *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // RequestLogger - main middleware method.
 func RequestLogger(
 	zapLogger *zap.Logger,
@@ -0,0 +1,35 @@
+// Package nodeadlinemid
+// implements middleware
+// for long-lived streaming responses.
+package nodeadlinemid
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// NoDeadlineMiddleware - main middleware method.
+// Clears the deadlines set by the server
+// timeouts, so streams are not cut after them.
+// An expired read deadline cancels
+// the request context as well.
+func NoDeadlineMiddleware(hand http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(writer http.ResponseWriter, req *http.Request) {
+			ctrl := http.NewResponseController(writer)
+
+			err := ctrl.SetWriteDeadline(time.Time{})
+			if err != nil {
+				fmt.Println("NoDeadlineMiddleware: %w", err)
+			}
+
+			err = ctrl.SetReadDeadline(time.Time{})
+			if err != nil {
+				fmt.Println("NoDeadlineMiddleware: %w", err)
+			}
+
+			hand.ServeHTTP(writer, req)
+		},
+	)
+}
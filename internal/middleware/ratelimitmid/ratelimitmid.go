@@ -0,0 +1,41 @@
+// Package ratelimitmid
+// implements middleware
+// to limit the rate of the requests.
+package ratelimitmid
+
+import (
+	"net/http"
+
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+)
+
+// RateLimitMiddleware - main middleware method.
+// Takes a token of the client keyed by the mode,
+// requests without one are rejected with 429
+// and the delay in the Retry-After header.
+func RateLimitMiddleware(
+	limiter *ratelimit.Limiter,
+	mode string,
+) func(http.Handler) http.Handler {
+	handler := func(hand http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(
+				writer http.ResponseWriter, req *http.Request,
+			) {
+				ok, wait := limiter.Allow(
+					ratelimit.RequestKey(req, mode))
+				if !ok {
+					writer.Header().Set(ratelimit.Header,
+						ratelimit.Seconds(wait))
+					writer.WriteHeader(http.StatusTooManyRequests)
+
+					return
+				}
+
+				hand.ServeHTTP(writer, req)
+			},
+		)
+	}
+
+	return handler
+}
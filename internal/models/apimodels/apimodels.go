@@ -2,6 +2,8 @@
 // describes the data exchange model for handlers
 package apimodels
 
+import "encoding/json"
+
 type Metrics struct {
 	Delta *int64   `json:"delta,omitempty"`
 	Value *float64 `json:"value,omitempty"`
@@ -11,25 +13,181 @@ type Metrics struct {
 
 type ArrMetrics []Metrics
 
+// RejectedMetric - a metric of the batch
+// that was not applied and the reason.
+type RejectedMetric struct {
+	ID     string `json:"id"`
+	MType  string `json:"type"`
+	Reason string `json:"reason"`
+	Index  int    `json:"index"`
+}
+
+// IngestResult - outcome of a metric batch.
+// Metrics holds all metrics stored on the server
+// unless only the summary was requested.
+type IngestResult struct {
+	Metrics   *ArrMetrics      `json:"metrics,omitempty"`
+	Rejected  []RejectedMetric `json:"rejected"`
+	Accepted  int              `json:"accepted"`
+	Duplicate bool             `json:"duplicate,omitempty"`
+}
+
+// PipelineStats - metrics dropped
+// by the ingestion pipeline per rule.
+type PipelineStats struct {
+	Dropped map[string]uint64 `json:"dropped"`
+}
+
+// QuotaLimits - ingestion limits.
+type QuotaLimits struct {
+	MaxSeries    int `json:"maxSeries"`
+	MaxNewSeries int `json:"maxNewSeriesPerMin"`
+	MaxBatch     int `json:"maxBatchSize"`
+}
+
+// SourceUsage - usage of a source,
+// NewSeries is counted in the current minute.
+type SourceUsage struct {
+	Series    int `json:"series"`
+	NewSeries int `json:"newSeries"`
+	Rejected  int `json:"rejected"`
+}
+
+// QuotaUsage - current series
+// and usage per source.
+type QuotaUsage struct {
+	Sources map[string]SourceUsage `json:"sources"`
+	Limits  QuotaLimits            `json:"limits"`
+	Series  int                    `json:"series"`
+}
+
 type GprcMetrics struct {
 	Metrics *[]byte `json:"metrics"`
 }
 
+type CfgBodyLimits struct {
+	MaxCompressed   int64 `json:"maxCompressed"`
+	MaxDecompressed int64 `json:"maxDecompressed"`
+}
+
+type CfgRouteLimits map[string]CfgBodyLimits
+
+type CfgRenameRule struct {
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+}
+
+type CfgPipeline struct {
+	IDPattern    string          `json:"idPattern"`
+	Prefix       string          `json:"prefix"`
+	AgentPolicy  string          `json:"agentPolicy"`
+	Allow        []string        `json:"allow"`
+	Deny         []string        `json:"deny"`
+	Rename       []CfgRenameRule `json:"rename"`
+	DropNegative bool            `json:"dropNegative"`
+}
+
+type CfgRateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+type CfgRouteRates map[string]CfgRateLimit
+
 type CfgServer struct {
-	PORT                 string `json:"address"`
-	FileStoragePath      string `json:"storeFile"`
-	DatabaseDSN          string `json:"databaseDsn"`
-	Key                  string `json:"keySha"`
-	CryptoPrivateKeyPath string `json:"cryptoKey"`
-	TrustedSubnet        string `json:"trustedSubnet"`
-	StoreInterval        int    `json:"storeInterval"`
-	Restore              bool   `json:"restore"`
+	BodyLimits           CfgRouteLimits `json:"bodyLimits"`
+	RateLimits           CfgRouteRates  `json:"rateLimits"`
+	Pipeline             CfgPipeline    `json:"pipeline"`
+	Quotas               QuotaLimits    `json:"quotas"`
+	PORT                 string         `json:"address"`
+	FileStoragePath      string         `json:"storeFile"`
+	DatabaseDSN          string         `json:"databaseDsn"`
+	Key                  string         `json:"keySha"`
+	CryptoPrivateKeyPath string         `json:"cryptoKey"`
+	TrustedSubnet        string         `json:"trustedSubnet"`
+	RateKey              string         `json:"rateKey"`
+	StoreInterval        int            `json:"storeInterval"`
+	Restore              bool           `json:"restore"`
 }
 
+type CfgCollector struct {
+	Enabled      *bool           `json:"enabled"`
+	Options      json.RawMessage `json:"options"`
+	PollInterval int             `json:"pollInterval"`
+}
+
+type CfgCollectors map[string]CfgCollector
+
+// CfgAggregation - aggregations of the
+// gauges keyed by the name pattern.
+type CfgAggregation map[string][]string
+
 type CfgAgent struct {
-	PORT                string `json:"address"`
-	Key                 string `json:"keySha"`
-	CryptoPublicKeyPath string `json:"cryptoKey"`
-	ReportInterval      int    `json:"reportInterval"`
-	PollInterval        int    `json:"pollInterval"`
+	Collectors          CfgCollectors   `json:"collectors"`
+	Aggregation         CfgAggregation  `json:"aggregation"`
+	GRPC                CfgGRPC         `json:"grpc"`
+	Destinations        CfgDestinations `json:"destinations"`
+	Retry               CfgRetry        `json:"retry"`
+	Breaker             CfgBreaker      `json:"breaker"`
+	PORT                string          `json:"address"`
+	Key                 string          `json:"keySha"`
+	CryptoPublicKeyPath string          `json:"cryptoKey"`
+	OutboxPath          string          `json:"outboxPath"`
+	AgentID             string          `json:"agentId"`
+	AgentIDFile         string          `json:"agentIdFile"`
+	ReportInterval      int             `json:"reportInterval"`
+	PollInterval        int             `json:"pollInterval"`
+	OutboxSize          int             `json:"outboxSize"`
+	ReportJitter        float64         `json:"reportJitter"`
+}
+
+// CfgDestination - server the agent sends the
+// reports to, the transport is http or grpc,
+// the empty fields are taken from the agent.
+type CfgDestination struct {
+	GRPC                CfgGRPC    `json:"grpc"`
+	Retry               CfgRetry   `json:"retry"`
+	Breaker             CfgBreaker `json:"breaker"`
+	Name                string     `json:"name"`
+	Transport           string     `json:"transport"`
+	PORT                string     `json:"address"`
+	UpdateURL           string     `json:"updateUrl"`
+	Key                 string     `json:"keySha"`
+	CryptoPublicKeyPath string     `json:"cryptoKey"`
+}
+
+type CfgDestinations []CfgDestination
+
+// CfgGRPC - grpc connection of the agent,
+// the keepalive durations are in seconds.
+// TLS is not set when it is null.
+type CfgGRPC struct {
+	Addr                string `json:"address"`
+	CAFile              string `json:"ca"`
+	CertFile            string `json:"cert"`
+	KeyFile             string `json:"certKey"`
+	ServerName          string `json:"serverName"`
+	Keepalive           int    `json:"keepalive"`
+	KeepaliveTimeout    int    `json:"keepaliveTimeout"`
+	TLS                 *bool  `json:"tls"`
+	PermitWithoutStream bool   `json:"permitWithoutStream"`
+}
+
+// CfgRetry - retries of the agent requests,
+// the durations are in seconds.
+type CfgRetry struct {
+	Initial    int     `json:"initial"`
+	Max        int     `json:"max"`
+	MaxElapsed int     `json:"maxElapsed"`
+	Attempts   int     `json:"attempts"`
+	Multiplier float64 `json:"multiplier"`
+	Jitter     float64 `json:"jitter"`
+}
+
+// CfgBreaker - circuit breaker of the agent
+// requests, the cooldown is in seconds,
+// negative failures disable the breaker.
+type CfgBreaker struct {
+	Failures int `json:"failures"`
+	Cooldown int `json:"cooldown"`
 }
@@ -4,11 +4,16 @@ package bizmodels
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v1"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	pb "github.com/dmitrovia/collector-metrics/pkg/microservice/v2"
 	"google.golang.org/grpc"
 )
 
@@ -30,81 +35,145 @@ type Counter struct {
 	Value int64
 }
 
-// Monitor - for storing runtime metrics.
-type (
-	Monitor struct {
-		Alloc           Gauge
-		TotalAlloc      Gauge
-		BuckHashSys     Gauge
-		Frees           Gauge
-		Mallocs         Gauge
-		Sys             Gauge
-		GCCPUFraction   Gauge
-		GCSys           Gauge
-		HeapAlloc       Gauge
-		HeapIdle        Gauge
-		HeapInuse       Gauge
-		HeapObjects     Gauge
-		HeapReleased    Gauge
-		HeapSys         Gauge
-		LastGC          Gauge
-		Lookups         Gauge
-		MCacheInuse     Gauge
-		MCacheSys       Gauge
-		MSpanInuse      Gauge
-		MSpanSys        Gauge
-		NextGC          Gauge
-		NumForcedGC     Gauge
-		NumGC           Gauge
-		OtherSys        Gauge
-		PauseTotalNs    Gauge
-		StackInuse      Gauge
-		StackSys        Gauge
-		TotalMemory     Gauge
-		FreeMemory      Gauge
-		CPUutilization1 Gauge
-
-		PollCount   Counter
-		RandomValue Gauge
-	}
-)
+// Monitor - for storing the agent metrics
+// read by the collectors, gauges keep the
+// last value and counters add up until
+// they are taken by a report.
+type Monitor struct {
+	gauges   map[string]float64
+	counters map[string]int64
+	mutex    sync.Mutex
+}
 
 // Init - monitor initialization method.
 func (m *Monitor) Init() {
-	m.Alloc = Gauge{Name: "TotalMemory", Value: 0}
-	m.BuckHashSys = Gauge{Name: "FreeMemory", Value: 0}
-	m.Frees = Gauge{Name: "CPUutilization1", Value: 0}
-
-	m.Alloc = Gauge{Name: "Alloc", Value: 0}
-	m.BuckHashSys = Gauge{Name: "BuckHashSys", Value: 0}
-	m.Frees = Gauge{Name: "Frees", Value: 0}
-	m.GCCPUFraction = Gauge{Name: "GCCPUFraction", Value: 0}
-	m.GCSys = Gauge{Name: "GCSys", Value: 0}
-	m.HeapAlloc = Gauge{Name: "HeapAlloc", Value: 0}
-	m.HeapIdle = Gauge{Name: "HeapIdle", Value: 0}
-	m.HeapInuse = Gauge{Name: "HeapInuse", Value: 0}
-	m.HeapObjects = Gauge{Name: "HeapObjects", Value: 0}
-	m.HeapReleased = Gauge{Name: "HeapReleased", Value: 0}
-	m.HeapSys = Gauge{Name: "HeapSys", Value: 0}
-	m.LastGC = Gauge{Name: "LastGC", Value: 0}
-	m.Lookups = Gauge{Name: "Lookups", Value: 0}
-	m.MCacheInuse = Gauge{Name: "MCacheInuse", Value: 0}
-	m.MCacheSys = Gauge{Name: "MCacheSys", Value: 0}
-	m.MSpanInuse = Gauge{Name: "MSpanInuse", Value: 0}
-	m.MSpanSys = Gauge{Name: "MSpanSys", Value: 0}
-	m.Mallocs = Gauge{Name: "Mallocs", Value: 0}
-	m.NextGC = Gauge{Name: "NextGC", Value: 0}
-	m.NumForcedGC = Gauge{Name: "NumForcedGC", Value: 0}
-	m.NumGC = Gauge{Name: "NumGC", Value: 0}
-	m.OtherSys = Gauge{Name: "OtherSys", Value: 0}
-	m.PauseTotalNs = Gauge{Name: "PauseTotalNs", Value: 0}
-	m.StackInuse = Gauge{Name: "StackInuse", Value: 0}
-	m.StackSys = Gauge{Name: "StackSys", Value: 0}
-	m.Sys = Gauge{Name: "Sys", Value: 0}
-	m.TotalAlloc = Gauge{Name: "TotalAlloc", Value: 0}
-
-	m.PollCount = Counter{Name: "PollCount", Value: 0}
-	m.RandomValue = Gauge{Name: "RandomValue", Value: 0}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.gauges = make(map[string]float64)
+	m.counters = make(map[string]int64)
+}
+
+// Merge - adds the sample to the monitor.
+func (m *Monitor) Merge(sample *Sample) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for name, value := range sample.Gauges {
+		m.gauges[name] = value
+	}
+
+	for name, delta := range sample.Counters {
+		m.counters[name] += delta
+	}
+}
+
+// Snapshot - returns the gauges sorted by
+// name and the counters added since the
+// previous snapshot, so every report carries
+// only the increments of its own window.
+func (m *Monitor) Snapshot() ([]Gauge, map[string]Counter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	gauges := make([]Gauge, 0, len(m.gauges))
+	for name, value := range m.gauges {
+		gauges = append(gauges, Gauge{Name: name, Value: value})
+	}
+
+	slices.SortFunc(gauges, func(a, b Gauge) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	counters := make(map[string]Counter, len(m.counters))
+	for name, value := range m.counters {
+		counters[name] = Counter{Name: name, Value: value}
+	}
+
+	m.counters = make(map[string]int64)
+
+	return gauges, counters
+}
+
+// Deltas - counter increments
+// safe for concurrent use.
+type Deltas struct {
+	values map[string]int64
+	mutex  sync.Mutex
+}
+
+// Add - adds the increments.
+func (d *Deltas) Add(values map[string]int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.values == nil {
+		d.values = make(map[string]int64, len(values))
+	}
+
+	for name, delta := range values {
+		d.values[name] += delta
+	}
+}
+
+// Take - returns the increments added
+// so far, they start again from zero.
+func (d *Deltas) Take() map[string]int64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	values := d.values
+	d.values = nil
+
+	return values
+}
+
+// Sample - metrics read by a collector.
+type Sample struct {
+	Gauges   map[string]float64
+	Counters map[string]int64
+}
+
+// NewSample - creates an empty sample.
+func NewSample() *Sample {
+	return &Sample{
+		Gauges:   make(map[string]float64),
+		Counters: make(map[string]int64),
+	}
+}
+
+// SetGauge - sets the gauge value.
+func (s *Sample) SetGauge(name string, value float64) {
+	s.Gauges[name] = value
+}
+
+// AddCounter - adds the delta to the counter.
+func (s *Sample) AddCounter(name string, delta int64) {
+	s.Counters[name] += delta
+}
+
+// Collector - reads a group of
+// the agent metrics into the sample.
+type Collector interface {
+	Collect(sample *Sample) error
+}
+
+// PolledCollector - enabled collector
+// with its poll interval in seconds.
+type PolledCollector struct {
+	Collector Collector
+	Name      string
+	Interval  int
+}
+
+// CollectorConfig - settings of a collector,
+// Enabled is nil when not configured, zero
+// interval means the agent poll interval,
+// Options are decoded by the collector.
+type CollectorConfig struct {
+	Enabled      *bool
+	Options      json.RawMessage
+	PollInterval int
 }
 
 // InitParams - store server configuration.
@@ -118,13 +187,219 @@ type InitParams struct {
 	CryptoPrivateKeyPath string
 	TrustedSubnet        string
 	GRPCPort             string
+	GRPCCertFile         string
+	GRPCKeyFile          string
+	GRPCClientCAFile     string
+	RateKey              string
+	BodyLimits           map[string]BodyLimits
+	RateLimits           map[string]RateLimit
+	DefBodyLimits        BodyLimits
+	DefRateLimit         RateLimit
 	StoreInterval        int
 	Restore              bool
 	WaitSecRespDB        time.Duration
+	BatchWindow          time.Duration
+	Pipeline             PipelineConfig
+	Quotas               QuotaLimits
+}
+
+// QuotaLimits - ingestion limits,
+// zero or negative value means no limit.
+// MaxNewSeries is counted per source
+// and minute.
+type QuotaLimits struct {
+	MaxSeries    int
+	MaxNewSeries int
+	MaxBatch     int
+}
+
+// PipelineConfig - rules applied to every
+// received metric. Empty IDPattern means
+// the default pattern, empty Allow means
+// all names are allowed. AgentPolicy tells
+// how the agent id labels the names.
+type PipelineConfig struct {
+	IDPattern          string
+	Prefix             string
+	Allow              []string
+	Deny               []string
+	AgentPolicy        string
+	Rename             []RenameRule
+	DropNegativeDeltas bool
+}
+
+// RenameRule - replaces the metric name
+// matching the regular expression,
+// Replace may refer to its groups.
+type RenameRule struct {
+	Match   string
+	Replace string
+}
+
+// BodyLimits - store request body size limits.
+// Zero value means no limit.
+type BodyLimits struct {
+	MaxCompressed   int64
+	MaxDecompressed int64
+}
+
+// RateLimit - token bucket of a route,
+// Rate is in requests per second,
+// zero or negative value means no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// GetRateLimit - returns the rate limit
+// configured for the route or the default one.
+func (p *InitParams) GetRateLimit(route string) RateLimit {
+	limit, ok := p.RateLimits[route]
+	if !ok {
+		return p.DefRateLimit
+	}
+
+	if limit.Rate == 0 {
+		limit.Rate = p.DefRateLimit.Rate
+	}
+
+	if limit.Burst == 0 {
+		limit.Burst = p.DefRateLimit.Burst
+	}
+
+	return limit
+}
+
+// GetBodyLimits - returns the limits configured
+// for the route or the default ones.
+func (p *InitParams) GetBodyLimits(
+	route string,
+) BodyLimits {
+	limits, ok := p.BodyLimits[route]
+	if !ok {
+		return p.DefBodyLimits
+	}
+
+	if limits.MaxCompressed == 0 {
+		limits.MaxCompressed = p.DefBodyLimits.MaxCompressed
+	}
+
+	if limits.MaxDecompressed == 0 {
+		limits.MaxDecompressed = p.DefBodyLimits.MaxDecompressed
+	}
+
+	return limits
+}
+
+// BatchSender - sends metric batches
+// over a long-lived connection.
+type BatchSender interface {
+	SendBatch(metrics *apimodels.ArrMetrics) error
+	Close() error
+	TakeDropped() int64
+}
+
+// Spool - queue of the batches
+// not yet accepted by the server.
+type Spool interface {
+	Push(batch *apimodels.ArrMetrics) error
+	Drain(send func(batch *apimodels.ArrMetrics) error) error
+	Depth() int
+	TakeDropped() int64
+}
+
+// RetryPolicy - delays of the agent request
+// retries, the delay starts at Initial and
+// grows by Multiplier up to Max, it is spread
+// by the Jitter fraction. The retries stop
+// after Attempts or MaxElapsed.
+type RetryPolicy struct {
+	Initial    time.Duration
+	Max        time.Duration
+	MaxElapsed time.Duration
+	Multiplier float64
+	Jitter     float64
+	Attempts   int
+}
+
+// Breaker - stops the requests
+// to a failing server.
+type Breaker interface {
+	Allow() error
+	Success()
+	Failure()
+}
+
+// GRPCClient - connection to the grpc server.
+// TLS is used as set, when it is not set TLS is
+// used if a CA or client certificate is
+// configured, the system roots are trusted
+// without the CA. Keepalive pings are
+// sent after Keepalive of inactivity.
+type GRPCClient struct {
+	Addr                string
+	CAFile              string
+	CertFile            string
+	KeyFile             string
+	ServerName          string
+	Keepalive           time.Duration
+	KeepaliveTimeout    time.Duration
+	TLS                 *bool
+	PermitWithoutStream bool
+}
+
+// Aggregator - aggregates the gauges polled
+// between reports, the values polled until
+// a report are dropped for the consumer
+// it is delivered to.
+type Aggregator interface {
+	Add(sample *Sample)
+	Gauges(consumer string, until time.Time) []Gauge
+	Drop(consumer string, until time.Time)
+}
+
+// AgentInfo - identity and attributes
+// of the agent sent with every batch.
+type AgentInfo struct {
+	ID       string
+	Hostname string
+	Version  string
+	OS       string
+}
+
+// Destination - server the agent sends
+// every report to with its own transport,
+// keys, retry policy and outbox. Unsent
+// keeps the counter increments of the
+// reports not delivered to the server.
+type Destination struct {
+	Outbox              Spool
+	Breaker             Breaker
+	ConnGRPC            *grpc.ClientConn
+	MicroServiceClient  pb.MicroServiceClient
+	Name                string
+	URL                 string
+	UpdateURL           string
+	Key                 string
+	CryptoPublicKeyPath string
+	Agent               *AgentInfo
+	GRPC                GRPCClient
+	Unsent              Deltas
+	Retry               RetryPolicy
+	BreakerCooldown     time.Duration
+	BreakerFailures     int
+	Sending             atomic.Bool
+	UseGRPC             bool
 }
 
 // InitParamsAgent - store agent configuration.
 type InitParamsAgent struct {
+	Stream              BatchSender
+	Aggregator          Aggregator
+	CollectorsCfg       map[string]CollectorConfig
+	Aggregation         map[string][]string
+	Destinations        []*Destination
+	Collectors          []PolledCollector
 	ConfigPath          string
 	URL                 string
 	PORT                string
@@ -132,14 +407,20 @@ type InitParamsAgent struct {
 	CryptoPublicKeyPath string
 	UpdateURL           string
 	GRPCPort            string
+	OutboxPath          string
+	AgentIDFile         string
+	Agent               AgentInfo
+	GRPC                GRPCClient
+	Retry               RetryPolicy
+	BreakerCooldown     time.Duration
+	ReportJitter        float64
 	ReportInterval      int
 	PollInterval        int
-	ReqInternal         int
-	StartReqInterval    int
-	CountReqRetries     int
 	RateLimit           int
-	RepeatedReq         bool
+	OutboxSize          int
+	BreakerFailures     int
 	UseGRPC             bool
+	UseStream           bool
 }
 
 // EndpointSettings - store endpoint configuration.
@@ -147,10 +428,12 @@ type EndpointSettings struct {
 	SendData           *bytes.Reader
 	Client             *http.Client
 	ConnGRPC           *grpc.ClientConn
-	MetricsGRPC        *[]byte
+	RequestGRPC        *pb.SenderRequest
 	MicroServiceClient pb.MicroServiceClient
+	Agent              *AgentInfo
 	URL                string
 	Hash               string
+	BatchID            string
 	Encoding           string
 	ContentType        string
 	RealIPHeader       string
@@ -158,9 +441,12 @@ type EndpointSettings struct {
 
 // JobData - store data for the worker.
 type JobData struct {
-	Mutex  *sync.Mutex
-	Par    *InitParamsAgent
-	Client *http.Client
-	Mon    *Monitor
-	Event  string
+	Collector *PolledCollector
+	Par       *InitParamsAgent
+	Dest      *Destination
+	Data      *apimodels.ArrMetrics
+	Taken     time.Time
+	Client    *http.Client
+	Mon       *Monitor
+	Event     string
 }
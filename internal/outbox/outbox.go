@@ -0,0 +1,328 @@
+// Package outbox provides the bounded on-disk
+// queue of the agent batches not yet accepted
+// by the server, the batches are replayed
+// in the order they were spooled.
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// batchExt - extension of the batch files.
+const batchExt = ".json"
+
+// batchMode - permissions of the batch files.
+const batchMode = 0o600
+
+// dirMode - permissions of the outbox directory.
+const dirMode = 0o750
+
+// seqWidth - digits of the batch file
+// names, keeps the names sorted by number.
+const seqWidth = 20
+
+var errLimit = errors.New(
+	"outbox limit must be positive")
+
+// Outbox - batches spooled to the directory,
+// at most limit batches are kept.
+type Outbox struct {
+	names   []string
+	dir     string
+	sending string
+	seq     uint64
+	dropped int64
+	limit   int
+	mutex   sync.Mutex
+	drain   sync.Mutex
+}
+
+// Open - opens the outbox directory
+// and picks up the batches left there.
+func Open(dir string, limit int) (*Outbox, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("Open: %w", errLimit)
+	}
+
+	err := os.MkdirAll(dir, dirMode)
+	if err != nil {
+		return nil, fmt.Errorf("Open->MkdirAll: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Open->ReadDir: %w", err)
+	}
+
+	box := &Outbox{dir: dir, limit: limit}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		seq, ok := parseName(name)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		box.names = append(box.names, name)
+		box.seq = max(box.seq, seq)
+	}
+
+	slices.Sort(box.names)
+
+	return box, nil
+}
+
+// Push - spools the batch, when the outbox is
+// full the oldest batches are dropped and their
+// counter deltas are carried into the new one.
+func (o *Outbox) Push(batch *apimodels.ArrMetrics) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	merged := slices.Clone(*batch)
+
+	for len(o.names) >= o.limit {
+		idx := o.oldestIdle()
+		if idx < 0 {
+			break
+		}
+
+		old, err := o.read(o.names[idx])
+		if err == nil {
+			merged = mergeCounters(old, merged)
+		}
+
+		err = o.remove(idx)
+		if err != nil {
+			return fmt.Errorf("Push->remove: %w", err)
+		}
+
+		o.dropped++
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("Push->Marshal: %w", err)
+	}
+
+	name := batchName(o.seq + 1)
+	tmp := filepath.Join(o.dir, name+".tmp")
+
+	err = os.WriteFile(tmp, data, batchMode)
+	if err != nil {
+		return fmt.Errorf("Push->WriteFile: %w", err)
+	}
+
+	err = os.Rename(tmp, filepath.Join(o.dir, name))
+	if err != nil {
+		return fmt.Errorf("Push->Rename: %w", err)
+	}
+
+	o.seq++
+	o.names = append(o.names, name)
+
+	return nil
+}
+
+// Drain - sends the batches oldest first and
+// removes the sent ones, stops at the first
+// error of the send. Only one drain runs at
+// a time, the batches pushed meanwhile are
+// sent by the same drain.
+func (o *Outbox) Drain(
+	send func(batch *apimodels.ArrMetrics) error,
+) error {
+	o.drain.Lock()
+	defer o.drain.Unlock()
+
+	for {
+		batch, ok, err := o.peek()
+		if err != nil {
+			return fmt.Errorf("Drain->peek: %w", err)
+		}
+
+		if !ok {
+			return nil
+		}
+
+		err = send(batch)
+
+		ackErr := o.ack(err == nil)
+		if err != nil {
+			return fmt.Errorf("Drain->send: %w", err)
+		}
+
+		if ackErr != nil {
+			return fmt.Errorf("Drain->ack: %w", ackErr)
+		}
+	}
+}
+
+// Depth - returns the number of spooled batches.
+func (o *Outbox) Depth() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.names)
+}
+
+// TakeDropped - returns the number of batches
+// dropped since the previous call.
+func (o *Outbox) TakeDropped() int64 {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	dropped := o.dropped
+	o.dropped = 0
+
+	return dropped
+}
+
+// peek - returns the oldest batch and marks
+// it as being sent, unreadable batches
+// are dropped.
+func (o *Outbox) peek() (
+	*apimodels.ArrMetrics, bool, error,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for len(o.names) > 0 {
+		batch, err := o.read(o.names[0])
+		if err == nil {
+			o.sending = o.names[0]
+
+			return &batch, true, nil
+		}
+
+		fmt.Println("peek->read:", o.names[0], err)
+
+		err = o.remove(0)
+		if err != nil {
+			return nil, false, fmt.Errorf("peek->remove: %w", err)
+		}
+
+		o.dropped++
+	}
+
+	return nil, false, nil
+}
+
+// ack - ends the send of the oldest
+// batch and removes it when it is sent.
+func (o *Outbox) ack(sent bool) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	sending := o.sending
+	o.sending = ""
+
+	if !sent || len(o.names) == 0 ||
+		o.names[0] != sending {
+		return nil
+	}
+
+	return o.remove(0)
+}
+
+// oldestIdle - returns the index of the
+// oldest batch not being sent or -1.
+func (o *Outbox) oldestIdle() int {
+	for idx, name := range o.names {
+		if name != o.sending {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// read - reads the batch file.
+func (o *Outbox) read(
+	name string,
+) (apimodels.ArrMetrics, error) {
+	data, err := os.ReadFile(filepath.Join(o.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("read->ReadFile: %w", err)
+	}
+
+	var batch apimodels.ArrMetrics
+
+	err = json.Unmarshal(data, &batch)
+	if err != nil {
+		return nil, fmt.Errorf("read->Unmarshal: %w", err)
+	}
+
+	return batch, nil
+}
+
+// remove - deletes the batch file.
+func (o *Outbox) remove(idx int) error {
+	err := os.Remove(filepath.Join(o.dir, o.names[idx]))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove->Remove: %w", err)
+	}
+
+	o.names = slices.Delete(o.names, idx, idx+1)
+
+	return nil
+}
+
+// mergeCounters - adds the counter
+// deltas of the old batch to the batch.
+func mergeCounters(
+	old, batch apimodels.ArrMetrics,
+) apimodels.ArrMetrics {
+	for _, metric := range old {
+		if metric.MType != bizmodels.CounterName ||
+			metric.Delta == nil {
+			continue
+		}
+
+		idx := slices.IndexFunc(batch,
+			func(m apimodels.Metrics) bool {
+				return m.MType == bizmodels.CounterName &&
+					m.ID == metric.ID && m.Delta != nil
+			})
+
+		if idx < 0 {
+			batch = append(batch, metric)
+
+			continue
+		}
+
+		delta := *batch[idx].Delta + *metric.Delta
+		batch[idx].Delta = &delta
+	}
+
+	return batch
+}
+
+// batchName - returns the file name of the batch.
+func batchName(seq uint64) string {
+	return fmt.Sprintf("%0*d%s", seqWidth, seq, batchExt)
+}
+
+// parseName - returns the number of the batch file.
+func parseName(name string) (uint64, bool) {
+	num, ok := strings.CutSuffix(name, batchExt)
+	if !ok || len(num) != seqWidth {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(num, 10, 64)
+
+	return seq, err == nil
+}
@@ -0,0 +1,105 @@
+package outbox_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+var errDown = errors.New("server is down")
+
+func batch(
+	delta int64,
+	value float64,
+) *apimodels.ArrMetrics {
+	return &apimodels.ArrMetrics{
+		{ID: "PollCount", MType: bizmodels.CounterName,
+			Delta: &delta},
+		{ID: "Alloc", MType: bizmodels.GaugeName,
+			Value: &value},
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	box, err := outbox.Open(dir, 2)
+	assert.NoError(t, err)
+
+	for i := range 3 {
+		assert.NoError(t, box.Push(batch(1, float64(i))))
+	}
+
+	assert.Equal(t, 2, box.Depth())
+	assert.Equal(t, int64(1), box.TakeDropped())
+	assert.Equal(t, int64(0), box.TakeDropped())
+
+	err = box.Drain(func(*apimodels.ArrMetrics) error {
+		return errDown
+	})
+	assert.ErrorIs(t, err, errDown)
+	assert.Equal(t, 2, box.Depth())
+
+	box, err = outbox.Open(dir, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, box.Depth())
+
+	sent := make([]apimodels.ArrMetrics, 0, 2)
+	err = box.Drain(func(b *apimodels.ArrMetrics) error {
+		sent = append(sent, *b)
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, box.Depth())
+	assert.Equal(t,
+		[]apimodels.ArrMetrics{*batch(1, 1), *batch(2, 2)}, sent)
+
+	assert.NoError(t, box.Push(batch(1, 3)))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.NoError(t,
+		os.WriteFile(files[0], []byte("{"), 0o600))
+
+	err = box.Drain(func(*apimodels.ArrMetrics) error {
+		return errDown
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, box.Depth())
+	assert.Equal(t, int64(1), box.TakeDropped())
+
+	_, err = outbox.Open(dir, 0)
+	assert.Error(t, err)
+}
+
+func TestDrainPush(t *testing.T) {
+	t.Parallel()
+
+	box, err := outbox.Open(t.TempDir(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, box.Push(batch(1, 1)))
+
+	sends := 0
+	err = box.Drain(func(*apimodels.ArrMetrics) error {
+		sends++
+		if sends == 1 {
+			assert.NoError(t, box.Push(batch(1, 2)))
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sends)
+	assert.Equal(t, 0, box.Depth())
+	assert.Equal(t, int64(0), box.TakeDropped())
+}
@@ -0,0 +1,371 @@
+// Package pipeline validates and relabels
+// the metrics received by the server.
+// The same pipeline is applied on every
+// write path and counts the dropped
+// metrics per rule.
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// DefIDPattern - metric names
+// accepted by default.
+const DefIDPattern = "^[0-9a-zA-Z/_ ]{1,40}$"
+
+// DefLabeledIDPattern - metric names accepted
+// by default when the agent id labels them,
+// there is room for an id of up to 40
+// symbols and the separator, so the name
+// keeps the default length.
+const DefLabeledIDPattern = "^[0-9a-zA-Z/_ ]{1,81}$"
+
+// RuleType - drops metrics of unknown type.
+const RuleType = "type"
+
+// RuleValue - drops metrics without a value.
+const RuleValue = "value"
+
+// RuleNonFinite - drops NaN and Inf gauges.
+const RuleNonFinite = "non_finite"
+
+// RuleNegativeDelta - drops negative
+// counter deltas when configured.
+const RuleNegativeDelta = "negative_delta"
+
+// RuleID - drops names not matching
+// the id pattern after relabeling.
+const RuleID = "id"
+
+// RuleAllow - drops names not
+// matching any allow expression.
+const RuleAllow = "allow"
+
+// AgentNone - the agent id is not
+// part of the metric names.
+const AgentNone = "none"
+
+// AgentPrefix - the names are
+// prefixed with the agent id.
+const AgentPrefix = "prefix"
+
+// AgentSeries - every agent keeps its own
+// series, the agent id is the name suffix.
+const AgentSeries = "series"
+
+// agentSeparator - joins the agent
+// id and the metric name.
+const agentSeparator = "/"
+
+// ruleDeny - prefix of the deny rules,
+// every expression is counted on its own.
+const ruleDeny = "deny:"
+
+const reasonType = "invalid type"
+
+const reasonValue = "missing value"
+
+const reasonDelta = "missing delta"
+
+const reasonNonFinite = "not finite value"
+
+const reasonNegative = "negative delta"
+
+const reasonID = "invalid id"
+
+const reasonAllow = "not allowed"
+
+const reasonDeny = "denied"
+
+var errEmptyMatch = errors.New("rename rule without match")
+
+var errAgentPolicy = errors.New("unknown agent policy")
+
+// Drop - the rule that dropped
+// the metric and the reason.
+type Drop struct {
+	Rule   string
+	Reason string
+}
+
+// rename - compiled rename rule.
+type rename struct {
+	match   *regexp.Regexp
+	replace string
+}
+
+// deny - compiled deny rule.
+type deny struct {
+	expr *regexp.Regexp
+	rule string
+}
+
+// Pipeline - compiled ingestion rules.
+// Safe for concurrent use.
+type Pipeline struct {
+	idPattern          *regexp.Regexp
+	allow              []*regexp.Regexp
+	deny               []deny
+	rename             []rename
+	dropped            map[string]*atomic.Uint64
+	prefix             string
+	agentPolicy        string
+	dropNegativeDeltas bool
+}
+
+// New - compiles the rules of the config.
+func New(cfg *bizmodels.PipelineConfig) (*Pipeline, error) {
+	pattern := cfg.IDPattern
+
+	switch {
+	case pattern != "":
+	case cfg.AgentPolicy == AgentPrefix,
+		cfg.AgentPolicy == AgentSeries:
+		pattern = DefLabeledIDPattern
+	default:
+		pattern = DefIDPattern
+	}
+
+	idPattern, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("New->Compile: %w", err)
+	}
+
+	switch cfg.AgentPolicy {
+	case "", AgentNone, AgentPrefix, AgentSeries:
+	default:
+		return nil, fmt.Errorf("New: %w: %s",
+			errAgentPolicy, cfg.AgentPolicy)
+	}
+
+	pipe := &Pipeline{
+		idPattern:          idPattern,
+		prefix:             cfg.Prefix,
+		agentPolicy:        cfg.AgentPolicy,
+		dropNegativeDeltas: cfg.DropNegativeDeltas,
+		dropped:            make(map[string]*atomic.Uint64),
+	}
+
+	pipe.allow, err = compileAll(cfg.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("New->compileAll: %w", err)
+	}
+
+	denied, err := compileAll(cfg.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("New->compileAll: %w", err)
+	}
+
+	for i, expr := range denied {
+		pipe.deny = append(pipe.deny,
+			deny{expr: expr, rule: ruleDeny + cfg.Deny[i]})
+	}
+
+	for _, rule := range cfg.Rename {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("New: %w", errEmptyMatch)
+		}
+
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("New->Compile: %w", err)
+		}
+
+		pipe.rename = append(pipe.rename,
+			rename{match: match, replace: rule.Replace})
+	}
+
+	rules := []string{RuleType, RuleValue, RuleNonFinite,
+		RuleNegativeDelta, RuleID, RuleAllow}
+
+	for _, rule := range pipe.deny {
+		rules = append(rules, rule.rule)
+	}
+
+	for _, rule := range rules {
+		pipe.dropped[rule] = &atomic.Uint64{}
+	}
+
+	return pipe, nil
+}
+
+// Default - pipeline that only validates
+// metrics with the default rules.
+func Default() *Pipeline {
+	pipe, _ := New(&bizmodels.PipelineConfig{})
+
+	return pipe
+}
+
+// compileAll - compiles the expressions.
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+
+	for _, expr := range exprs {
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("compileAll->Compile: %w", err)
+		}
+
+		res = append(res, compiled)
+	}
+
+	return res, nil
+}
+
+// Process - checks the metric and relabels
+// its name, returns the rule that dropped
+// the metric or nil for a kept one.
+// The type and value are checked first,
+// then the name is renamed and prefixed,
+// the id pattern and the allow and deny
+// lists are checked against the new name.
+func (p *Pipeline) Process(met *apimodels.Metrics) *Drop {
+	return p.ProcessFrom(met, "")
+}
+
+// ProcessFrom - processes the metric sent
+// by the agent, the agent id is added to
+// the prefixed name by the agent policy.
+func (p *Pipeline) ProcessFrom(
+	met *apimodels.Metrics,
+	agent string,
+) *Drop {
+	drop := p.checkValue(met)
+	if drop != nil {
+		return p.count(drop)
+	}
+
+	name := met.ID
+
+	for _, rule := range p.rename {
+		name = rule.match.ReplaceAllString(name, rule.replace)
+	}
+
+	name = p.label(p.prefix+name, agent)
+
+	drop = p.checkName(name)
+	if drop != nil {
+		return p.count(drop)
+	}
+
+	met.ID = name
+
+	return nil
+}
+
+// ValidID - checks the name against the id
+// pattern, used to look up stored metrics.
+func (p *Pipeline) ValidID(name string) bool {
+	return p.idPattern.MatchString(name)
+}
+
+// ValidType - checks the metric type.
+func ValidType(mtype string) bool {
+	return mtype == bizmodels.GaugeName ||
+		mtype == bizmodels.CounterName
+}
+
+// Stats - dropped metrics per rule.
+func (p *Pipeline) Stats() map[string]uint64 {
+	stats := make(map[string]uint64, len(p.dropped))
+
+	for rule, cnt := range p.dropped {
+		stats[rule] = cnt.Load()
+	}
+
+	return stats
+}
+
+// checkValue - checks the type
+// and the value of the metric.
+func (p *Pipeline) checkValue(
+	met *apimodels.Metrics,
+) *Drop {
+	switch met.MType {
+	case bizmodels.GaugeName:
+		if met.Value == nil {
+			return &Drop{Rule: RuleValue, Reason: reasonValue}
+		}
+
+		if math.IsNaN(*met.Value) ||
+			math.IsInf(*met.Value, 0) {
+			return &Drop{Rule: RuleNonFinite,
+				Reason: reasonNonFinite}
+		}
+	case bizmodels.CounterName:
+		if met.Delta == nil {
+			return &Drop{Rule: RuleValue, Reason: reasonDelta}
+		}
+
+		if p.dropNegativeDeltas && *met.Delta < 0 {
+			return &Drop{Rule: RuleNegativeDelta,
+				Reason: reasonNegative}
+		}
+	default:
+		return &Drop{Rule: RuleType, Reason: reasonType}
+	}
+
+	return nil
+}
+
+// label - adds the agent id to the name.
+func (p *Pipeline) label(name, agent string) string {
+	if agent == "" {
+		return name
+	}
+
+	switch p.agentPolicy {
+	case AgentPrefix:
+		return agent + agentSeparator + name
+	case AgentSeries:
+		return name + agentSeparator + agent
+	}
+
+	return name
+}
+
+// checkName - checks the relabeled name.
+func (p *Pipeline) checkName(name string) *Drop {
+	if !p.idPattern.MatchString(name) {
+		return &Drop{Rule: RuleID, Reason: reasonID}
+	}
+
+	if len(p.allow) > 0 && !matchAny(p.allow, name) {
+		return &Drop{Rule: RuleAllow, Reason: reasonAllow}
+	}
+
+	for _, rule := range p.deny {
+		if rule.expr.MatchString(name) {
+			return &Drop{Rule: rule.rule, Reason: reasonDeny}
+		}
+	}
+
+	return nil
+}
+
+// count - counts the dropped metric.
+func (p *Pipeline) count(drop *Drop) *Drop {
+	p.dropped[drop.Rule].Add(1)
+
+	return drop
+}
+
+// matchAny - checks the name
+// against the expressions.
+func matchAny(exprs []*regexp.Regexp, name string) bool {
+	for _, expr := range exprs {
+		if expr.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
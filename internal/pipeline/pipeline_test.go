@@ -0,0 +1,191 @@
+package pipeline_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testData struct {
+	tn     string
+	metric apimodels.Metrics
+	exid   string
+	exrule string
+}
+
+func gauge(id string, value float64) apimodels.Metrics {
+	return apimodels.Metrics{
+		ID: id, MType: bizmodels.GaugeName, Value: &value,
+	}
+}
+
+func counter(id string, delta int64) apimodels.Metrics {
+	return apimodels.Metrics{
+		ID: id, MType: bizmodels.CounterName, Delta: &delta,
+	}
+}
+
+func getTestData() []testData {
+	return []testData{
+		{tn: "1", metric: gauge("Alloc", 1), exid: "app/Alloc"},
+		{
+			tn: "2", metric: counter("Poll", -1),
+			exrule: pipeline.RuleNegativeDelta,
+		},
+		{
+			tn: "3", metric: gauge("Heap", math.NaN()),
+			exrule: pipeline.RuleNonFinite,
+		},
+		{
+			tn: "4", metric: gauge("Heap", math.Inf(1)),
+			exrule: pipeline.RuleNonFinite,
+		},
+		{
+			tn: "5", metric: apimodels.Metrics{
+				ID: "A", MType: "hist",
+			},
+			exrule: pipeline.RuleType,
+		},
+		{
+			tn: "6", metric: apimodels.Metrics{
+				ID: "A", MType: bizmodels.GaugeName,
+			},
+			exrule: pipeline.RuleValue,
+		},
+		{
+			tn: "7", metric: gauge("go.mem.heap", 1),
+			exid: "app/mem/heap",
+		},
+		{
+			tn: "8", metric: gauge("Bad*", 1),
+			exrule: pipeline.RuleID,
+		},
+		{
+			tn: "9", metric: gauge("Other", 1),
+			exrule: pipeline.RuleAllow,
+		},
+		{
+			tn: "10", metric: gauge("AllocDebug", 1),
+			exrule: "deny:Debug$",
+		},
+	}
+}
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	pipe, err := pipeline.New(&bizmodels.PipelineConfig{
+		Prefix: "app/",
+		Allow:  []string{"^app/(Alloc|Heap|Poll|mem)"},
+		Deny:   []string{"Debug$"},
+		Rename: []bizmodels.RenameRule{
+			{Match: `^go\.`, Replace: ""},
+			{Match: `\.`, Replace: "/"},
+		},
+		DropNegativeDeltas: true,
+	})
+	require.NoError(t, err)
+
+	for _, test := range getTestData() {
+		drop := pipe.Process(&test.metric)
+
+		if test.exrule != "" {
+			require.NotNil(t, drop, test.tn)
+			assert.Equal(t, test.exrule, drop.Rule, test.tn)
+
+			continue
+		}
+
+		assert.Nil(t, drop, test.tn)
+		assert.Equal(t, test.exid, test.metric.ID, test.tn)
+	}
+
+	stats := pipe.Stats()
+	assert.Equal(t, uint64(2), stats[pipeline.RuleNonFinite])
+	assert.Equal(t, uint64(1), stats["deny:Debug$"])
+	assert.Equal(t, uint64(1), stats[pipeline.RuleID])
+	assert.Equal(t, uint64(1), stats[pipeline.RuleAllow])
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	pipe := pipeline.Default()
+	metric := counter("Poll", -1)
+
+	assert.Nil(t, pipe.Process(&metric))
+	assert.Equal(t, "Poll", metric.ID)
+	assert.False(t, pipe.ValidID("Name-123!"))
+	assert.True(t, pipe.ValidID("HeapAlloc_max"))
+	assert.True(t, pipeline.ValidType(bizmodels.CounterName))
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	for _, cfg := range []bizmodels.PipelineConfig{
+		{IDPattern: "("},
+		{Allow: []string{"["}},
+		{Deny: []string{"*"}},
+		{Rename: []bizmodels.RenameRule{{Match: ""}}},
+		{AgentPolicy: "label"},
+	} {
+		_, err := pipeline.New(&cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestProcessFrom(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"":                   "app/Alloc",
+		pipeline.AgentNone:   "app/Alloc",
+		pipeline.AgentPrefix: "a1/app/Alloc",
+		pipeline.AgentSeries: "app/Alloc/a1",
+	}
+
+	for policy, exid := range cases {
+		pipe, err := pipeline.New(&bizmodels.PipelineConfig{
+			Prefix:      "app/",
+			AgentPolicy: policy,
+		})
+		require.NoError(t, err)
+
+		metric := gauge("Alloc", 1)
+		assert.Nil(t, pipe.ProcessFrom(&metric, "a1"), policy)
+		assert.Equal(t, exid, metric.ID, policy)
+
+		metric = gauge("Alloc", 1)
+		assert.Nil(t, pipe.ProcessFrom(&metric, ""), policy)
+		assert.Equal(t, "app/Alloc", metric.ID, policy)
+	}
+}
+
+func TestProcessFromLongName(t *testing.T) {
+	t.Parallel()
+
+	// a disk collector series with a generated agent id
+	const (
+		name  = "DiskUsedPercent/var/lib/docker"
+		agent = "0123456789abcdef"
+	)
+
+	for _, policy := range []string{
+		pipeline.AgentPrefix, pipeline.AgentSeries,
+	} {
+		pipe, err := pipeline.New(&bizmodels.PipelineConfig{
+			AgentPolicy: policy,
+		})
+		require.NoError(t, err)
+
+		metric := gauge(name, 1)
+		assert.Nil(t, pipe.ProcessFrom(&metric, agent), policy)
+		assert.True(t, pipe.ValidID(metric.ID), policy)
+	}
+}
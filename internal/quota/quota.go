@@ -0,0 +1,266 @@
+// Package quota limits the series created
+// by the agents: the total number of series,
+// the new series per source and minute
+// and the size of a batch.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+)
+
+// ReasonSeries - the total
+// series limit is reached.
+const ReasonSeries = "series limit"
+
+// ReasonNewSeries - the source created
+// too many series in the last minute.
+const ReasonNewSeries = "new series limit"
+
+// ReasonUnavailable - the stored
+// series could not be loaded.
+const ReasonUnavailable = "quota unavailable"
+
+// window - period of the new series limit.
+const window = time.Minute
+
+// sweepInterval - how often
+// the idle sources are removed.
+const sweepInterval = time.Minute
+
+// idleTTL - sources without
+// admissions are forgotten after it.
+const idleTTL = time.Hour
+
+// ErrBatchTooLarge - returned for a batch
+// exceeding the batch size limit.
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// SeedFunc - returns the keys
+// of the stored series.
+type SeedFunc func() ([]string, error)
+
+// usage - usage of a source.
+type usage struct {
+	start     time.Time
+	last      time.Time
+	newSeries int
+	series    int
+	rejected  int
+}
+
+// Quota - tracks the known series and the
+// usage of every source. The stored series
+// are loaded on the first admission, every
+// series keeps the time it was reserved at.
+// Idle sources are removed.
+type Quota struct {
+	swept   time.Time
+	series  map[string]time.Time
+	sources map[string]*usage
+	seed    SeedFunc
+	limits  bizmodels.QuotaLimits
+	mutex   sync.Mutex
+	seeded  bool
+}
+
+// New - creates the quota.
+func New(
+	limits bizmodels.QuotaLimits,
+	seed SeedFunc,
+) *Quota {
+	return &Quota{
+		series:  make(map[string]time.Time),
+		sources: make(map[string]*usage),
+		seed:    seed,
+		limits:  limits,
+	}
+}
+
+// Key - identifies the series.
+func Key(mtype, name string) string {
+	return mtype + ":" + name
+}
+
+// CheckBatch - checks the batch size.
+func (q *Quota) CheckBatch(source string, size int) error {
+	if q.limits.MaxBatch <= 0 || size <= q.limits.MaxBatch {
+		return nil
+	}
+
+	now := time.Now()
+
+	q.mutex.Lock()
+	q.sweep(now)
+	q.source(source, now).rejected += size
+	q.mutex.Unlock()
+
+	return fmt.Errorf("CheckBatch: %w: %d metrics, limit %d",
+		ErrBatchTooLarge, size, q.limits.MaxBatch)
+}
+
+// Admit - checks the series against the
+// limits, returns the reason it is rejected
+// and whether a new series was reserved.
+// A reserved series that was not stored
+// must be released with Forget.
+func (q *Quota) Admit(source, key string) (string, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	err := q.load()
+	if err != nil {
+		fmt.Printf("Admit->load: %v\n", err)
+
+		return ReasonUnavailable, false
+	}
+
+	if _, ok := q.series[key]; ok {
+		return "", false
+	}
+
+	now := time.Now()
+	q.sweep(now)
+
+	use := q.source(source, now)
+
+	if now.Sub(use.start) >= window {
+		use.start = now
+		use.newSeries = 0
+	}
+
+	if q.limits.MaxSeries > 0 &&
+		len(q.series) >= q.limits.MaxSeries {
+		use.rejected++
+
+		return ReasonSeries, false
+	}
+
+	if q.limits.MaxNewSeries > 0 &&
+		use.newSeries >= q.limits.MaxNewSeries {
+		use.rejected++
+
+		return ReasonNewSeries, false
+	}
+
+	q.series[key] = now
+	use.newSeries++
+	use.series++
+
+	return "", true
+}
+
+// Forget - releases the series reserved by
+// Admit, the new series count of the source
+// only while the window it was reserved in
+// lasts.
+func (q *Quota) Forget(source, key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	reserved, ok := q.series[key]
+	if !ok {
+		return
+	}
+
+	delete(q.series, key)
+
+	use, ok := q.sources[source]
+	if !ok {
+		return
+	}
+
+	use.series = max(use.series-1, 0)
+
+	if !reserved.Before(use.start) &&
+		time.Since(use.start) < window {
+		use.newSeries = max(use.newSeries-1, 0)
+	}
+}
+
+// Usage - returns the limits, the number
+// of series and the usage per source.
+func (q *Quota) Usage() *apimodels.QuotaUsage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	res := &apimodels.QuotaUsage{
+		Sources: make(map[string]apimodels.SourceUsage,
+			len(q.sources)),
+		Limits: apimodels.QuotaLimits{
+			MaxSeries:    q.limits.MaxSeries,
+			MaxNewSeries: q.limits.MaxNewSeries,
+			MaxBatch:     q.limits.MaxBatch,
+		},
+		Series: len(q.series),
+	}
+
+	for name, use := range q.sources {
+		newSeries := use.newSeries
+		if time.Now().Sub(use.start) >= window {
+			newSeries = 0
+		}
+
+		res.Sources[name] = apimodels.SourceUsage{
+			Series:    use.series,
+			NewSeries: newSeries,
+			Rejected:  use.rejected,
+		}
+	}
+
+	return res
+}
+
+// source - returns the usage of the source.
+func (q *Quota) source(name string, now time.Time) *usage {
+	use, ok := q.sources[name]
+	if !ok {
+		use = &usage{start: now}
+		q.sources[name] = use
+	}
+
+	use.last = now
+
+	return use
+}
+
+// sweep - removes the sources idle
+// for longer than the ttl.
+func (q *Quota) sweep(now time.Time) {
+	if now.Sub(q.swept) < sweepInterval {
+		return
+	}
+
+	q.swept = now
+
+	for name, use := range q.sources {
+		if now.Sub(use.last) >= idleTTL {
+			delete(q.sources, name)
+		}
+	}
+}
+
+// load - loads the stored series once.
+func (q *Quota) load() error {
+	if q.seeded || q.seed == nil {
+		return nil
+	}
+
+	keys, err := q.seed()
+	if err != nil {
+		return fmt.Errorf("load->seed: %w", err)
+	}
+
+	for _, key := range keys {
+		q.series[key] = time.Time{}
+	}
+
+	q.seeded = true
+
+	return nil
+}
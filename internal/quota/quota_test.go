@@ -0,0 +1,85 @@
+package quota_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
+	"github.com/stretchr/testify/assert"
+)
+
+var errSeed = errors.New("seed")
+
+func TestAdmit(t *testing.T) {
+	t.Parallel()
+
+	seed := func() ([]string, error) {
+		return []string{quota.Key("gauge", "Alloc")}, nil
+	}
+
+	quo := quota.New(bizmodels.QuotaLimits{
+		MaxSeries: 4, MaxNewSeries: 2,
+	}, seed)
+
+	reason, isNew := quo.Admit("a",
+		quota.Key("gauge", "Alloc"))
+	assert.Empty(t, reason)
+	assert.False(t, isNew)
+
+	for _, name := range []string{"A", "B"} {
+		reason, isNew = quo.Admit("a", quota.Key("gauge", name))
+		assert.Empty(t, reason)
+		assert.True(t, isNew)
+	}
+
+	reason, _ = quo.Admit("a", quota.Key("gauge", "C"))
+	assert.Equal(t, quota.ReasonNewSeries, reason)
+
+	reason, _ = quo.Admit("b", quota.Key("counter", "A"))
+	assert.Empty(t, reason)
+
+	reason, _ = quo.Admit("b", quota.Key("counter", "B"))
+	assert.Equal(t, quota.ReasonSeries, reason)
+
+	quo.Forget("b", quota.Key("counter", "A"))
+
+	usage := quo.Usage()
+	assert.Equal(t, 3, usage.Series)
+	assert.Equal(t, 2, usage.Sources["a"].Series)
+	assert.Equal(t, 1, usage.Sources["a"].Rejected)
+	assert.Equal(t, 0, usage.Sources["b"].NewSeries)
+	assert.Equal(t, 4, usage.Limits.MaxSeries)
+}
+
+func TestCheckBatch(t *testing.T) {
+	t.Parallel()
+
+	quo := quota.New(bizmodels.QuotaLimits{MaxBatch: 2}, nil)
+
+	assert.NoError(t, quo.CheckBatch("a", 2))
+	assert.ErrorIs(t, quo.CheckBatch("a", 3),
+		quota.ErrBatchTooLarge)
+	assert.Equal(t, 3, quo.Usage().Sources["a"].Rejected)
+}
+
+func TestSeedError(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	quo := quota.New(bizmodels.QuotaLimits{},
+		func() ([]string, error) {
+			calls++
+			if calls == 1 {
+				return nil, errSeed
+			}
+
+			return nil, nil
+		})
+
+	reason, _ := quo.Admit("a", quota.Key("gauge", "A"))
+	assert.Equal(t, quota.ReasonUnavailable, reason)
+
+	reason, _ = quo.Admit("a", quota.Key("gauge", "A"))
+	assert.Empty(t, reason)
+}
@@ -0,0 +1,52 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForgetAfterWindow(t *testing.T) {
+	t.Parallel()
+
+	quo := New(bizmodels.QuotaLimits{MaxNewSeries: 1}, nil)
+
+	reason, _ := quo.Admit("a", Key("gauge", "A"))
+	assert.Empty(t, reason)
+
+	quo.sources["a"].start = time.Now().Add(-2 * window)
+
+	reason, _ = quo.Admit("a", Key("gauge", "B"))
+	assert.Empty(t, reason)
+
+	// reserved in the previous window.
+	quo.Forget("a", Key("gauge", "A"))
+
+	reason, _ = quo.Admit("a", Key("gauge", "C"))
+	assert.Equal(t, ReasonNewSeries, reason)
+	assert.Equal(t, 1, quo.Usage().Sources["a"].Series)
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+
+	quo := New(bizmodels.QuotaLimits{}, nil)
+
+	for _, source := range []string{"a", "b"} {
+		reason, _ := quo.Admit(source, Key("gauge", source))
+		assert.Empty(t, reason)
+	}
+
+	quo.sources["a"].last = time.Now().Add(-idleTTL)
+	quo.swept = time.Time{}
+
+	reason, _ := quo.Admit("b", Key("gauge", "C"))
+	assert.Empty(t, reason)
+
+	usage := quo.Usage()
+	assert.NotContains(t, usage.Sources, "a")
+	assert.Contains(t, usage.Sources, "b")
+	assert.Equal(t, 3, usage.Series)
+}
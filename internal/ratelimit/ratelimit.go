@@ -0,0 +1,323 @@
+// Package ratelimit limits the ingestion
+// requests of every client with token buckets
+// and reads the retry delay hinted
+// by the server on the agent side.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/source"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Header - http header of the retry delay.
+const Header = "Retry-After"
+
+// MetadataKey - grpc header metadata
+// key of the retry delay.
+const MetadataKey = "retry-after"
+
+// KeyRealIP - buckets are keyed by the
+// X-Real-IP address. The client may set it
+// to anything, so the mode is only safe
+// behind a trusted proxy that sets it.
+const KeyRealIP = "real-ip"
+
+// KeyIP - buckets are keyed
+// by the peer address.
+const KeyIP = "ip"
+
+// KeyAgent - buckets are keyed by the common
+// name of the verified client certificate of
+// the agent, requests without one are keyed
+// by the peer address.
+const KeyAgent = "agent"
+
+// sweepInterval - how often
+// the idle buckets are removed.
+const sweepInterval = time.Minute
+
+// errLimited - returned by Wait
+// when the context ends first.
+var errLimited = errors.New("rate limited")
+
+// ErrKey - returned for an unknown key mode.
+var ErrKey = errors.New("unknown rate limit key")
+
+// bucket - tokens of a client.
+type bucket struct {
+	last   time.Time
+	tokens float64
+}
+
+// Limiter - token buckets of a route,
+// a nil limiter allows every request.
+type Limiter struct {
+	buckets map[string]*bucket
+	swept   time.Time
+	rate    float64
+	burst   float64
+	mutex   sync.Mutex
+}
+
+// New - creates the limiter, returns nil
+// when the rate is not limited. The burst
+// defaults to one second of requests.
+func New(limit bizmodels.RateLimit) *Limiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(limit.Rate))
+	}
+
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+		rate:    limit.Rate,
+		burst:   burst,
+	}
+}
+
+// Allow - takes a token of the client,
+// otherwise returns how long to wait
+// for the next one.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	buck, ok := l.buckets[key]
+	if !ok {
+		buck = &bucket{last: now, tokens: l.burst}
+		l.buckets[key] = buck
+	}
+
+	buck.tokens = math.Min(l.burst,
+		buck.tokens+now.Sub(buck.last).Seconds()*l.rate)
+	buck.last = now
+
+	if buck.tokens >= 1 {
+		buck.tokens--
+
+		return true, 0
+	}
+
+	wait := (1 - buck.tokens) / l.rate
+
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// Wait - waits for a token of the client.
+func (l *Limiter) Wait(
+	ctx context.Context,
+	key string,
+) error {
+	for {
+		ok, wait := l.Allow(key)
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Wait: %w", errLimited)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// sweep - removes the buckets
+// refilled up to the burst.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+
+	l.swept = now
+	full := time.Duration(
+		l.burst / l.rate * float64(time.Second))
+
+	for key, buck := range l.buckets {
+		if now.Sub(buck.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Set - limiters of the routes,
+// created with the route limit on first use.
+type Set struct {
+	limiters map[string]*Limiter
+	params   *bizmodels.InitParams
+	mutex    sync.Mutex
+}
+
+// NewSet - creates the limiters
+// of the configured routes.
+func NewSet(params *bizmodels.InitParams) *Set {
+	return &Set{
+		limiters: make(map[string]*Limiter),
+		params:   params,
+	}
+}
+
+// Get - returns the limiter of the route.
+func (s *Set) Get(route string) *Limiter {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lim, ok := s.limiters[route]
+	if !ok {
+		lim = New(s.params.GetRateLimit(route))
+		s.limiters[route] = lim
+	}
+
+	return lim
+}
+
+// ValidateKey - checks the key mode.
+func ValidateKey(mode string) error {
+	switch mode {
+	case KeyRealIP, KeyIP, KeyAgent:
+		return nil
+	default:
+		return fmt.Errorf("ValidateKey: %w: %q", ErrKey, mode)
+	}
+}
+
+// RequestKey - returns the bucket
+// key of the http request.
+func RequestKey(req *http.Request, mode string) string {
+	switch mode {
+	case KeyIP:
+		return source.PeerFromRequest(req)
+	case KeyAgent:
+		name := source.CertFromRequest(req)
+		if name != "" {
+			return agentKey(name)
+		}
+
+		return source.PeerFromRequest(req)
+	}
+
+	return source.FromRequest(req)
+}
+
+// ContextKey - returns the bucket
+// key of the grpc request.
+func ContextKey(ctx context.Context, mode string) string {
+	switch mode {
+	case KeyIP:
+		return source.PeerFromContext(ctx)
+	case KeyAgent:
+		name := source.CertFromContext(ctx)
+		if name != "" {
+			return agentKey(name)
+		}
+
+		return source.PeerFromContext(ctx)
+	}
+
+	return source.FromContext(ctx)
+}
+
+// agentKey - keys the agent apart from the
+// addresses of agents without a certificate.
+func agentKey(id string) string {
+	return KeyAgent + ":" + id
+}
+
+// Seconds - formats the delay
+// in whole seconds, at least one.
+func Seconds(wait time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(wait.Seconds()))))
+}
+
+// Exhausted - RESOURCE_EXHAUSTED error
+// with the retry delay in the details.
+func Exhausted(wait time.Duration) error {
+	stat := status.New(codes.ResourceExhausted,
+		errLimited.Error())
+
+	detailed, err := stat.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(wait),
+	})
+	if err != nil {
+		return stat.Err()
+	}
+
+	return detailed.Err()
+}
+
+// FromResponse - returns the delay of a too
+// many requests response or of an unavailable
+// response with the retry delay, the delay
+// is in seconds or an http date.
+func FromResponse(
+	resp *http.Response,
+) (time.Duration, bool) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		wait, _ := parseRetryAfter(resp.Header.Get(Header))
+
+		return wait, true
+	case http.StatusServiceUnavailable:
+		return parseRetryAfter(resp.Header.Get(Header))
+	default:
+		return 0, false
+	}
+}
+
+// parseRetryAfter - parses the retry delay header.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	secs, err := strconv.Atoi(value)
+	if err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(time.Until(date), 0), true
+}
+
+// FromError - returns the delay
+// of a RESOURCE_EXHAUSTED error.
+func FromError(err error) (time.Duration, bool) {
+	stat, ok := status.FromError(err)
+	if !ok || stat.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+
+	for _, detail := range stat.Details() {
+		info, ok := detail.(*errdetails.RetryInfo)
+		if ok {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	return 0, true
+}
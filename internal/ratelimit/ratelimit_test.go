@@ -0,0 +1,191 @@
+package ratelimit_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitrovia/collector-metrics/internal/functions/identity"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/ratelimitmid"
+	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow(t *testing.T) {
+	t.Parallel()
+
+	lim := ratelimit.New(
+		bizmodels.RateLimit{Rate: 1, Burst: 2})
+
+	for range 2 {
+		ok, _ := lim.Allow("a")
+		assert.True(t, ok)
+	}
+
+	ok, wait := lim.Allow("a")
+	assert.False(t, ok)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, time.Second)
+
+	ok, _ = lim.Allow("b")
+	assert.True(t, ok)
+
+	var unlimited *ratelimit.Limiter
+
+	ok, _ = unlimited.Allow("a")
+	assert.True(t, ok)
+	assert.Nil(t, ratelimit.New(bizmodels.RateLimit{}))
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	lim := ratelimit.New(
+		bizmodels.RateLimit{Rate: 50, Burst: 1})
+
+	assert.NoError(t, lim.Wait(context.Background(), "a"))
+	assert.NoError(t, lim.Wait(context.Background(), "a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	lim = ratelimit.New(
+		bizmodels.RateLimit{Rate: 0.1, Burst: 1})
+	assert.NoError(t, lim.Wait(ctx, "a"))
+	assert.Error(t, lim.Wait(ctx, "a"))
+}
+
+func TestGetRoute(t *testing.T) {
+	t.Parallel()
+
+	set := ratelimit.NewSet(&bizmodels.InitParams{
+		DefRateLimit: bizmodels.RateLimit{Rate: 1, Burst: 1},
+		RateLimits: map[string]bizmodels.RateLimit{
+			"/updates/": {Rate: -1},
+		},
+	})
+
+	assert.Nil(t, set.Get("/updates/"))
+	assert.NotNil(t, set.Get("/update/"))
+	assert.Same(t, set.Get("/update/"), set.Get("/update/"))
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	lim := ratelimit.New(
+		bizmodels.RateLimit{Rate: 0.5, Burst: 1})
+	next := http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	handler := ratelimitmid.RateLimitMiddleware(lim,
+		ratelimit.KeyRealIP)(next)
+
+	codes := []int{http.StatusOK, http.StatusTooManyRequests}
+	for _, code := range codes {
+		req := httptest.NewRequest(http.MethodPost,
+			"/updates/", nil)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		resp := rec.Result()
+		resp.Body.Close()
+
+		assert.Equal(t, code, resp.StatusCode)
+
+		wait, limited := ratelimit.FromResponse(resp)
+		assert.Equal(t, code != http.StatusOK, limited)
+
+		if limited {
+			assert.Equal(t, 2*time.Second, wait)
+		}
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t,
+		ratelimit.ValidateKey(ratelimit.KeyAgent))
+	assert.ErrorIs(t, ratelimit.ValidateKey("real_ip"),
+		ratelimit.ErrKey)
+
+	req := httptest.NewRequest(http.MethodPost,
+		"/updates/", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+
+	other := req.Clone(context.Background())
+	identity.SetHeaders(req.Header,
+		&bizmodels.AgentInfo{ID: "a1"})
+
+	assert.Equal(t,
+		ratelimit.RequestKey(req, ratelimit.KeyRealIP),
+		ratelimit.RequestKey(other, ratelimit.KeyRealIP))
+
+	// the agent id and the address it reports
+	// are not verified, the peer one is used.
+	assert.Equal(t,
+		ratelimit.RequestKey(req, ratelimit.KeyAgent),
+		ratelimit.RequestKey(other, ratelimit.KeyAgent))
+	assert.Equal(t, "192.0.2.1",
+		ratelimit.RequestKey(req, ratelimit.KeyAgent))
+
+	req.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{
+			Subject: pkix.Name{CommonName: "a1"},
+		}}},
+	}
+
+	assert.NotEqual(t,
+		ratelimit.RequestKey(req, ratelimit.KeyAgent),
+		ratelimit.RequestKey(other, ratelimit.KeyAgent))
+}
+
+func TestFromError(t *testing.T) {
+	t.Parallel()
+
+	wait, limited := ratelimit.FromError(
+		ratelimit.Exhausted(3 * time.Second))
+	assert.True(t, limited)
+	assert.Equal(t, 3*time.Second, wait)
+
+	_, limited = ratelimit.FromError(nil)
+	assert.False(t, limited)
+
+	assert.Equal(t, "1", ratelimit.Seconds(time.Millisecond))
+}
+
+func TestFromResponse(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{},
+	}
+
+	_, limited := ratelimit.FromResponse(resp)
+	assert.False(t, limited)
+
+	resp.Header.Set(ratelimit.Header,
+		time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+
+	wait, limited := ratelimit.FromResponse(resp)
+	assert.True(t, limited)
+	assert.Greater(t, wait, 59*time.Minute)
+
+	resp.StatusCode = http.StatusTooManyRequests
+	resp.Header.Set(ratelimit.Header, "x")
+
+	wait, limited = ratelimit.FromResponse(resp)
+	assert.True(t, limited)
+	assert.Equal(t, time.Duration(0), wait)
+}
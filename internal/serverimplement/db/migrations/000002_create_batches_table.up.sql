@@ -0,0 +1,6 @@
+CREATE TABLE batches (
+   id varchar primary key,
+   created_at TIMESTAMP default now()
+);
+
+CREATE INDEX batches_created_at_idx ON batches (created_at);
\ No newline at end of file
@@ -23,20 +23,29 @@ import (
 	"github.com/dmitrovia/collector-metrics/internal/functions/config"
 	"github.com/dmitrovia/collector-metrics/internal/functions/validate"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/defaulthandler"
+	"github.com/dmitrovia/collector-metrics/internal/handlers/eventshandler"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/getmetrichandler"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/getmetricjsonhandler"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/notallowedhandler"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/pinghandler"
+	"github.com/dmitrovia/collector-metrics/internal/handlers/pipelinestatshandler"
+	"github.com/dmitrovia/collector-metrics/internal/handlers/quotausagehandler"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/sender"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/setmetrichandler"
 	"github.com/dmitrovia/collector-metrics/internal/handlers/setmetricjsonhandler"
 	"github.com/dmitrovia/collector-metrics/internal/logger"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/bodylimitmid"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/checkipmid"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/decryptmid"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/gzipcompressmiddleware"
 	"github.com/dmitrovia/collector-metrics/internal/middleware/loggermiddleware"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/nodeadlinemid"
+	"github.com/dmitrovia/collector-metrics/internal/middleware/ratelimitmid"
 	"github.com/dmitrovia/collector-metrics/internal/migrator"
+	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/dmitrovia/collector-metrics/internal/ratelimit"
 	"github.com/dmitrovia/collector-metrics/internal/service"
 	"github.com/dmitrovia/collector-metrics/internal/storage/dbrepository"
 	"github.com/dmitrovia/collector-metrics/internal/storage/memoryrepository"
@@ -52,6 +61,12 @@ var buildVersion,
 
 const rTimeout = 60
 
+const defBatchWindow = time.Hour
+
+// sseHeartbeat - seconds between heartbeats
+// of the events stream.
+const sseHeartbeat = 15
+
 const wTimeout = 60
 
 const iTimeout = 60
@@ -68,6 +83,14 @@ const defSavingIntervalDisk = 0
 
 const defWaitSecRespDB = 10
 
+const defMaxBodySize int64 = 1 << 20
+
+const defMaxDecompressedSize int64 = 8 << 20
+
+const defBodyLimitsRoute = "default"
+
+const defRateLimitsRoute = "default"
+
 var errParseFlags = errors.New("addr is not valid")
 
 var errPath = errors.New("path is not valid")
@@ -95,6 +118,11 @@ func InitStorage(
 	DBStorage = &dbrepository.DBepository{}
 	memStorage = &memoryrepository.MemoryRepository{}
 
+	pipe, err := pipeline.New(&par.Pipeline)
+	if err != nil {
+		return nil, nil, fmt.Errorf("initStorage->New: %w", err)
+	}
+
 	if par.DatabaseDSN != "" {
 		datas := service.NewMemoryService(DBStorage,
 			par.WaitSecRespDB)
@@ -105,6 +133,9 @@ func InitStorage(
 		}
 
 		DBStorage.Initiate(par.DatabaseDSN, dbConn)
+		datas.SetBatchWindow(par.BatchWindow)
+		datas.SetPipeline(pipe)
+		datas.SetQuotaLimits(par.Quotas)
 
 		return dbConn, datas, nil
 	}
@@ -113,6 +144,9 @@ func InitStorage(
 		par.WaitSecRespDB)
 
 	memStorage.Init()
+	datas.SetBatchWindow(par.BatchWindow)
+	datas.SetPipeline(pipe)
+	datas.SetQuotaLimits(par.Quotas)
 
 	return nil, datas, nil
 }
@@ -227,6 +261,27 @@ func Initiate(
 	}
 
 	setInitParamsDB(par)
+
+	err = setInitParamsBatch(par)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setInitParamsBodyLimits(par)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setInitParamsQuotas(par)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setInitParamsRateLimits(par)
+	if err != nil {
+		return nil, err
+	}
+
 	par.ValidateAddrPattern = "^[a-zA-Z/ ]{1,100}:[0-9]{1,10}$"
 
 	err = setInitParams(par)
@@ -266,8 +321,37 @@ func initiateFlags(par *bizmodels.InitParams) error {
 		"address in CIDR format.")
 	flag.StringVar(&par.GRPCPort, "grpcp", "50051",
 		"grpc Port")
+	flag.StringVar(&par.GRPCCertFile, "grpc-cert", "",
+		"grpc server TLS certificate.")
+	flag.StringVar(&par.GRPCKeyFile, "grpc-key", "",
+		"grpc server TLS certificate key.")
+	flag.StringVar(&par.GRPCClientCAFile, "grpc-client-ca", "",
+		"CA verifying the grpc client certificates.")
 	flag.BoolVar(&par.Restore,
 		"r", true, "Loading metrics at server startup.")
+	flag.DurationVar(&par.BatchWindow,
+		"batch-window", defBatchWindow,
+		"how long applied batch ids are remembered, 0 - off.")
+	flag.IntVar(&par.Quotas.MaxSeries, "max-series", 0,
+		"maximum number of series, negative - no limit.")
+	flag.IntVar(&par.Quotas.MaxNewSeries, "max-new-series", 0,
+		"maximum new series per source and minute.")
+	flag.IntVar(&par.Quotas.MaxBatch, "max-batch", 0,
+		"maximum metrics in a batch, negative - no limit.")
+	flag.Float64Var(&par.DefRateLimit.Rate, "rate", 0,
+		"requests per second of a client, negative - no limit.")
+	flag.IntVar(&par.DefRateLimit.Burst, "rate-burst", 0,
+		"requests of a client allowed at once.")
+	flag.StringVar(&par.RateKey, "rate-key", "",
+		"rate limit and quota key: real-ip (behind"+
+			" a trusted proxy only), ip or agent"+
+			" (client certificate).")
+	flag.Int64Var(&par.DefBodyLimits.MaxCompressed,
+		"max-body", 0,
+		"maximum request body size, negative - no limit.")
+	flag.Int64Var(&par.DefBodyLimits.MaxDecompressed,
+		"max-body-decompressed", 0,
+		"maximum decompressed body size, negative - no limit.")
 	flag.Parse()
 
 	return nil
@@ -292,7 +376,10 @@ func InitiateServer(
 	AttachProfiler(mux)
 
 	initPostMethods(mux, mser, zapLogger, par)
-	initGetMethods(mux, mser, zapLogger, par)
+	hEvents := eventshandler.NewEventsHandler(mser,
+		sseHeartbeat*time.Second)
+
+	initGetMethods(mux, mser, zapLogger, par, hEvents)
 
 	*server = http.Server{
 		Addr:         par.PORT,
@@ -303,6 +390,8 @@ func InitiateServer(
 		IdleTimeout:  iTimeout * time.Second,
 	}
 
+	server.RegisterOnShutdown(hEvents.Close)
+
 	if par.Restore {
 		err := mser.LoadFromFile(par.FileStoragePath)
 		if err != nil {
@@ -326,10 +415,13 @@ func initGetMethods(
 	dse *service.DS,
 	zapLogger *zap.Logger,
 	par *bizmodels.InitParams,
+	hEvents *eventshandler.EventsHandler,
 ) {
 	hPing := pinghandler.NewPingHandler(dse, par)
 	hGet := getmetrichandler.NewGetMetricHandler(dse)
 	hDefault := defaulthandler.NewDefaultHandler(dse)
+	hStats := pipelinestatshandler.NewPipelineStatsHandler(dse)
+	hUsage := quotausagehandler.NewQuotaUsageHandler(dse)
 	hNotAllowed := notallowedhandler.NotAllowedHandler{}
 
 	// mux.PathPrefix("/debug/").Handler(http.DefaultServeMux)
@@ -342,14 +434,37 @@ func initGetMethods(
 
 	getPingBDMux := mux.Methods(http.MethodGet).Subrouter()
 	getPingBDMux.HandleFunc("/ping", hPing.PingHandler)
-	getPingBDMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	getPingBDMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(par.DefBodyLimits),
+		loggermiddleware.RequestLogger(zapLogger))
+
+	getEventsMux := mux.Methods(http.MethodGet).Subrouter()
+	getEventsMux.HandleFunc("/events", hEvents.EventsHandler)
+	getEventsMux.Use(
+		nodeadlinemid.NoDeadlineMiddleware,
+		gzipcompressmiddleware.GzipMiddleware(par.DefBodyLimits),
+		loggermiddleware.RequestLogger(zapLogger))
+
+	getStatsMux := mux.Methods(http.MethodGet).Subrouter()
+	getStatsMux.HandleFunc("/pipeline/stats",
+		hStats.PipelineStatsHandler)
+	getStatsMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(par.DefBodyLimits),
+		loggermiddleware.RequestLogger(zapLogger))
+
+	getUsageMux := mux.Methods(http.MethodGet).Subrouter()
+	getUsageMux.HandleFunc("/quota/usage",
+		hUsage.QuotaUsageHandler)
+	getUsageMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(par.DefBodyLimits),
 		loggermiddleware.RequestLogger(zapLogger))
 
 	mux.MethodNotAllowedHandler = hNotAllowed
 
 	defaultMux := mux.Methods(http.MethodGet).Subrouter()
 	defaultMux.HandleFunc("/", hDefault.DefaultHandler)
-	defaultMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	defaultMux.Use(
+		gzipcompressmiddleware.GzipMiddleware(par.DefBodyLimits),
 		loggermiddleware.RequestLogger(zapLogger))
 }
 
@@ -360,40 +475,57 @@ func initPostMethods(
 	zapLogger *zap.Logger,
 	par *bizmodels.InitParams,
 ) {
-	hSet := setmetrichandler.NewSetMetricHandler(dse)
-	hJSONSet := setmetricjsonhandler.NewSetMJH(dse)
+	hSet := setmetrichandler.NewSetMetricHandler(dse, par)
+	hJSONSet := setmetricjsonhandler.NewSetMJH(dse, par)
 	hJSONSets := sender.NewSenderHandler(
 		dse, par)
 	hJSONGet := getmetricjsonhandler.NewGetMJSONHandler(dse)
+	limiters := ratelimit.NewSet(par)
 
 	setMMux := mux.Methods(http.MethodPost).Subrouter()
 	setMMux.HandleFunc(
 		"/update/{metric_type}/{metric_name}/{metric_value}",
 		hSet.SetMetricHandler)
-	setMMux.Use(loggermiddleware.RequestLogger(zapLogger))
+	setMMux.Use(
+		ratelimitmid.RateLimitMiddleware(
+			limiters.Get("/update/"), par.RateKey),
+		bodylimitmid.BodyLimitMiddleware(par.DefBodyLimits),
+		loggermiddleware.RequestLogger(zapLogger))
 
+	valueLimits := par.GetBodyLimits("/value/")
 	getMJSONMux := mux.Methods(http.MethodPost).Subrouter()
 	getMJSONMux.HandleFunc(
 		"/value/",
 		hJSONGet.GetMetricJSONHandler)
-	getMJSONMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	getMJSONMux.Use(
+		bodylimitmid.BodyLimitMiddleware(valueLimits),
+		gzipcompressmiddleware.GzipMiddleware(valueLimits),
 		loggermiddleware.RequestLogger(zapLogger))
 
+	updateLimits := par.GetBodyLimits("/update/")
 	setMJSONMux := mux.Methods(http.MethodPost).Subrouter()
 	setMJSONMux.HandleFunc(
 		"/update/",
 		hJSONSet.SetMJSONHandler)
-	setMJSONMux.Use(gzipcompressmiddleware.GzipMiddleware(),
+	setMJSONMux.Use(
+		ratelimitmid.RateLimitMiddleware(
+			limiters.Get("/update/"), par.RateKey),
+		bodylimitmid.BodyLimitMiddleware(updateLimits),
+		gzipcompressmiddleware.GzipMiddleware(updateLimits),
 		loggermiddleware.RequestLogger(zapLogger))
 
+	updatesLimits := par.GetBodyLimits("/updates/")
 	setMsJSONMux := mux.Methods(http.MethodPost).Subrouter()
 	setMsJSONMux.HandleFunc(
 		"/updates/",
 		hJSONSets.SenderHandler)
 	setMsJSONMux.Use(
+		ratelimitmid.RateLimitMiddleware(
+			limiters.Get("/updates/"), par.RateKey),
+		bodylimitmid.BodyLimitMiddleware(updatesLimits),
 		checkipmid.CheckIPMiddleware(*par),
 		decryptmid.DecryptMiddleware(*par),
-		gzipcompressmiddleware.GzipMiddleware(),
+		gzipcompressmiddleware.GzipMiddleware(updatesLimits),
 		loggermiddleware.RequestLogger(zapLogger))
 }
 
@@ -408,6 +540,128 @@ func setInitParamsDB(params *bizmodels.InitParams) {
 	}
 }
 
+// setInitParamsBatch - gets environment variables.
+func setInitParamsBatch(
+	params *bizmodels.InitParams,
+) error {
+	envBatchWindow := os.Getenv("BATCH_WINDOW")
+
+	if envBatchWindow != "" {
+		value, err := time.ParseDuration(envBatchWindow)
+		if err != nil {
+			return fmt.Errorf("setInitParamsB->Parse: %w", err)
+		}
+
+		params.BatchWindow = value
+	}
+
+	return nil
+}
+
+// setInitParamsQuotas - gets environment variables.
+func setInitParamsQuotas(
+	params *bizmodels.InitParams,
+) error {
+	for env, dst := range map[string]*int{
+		"MAX_SERIES":     &params.Quotas.MaxSeries,
+		"MAX_NEW_SERIES": &params.Quotas.MaxNewSeries,
+		"MAX_BATCH_SIZE": &params.Quotas.MaxBatch,
+	} {
+		envValue := os.Getenv(env)
+		if envValue == "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(envValue)
+		if err != nil {
+			return fmt.Errorf("setInitParamsQ->Atoi: %w", err)
+		}
+
+		*dst = value
+	}
+
+	return nil
+}
+
+// setInitParamsRateLimits - gets environment variables.
+func setInitParamsRateLimits(
+	params *bizmodels.InitParams,
+) error {
+	envRate := os.Getenv("INGEST_RATE_LIMIT")
+	envBurst := os.Getenv("RATE_BURST")
+	envKey := os.Getenv("RATE_KEY")
+
+	if envRate != "" {
+		value, err := strconv.ParseFloat(envRate, 64)
+		if err != nil {
+			return fmt.Errorf("setInitParamsRL->Parse: %w", err)
+		}
+
+		params.DefRateLimit.Rate = value
+	}
+
+	if envBurst != "" {
+		value, err := strconv.Atoi(envBurst)
+		if err != nil {
+			return fmt.Errorf("setInitParamsRL->Atoi: %w", err)
+		}
+
+		params.DefRateLimit.Burst = value
+	}
+
+	if envKey != "" {
+		params.RateKey = envKey
+	}
+
+	if params.RateKey == "" {
+		params.RateKey = ratelimit.KeyRealIP
+	}
+
+	err := ratelimit.ValidateKey(params.RateKey)
+	if err != nil {
+		return fmt.Errorf("setInitParamsRL->ValidateKey: %w", err)
+	}
+
+	return nil
+}
+
+// setInitParamsBodyLimits - gets environment variables.
+func setInitParamsBodyLimits(
+	params *bizmodels.InitParams,
+) error {
+	envMaxBody := os.Getenv("MAX_BODY_SIZE")
+	envMaxDecomp := os.Getenv("MAX_BODY_DECOMPRESSED_SIZE")
+
+	if envMaxBody != "" {
+		value, err := strconv.ParseInt(envMaxBody, 10, 64)
+		if err != nil {
+			return fmt.Errorf("setInitParamsBL->Parse: %w", err)
+		}
+
+		params.DefBodyLimits.MaxCompressed = value
+	}
+
+	if envMaxDecomp != "" {
+		value, err := strconv.ParseInt(envMaxDecomp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("setInitParamsBL->Parse: %w", err)
+		}
+
+		params.DefBodyLimits.MaxDecompressed = value
+	}
+
+	if params.DefBodyLimits.MaxCompressed == 0 {
+		params.DefBodyLimits.MaxCompressed = defMaxBodySize
+	}
+
+	if params.DefBodyLimits.MaxDecompressed == 0 {
+		params.DefBodyLimits.
+			MaxDecompressed = defMaxDecompressedSize
+	}
+
+	return nil
+}
+
 // setInitParamsFileStorage - gets environment variables.
 func setInitParamsFileStorage(
 	params *bizmodels.InitParams,
@@ -441,6 +695,9 @@ func setInitParams(params *bizmodels.InitParams) error {
 	cryptoKey := os.Getenv("CRYPTO_KEY_SERVER")
 	cfgServer := os.Getenv("CONFIG_SERVER")
 	trustedSubnet := os.Getenv("TRUSTED_SUBNET")
+	grpcCert := os.Getenv("GRPC_CERT")
+	grpcKey := os.Getenv("GRPC_KEY")
+	grpcClientCA := os.Getenv("GRPC_CLIENT_CA")
 
 	_, path, _, isok := runtime.Caller(0)
 	Root := filepath.Join(filepath.Dir(path), "../..")
@@ -465,6 +722,18 @@ func setInitParams(params *bizmodels.InitParams) error {
 		params.TrustedSubnet = trustedSubnet
 	}
 
+	if grpcCert != "" {
+		params.GRPCCertFile = grpcCert
+	}
+
+	if grpcKey != "" {
+		params.GRPCKeyFile = grpcKey
+	}
+
+	if grpcClientCA != "" {
+		params.GRPCClientCAFile = grpcClientCA
+	}
+
 	if envSI != "" {
 		value, err := strconv.Atoi(envSI)
 		if err != nil {
@@ -536,9 +805,124 @@ func GetParamsFromCFG(
 		par.StoreInterval = cfg.StoreInterval
 	}
 
+	setBodyLimitsFromCFG(par, cfg)
+	setPipelineFromCFG(par, cfg)
+	setQuotasFromCFG(par, cfg)
+	setRateLimitsFromCFG(par, cfg)
+
 	return nil
 }
 
+// setRateLimitsFromCFG - sets per route rate limits,
+// the "default" entry is used when no flag is passed.
+func setRateLimitsFromCFG(
+	par *bizmodels.InitParams,
+	cfg *apimodels.CfgServer,
+) {
+	if par.RateKey == "" {
+		par.RateKey = cfg.RateKey
+	}
+
+	par.RateLimits = make(map[string]bizmodels.RateLimit,
+		len(cfg.RateLimits))
+
+	for route, lim := range cfg.RateLimits {
+		limit := bizmodels.RateLimit{
+			Rate:  lim.Rate,
+			Burst: lim.Burst,
+		}
+
+		if route != defRateLimitsRoute {
+			par.RateLimits[route] = limit
+
+			continue
+		}
+
+		if par.DefRateLimit.Rate == 0 {
+			par.DefRateLimit.Rate = limit.Rate
+		}
+
+		if par.DefRateLimit.Burst == 0 {
+			par.DefRateLimit.Burst = limit.Burst
+		}
+	}
+}
+
+// setQuotasFromCFG - sets the ingestion
+// limits not passed as flags.
+func setQuotasFromCFG(
+	par *bizmodels.InitParams,
+	cfg *apimodels.CfgServer,
+) {
+	if par.Quotas.MaxSeries == 0 {
+		par.Quotas.MaxSeries = cfg.Quotas.MaxSeries
+	}
+
+	if par.Quotas.MaxNewSeries == 0 {
+		par.Quotas.MaxNewSeries = cfg.Quotas.MaxNewSeries
+	}
+
+	if par.Quotas.MaxBatch == 0 {
+		par.Quotas.MaxBatch = cfg.Quotas.MaxBatch
+	}
+}
+
+// setPipelineFromCFG - sets the rules
+// applied to the received metrics.
+func setPipelineFromCFG(
+	par *bizmodels.InitParams,
+	cfg *apimodels.CfgServer,
+) {
+	par.Pipeline = bizmodels.PipelineConfig{
+		IDPattern:          cfg.Pipeline.IDPattern,
+		Prefix:             cfg.Pipeline.Prefix,
+		AgentPolicy:        cfg.Pipeline.AgentPolicy,
+		Allow:              cfg.Pipeline.Allow,
+		Deny:               cfg.Pipeline.Deny,
+		DropNegativeDeltas: cfg.Pipeline.DropNegative,
+	}
+
+	for _, rule := range cfg.Pipeline.Rename {
+		par.Pipeline.Rename = append(par.Pipeline.Rename,
+			bizmodels.RenameRule{
+				Match:   rule.Match,
+				Replace: rule.Replace,
+			})
+	}
+}
+
+// setBodyLimitsFromCFG - sets per route body limits,
+// the "default" entry is used when no flag is passed.
+func setBodyLimitsFromCFG(
+	par *bizmodels.InitParams,
+	cfg *apimodels.CfgServer,
+) {
+	par.BodyLimits = make(map[string]bizmodels.BodyLimits,
+		len(cfg.BodyLimits))
+
+	for route, lim := range cfg.BodyLimits {
+		limits := bizmodels.BodyLimits{
+			MaxCompressed:   lim.MaxCompressed,
+			MaxDecompressed: lim.MaxDecompressed,
+		}
+
+		if route != defBodyLimitsRoute {
+			par.BodyLimits[route] = limits
+
+			continue
+		}
+
+		if par.DefBodyLimits.MaxCompressed == 0 {
+			par.DefBodyLimits.MaxCompressed = limits.MaxCompressed
+		}
+
+		if par.DefBodyLimits.MaxDecompressed == 0 {
+			par.DefBodyLimits.
+				MaxDecompressed = limits.MaxDecompressed
+		}
+	}
+}
+
 func ServerProcess() error {
 	var (
 		dataService *service.DS
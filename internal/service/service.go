@@ -10,13 +10,24 @@ import (
 	"os"
 	"time"
 
+	"github.com/dmitrovia/collector-metrics/internal/eventbus"
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/pipeline"
+	"github.com/dmitrovia/collector-metrics/internal/quota"
 	"github.com/dmitrovia/collector-metrics/internal/storage"
 )
 
 const fmd os.FileMode = 0o666
 
+// defEventHistory - events kept for
+// resuming subscriptions.
+const defEventHistory = 1024
+
+// defBatchWindow - how long applied
+// batch ids are remembered.
+const defBatchWindow = time.Hour
+
 // Service - for working with metrics.
 type Service interface {
 	AddGauge(mname string, mvalue float64) error
@@ -34,12 +45,166 @@ type Service interface {
 	GetAllGauges() (map[string]bizmodels.Gauge, error)
 	GetAllCounters() (map[string]bizmodels.Counter, error)
 	GetAllMetricsAPI() (*apimodels.ArrMetrics, error)
+	Subscribe(filter *eventbus.Filter) *eventbus.Subscription
+	SubscribeAfter(
+		filter *eventbus.Filter,
+		after uint64,
+	) (*eventbus.Subscription, []eventbus.Event, error)
+	AddBatch(id string,
+		gms map[string]bizmodels.Gauge,
+		cms map[string]bizmodels.Counter) (bool, error)
+	Pipeline() *pipeline.Pipeline
+	Quota() *quota.Quota
 }
 
 // DS - describing the service.
+// Every successful change of a metric
+// is published to the event bus.
 type DS struct {
 	repository  storage.Repository
+	bus         *eventbus.Bus
+	pipe        *pipeline.Pipeline
+	quota       *quota.Quota
 	ctxDuration time.Duration
+	batchWindow time.Duration
+}
+
+// SetBatchWindow - sets how long applied
+// batch ids are remembered.
+func (s *DS) SetBatchWindow(window time.Duration) {
+	s.batchWindow = window
+}
+
+// SetPipeline - sets the rules applied
+// to the received metrics.
+func (s *DS) SetPipeline(pipe *pipeline.Pipeline) {
+	s.pipe = pipe
+}
+
+// Pipeline - returns the rules applied
+// to the received metrics.
+func (s *DS) Pipeline() *pipeline.Pipeline {
+	return s.pipe
+}
+
+// SetQuotaLimits - sets the limits
+// of the series created by the agents.
+func (s *DS) SetQuotaLimits(limits bizmodels.QuotaLimits) {
+	s.quota = quota.New(limits, s.seriesKeys)
+}
+
+// Quota - returns the limits of the
+// series created by the agents.
+func (s *DS) Quota() *quota.Quota {
+	return s.quota
+}
+
+// seriesKeys - returns the quota
+// keys of the stored metrics.
+func (s *DS) seriesKeys() ([]string, error) {
+	gauges, err := s.GetAllGauges()
+	if err != nil {
+		return nil, fmt.Errorf("seriesKeys->GetAllG: %w", err)
+	}
+
+	counters, err := s.GetAllCounters()
+	if err != nil {
+		return nil, fmt.Errorf("seriesKeys->GetAllC: %w", err)
+	}
+
+	keys := make([]string, 0, len(gauges)+len(counters))
+
+	for name := range gauges {
+		keys = append(keys, quota.Key(bizmodels.GaugeName, name))
+	}
+
+	for name := range counters {
+		keys = append(keys,
+			quota.Key(bizmodels.CounterName, name))
+	}
+
+	return keys, nil
+}
+
+// AddBatch - adds the metrics of the batch
+// once per batch id within the window. The
+// batch is marked in the same transaction as
+// its metrics, so a failed batch is applied
+// on retry and a replayed one is acknowledged
+// without being applied again. An empty id
+// is always applied.
+func (s *DS) AddBatch(
+	id string,
+	gms map[string]bizmodels.Gauge,
+	cms map[string]bizmodels.Counter,
+) (bool, error) {
+	if id == "" || s.batchWindow <= 0 {
+		err := s.AddMetrics(gms, cms)
+		if err != nil {
+			return false, fmt.Errorf("AddBatch->AddMetrics: %w", err)
+		}
+
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		s.ctxDuration)
+	defer cancel()
+
+	totals, isNew, err := s.repository.AddBatch(&ctx, id,
+		s.batchWindow, gms, cms)
+	if err != nil {
+		return false, fmt.Errorf("AddBatch->AddBatch: %w", err)
+	}
+
+	if !isNew {
+		return false, nil
+	}
+
+	s.publishMetrics(gms, totals)
+
+	return true, nil
+}
+
+// Subscribe - starts receiving metric changes.
+func (s *DS) Subscribe(
+	filter *eventbus.Filter,
+) *eventbus.Subscription {
+	return s.bus.Subscribe(filter)
+}
+
+// SubscribeAfter - resumes receiving metric
+// changes after the event sequence.
+func (s *DS) SubscribeAfter(
+	filter *eventbus.Filter,
+	after uint64,
+) (*eventbus.Subscription, []eventbus.Event, error) {
+	sub, missed, err := s.bus.SubscribeAfter(filter, after)
+	if err != nil {
+		return nil, nil,
+			fmt.Errorf("SubscribeAfter->SubscribeAfter: %w", err)
+	}
+
+	return sub, missed, nil
+}
+
+// publishGauge - notifies about the gauge value.
+func (s *DS) publishGauge(name string, value float64) {
+	s.bus.Publish(&apimodels.Metrics{
+		ID:    name,
+		MType: bizmodels.GaugeName,
+		Value: &value,
+	})
+}
+
+// publishCounter - notifies about the counter total.
+func (s *DS) publishCounter(name string, total int64) {
+	s.bus.Publish(&apimodels.Metrics{
+		ID:    name,
+		MType: bizmodels.CounterName,
+		Delta: &total,
+	})
 }
 
 // GetAllMetricsAPI - get all metrics in API format.
@@ -103,14 +268,34 @@ func (s *DS) AddMetrics(
 		s.ctxDuration)
 	defer cancel()
 
-	err := s.repository.AddMetrics(&ctx, gms, cms)
+	totals, err := s.repository.AddMetrics(&ctx, gms, cms)
 	if err != nil {
 		return fmt.Errorf("DataService->AddMetrics: %w", err)
 	}
 
+	s.publishMetrics(gms, totals)
+
 	return nil
 }
 
+// publishMetrics - publishes the added
+// metrics, the counter totals are read
+// by the repository when adding them.
+// Events are kept in the history even
+// when nobody is subscribed.
+func (s *DS) publishMetrics(
+	gms map[string]bizmodels.Gauge,
+	totals map[string]bizmodels.Counter,
+) {
+	for _, gauge := range gms {
+		s.publishGauge(gauge.Name, gauge.Value)
+	}
+
+	for _, total := range totals {
+		s.publishCounter(total.Name, total.Value)
+	}
+}
+
 // SaveInFile - saves metrics to a file.
 func (s *DS) SaveInFile(pth string) error {
 	ctx, cancel := context.WithTimeout(
@@ -273,6 +458,8 @@ func (s *DS) AddGauge(mname string, mvalue float64) error {
 		return fmt.Errorf("AddGauge->AddGauge: %w", err)
 	}
 
+	s.publishGauge(mname, mvalue)
+
 	return nil
 }
 
@@ -294,6 +481,8 @@ func (s *DS) AddCounter(
 		return nil, fmt.Errorf("AddCounter->AddCounter: %w", err)
 	}
 
+	s.publishCounter(res.Name, res.Value)
+
 	return res, nil
 }
 
@@ -332,5 +521,15 @@ func (s *DS) GetValueCM(mname string) (int64, error) {
 func NewMemoryService(repository storage.Repository,
 	ctxDur time.Duration,
 ) *DS {
-	return &DS{repository: repository, ctxDuration: ctxDur}
+	dse := &DS{
+		repository:  repository,
+		bus:         eventbus.NewBus(defEventHistory),
+		pipe:        pipeline.Default(),
+		ctxDuration: ctxDur,
+		batchWindow: defBatchWindow,
+	}
+
+	dse.SetQuotaLimits(bizmodels.QuotaLimits{})
+
+	return dse
 }
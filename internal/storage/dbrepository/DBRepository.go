@@ -4,14 +4,28 @@ package dbrepository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// querier - runs queries on the pool
+// or inside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string,
+		args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string,
+		args ...any) pgx.Row
+}
+
 // DBepository - describing the storage.
 type DBepository struct {
 	conn        *pgxpool.Pool
@@ -35,27 +49,132 @@ func (m *DBepository) Initiate(
 func (m *DBepository) Init() {
 }
 
-// AddMetrics - adds metrics to the database.
+// AddBatch - marks the batch as applied and
+// adds its metrics in one transaction, false
+// without adding when the batch was already
+// marked within the window. Batches older
+// than the window are deleted first. Returns
+// the totals of the added counters.
+func (m *DBepository) AddBatch(
+	ctx *context.Context,
+	id string,
+	window time.Duration,
+	gauges map[string]bizmodels.Gauge,
+	counters map[string]bizmodels.Counter,
+) (map[string]bizmodels.Counter, bool, error) {
+	m.mutexG.Lock()
+	defer m.mutexG.Unlock()
+
+	m.mutexC.Lock()
+	defer m.mutexC.Unlock()
+
+	trx, err := m.conn.Begin(*ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("AddBatch->Begin: %w", err)
+	}
+
+	defer func() {
+		_ = trx.Rollback(*ctx)
+	}()
+
+	_, err = trx.Exec(*ctx,
+		"DELETE FROM batches WHERE created_at < "+
+			"now() - make_interval(secs => $1)",
+		window.Seconds())
+	if err != nil {
+		return nil, false, fmt.Errorf("AddBatch->DELETE: %w", err)
+	}
+
+	rows, err := trx.Exec(*ctx,
+		"INSERT INTO batches (id) VALUES ($1) "+
+			"ON CONFLICT (id) DO NOTHING",
+		id)
+	if err != nil {
+		return nil, false, fmt.Errorf("AddBatch->INSERT: %w", err)
+	}
+
+	if rows.RowsAffected() == 0 {
+		return nil, false, nil
+	}
+
+	totals, err := addMetrics(ctx, trx, gauges, counters)
+	if err != nil {
+		return nil, false,
+			fmt.Errorf("AddBatch->addMetrics: %w", err)
+	}
+
+	err = trx.Commit(*ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("AddBatch->Commit: %w", err)
+	}
+
+	return totals, true, nil
+}
+
+// AddMetrics - adds metrics to the database
+// in one transaction, returns the totals
+// of the added counters.
 func (m *DBepository) AddMetrics(
 	ctx *context.Context,
 	gauges map[string]bizmodels.Gauge,
 	counters map[string]bizmodels.Counter,
-) error {
+) (map[string]bizmodels.Counter, error) {
+	m.mutexG.Lock()
+	defer m.mutexG.Unlock()
+
+	m.mutexC.Lock()
+	defer m.mutexC.Unlock()
+
+	trx, err := m.conn.Begin(*ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AddMetrics->Begin: %w", err)
+	}
+
+	defer func() {
+		_ = trx.Rollback(*ctx)
+	}()
+
+	totals, err := addMetrics(ctx, trx, gauges, counters)
+	if err != nil {
+		return nil, fmt.Errorf("AddMetrics->addMetrics: %w", err)
+	}
+
+	err = trx.Commit(*ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AddMetrics->Commit: %w", err)
+	}
+
+	return totals, nil
+}
+
+// addMetrics - adds the metrics, the counter
+// totals are read in the same transaction.
+func addMetrics(
+	ctx *context.Context,
+	qur querier,
+	gauges map[string]bizmodels.Gauge,
+	counters map[string]bizmodels.Counter,
+) (map[string]bizmodels.Counter, error) {
 	for _, gauge := range gauges {
-		err := m.AddGauge(ctx, &gauge)
+		err := addGauge(ctx, qur, &gauge)
 		if err != nil {
-			return fmt.Errorf("AddMetrics->m.AddGauge: %w", err)
+			return nil, fmt.Errorf("addMetrics->addGauge: %w", err)
 		}
 	}
 
+	totals := make(map[string]bizmodels.Counter, len(counters))
+
 	for _, counter := range counters {
-		_, err := m.AddCounter(ctx, &counter, false)
+		total, err := addCounter(ctx, qur, &counter, false)
 		if err != nil {
-			return fmt.Errorf("AddMetrics->m.AddCounter: %w", err)
+			return nil,
+				fmt.Errorf("addMetrics->addCounter: %w", err)
 		}
+
+		totals[total.Name] = *total
 	}
 
-	return nil
+	return totals, nil
 }
 
 // GetAllMetricsAPI - get all metrics in API format.
@@ -251,6 +370,11 @@ func (m *DBepository) GetGaugeMetric(
 		*ctx,
 		"select name, value from gauges where name=$1",
 		name).Scan(&nameMetric, &value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GetGaugeMetric: %w",
+			storage.ErrNotFound)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("GetGaugeMetric->QR: %w", err)
 	}
@@ -266,6 +390,15 @@ func (m *DBepository) GetGaugeMetric(
 func (m *DBepository) GetCounterMetric(
 	ctx *context.Context,
 	name string,
+) (*bizmodels.Counter, error) {
+	return getCounter(ctx, m.conn, name)
+}
+
+// getCounter - get counter metric by name.
+func getCounter(
+	ctx *context.Context,
+	qur querier,
+	name string,
 ) (*bizmodels.Counter, error) {
 	var temp *bizmodels.Counter
 
@@ -275,10 +408,15 @@ func (m *DBepository) GetCounterMetric(
 
 	temp = &bizmodels.Counter{}
 
-	err := m.conn.QueryRow(
+	err := qur.QueryRow(
 		*ctx,
 		"select name, value from counters where name=$1",
 		name).Scan(&nameMetric, &value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("getCounter: %w",
+			storage.ErrNotFound)
+	}
+
 	if err != nil {
 		return nil,
 			fmt.Errorf("GetGaugeMetric->m.conn.QueryRow: %w", err)
@@ -298,8 +436,17 @@ func (m *DBepository) AddGauge(
 	m.mutexG.Lock()
 	defer m.mutexG.Unlock()
 
+	return addGauge(ctx, m.conn, gauge)
+}
+
+// addGauge - add the gauge metric.
+func addGauge(
+	ctx *context.Context,
+	qur querier,
+	gauge *bizmodels.Gauge,
+) error {
 	// comment - a transaction is needed here
-	rows, err := m.conn.Exec(
+	rows, err := qur.Exec(
 		*ctx,
 		"UPDATE gauges SET value = $1 where name=$2",
 		gauge.Value,
@@ -309,7 +456,7 @@ func (m *DBepository) AddGauge(
 	}
 
 	if rows.RowsAffected() == 0 {
-		_, err := m.conn.Exec(
+		_, err := qur.Exec(
 			*ctx,
 			"INSERT INTO gauges (name, value) VALUES ($1, $2)",
 			gauge.Name,
@@ -331,6 +478,16 @@ func (m *DBepository) AddCounter(
 	m.mutexC.Lock()
 	defer m.mutexC.Unlock()
 
+	return addCounter(ctx, m.conn, counter, isNew)
+}
+
+// addCounter - add the counter metric.
+func addCounter(
+	ctx *context.Context,
+	qur querier,
+	counter *bizmodels.Counter,
+	isNew bool,
+) (*bizmodels.Counter, error) {
 	var tmp string
 
 	if isNew {
@@ -340,7 +497,7 @@ func (m *DBepository) AddCounter(
 			"+ $1 where name=$2"
 	}
 
-	rows, err := m.conn.Exec(*ctx,
+	rows, err := qur.Exec(*ctx,
 		tmp,
 		counter.Value,
 		counter.Name)
@@ -349,7 +506,7 @@ func (m *DBepository) AddCounter(
 	}
 
 	if rows.RowsAffected() == 0 {
-		_, err = m.conn.Exec(
+		_, err = qur.Exec(
 			*ctx,
 			"INSERT INTO counters (name, value) VALUES ($1, $2)",
 			counter.Name,
@@ -361,7 +518,7 @@ func (m *DBepository) AddCounter(
 		return counter, nil
 	}
 
-	temp, err := m.GetCounterMetric(ctx, counter.Name)
+	temp, err := getCounter(ctx, qur, counter.Name)
 	if err != nil {
 		return nil, fmt.Errorf("AddCounter->m.GetCM %w", err)
 	}
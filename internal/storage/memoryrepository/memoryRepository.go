@@ -4,54 +4,134 @@ package memoryrepository
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/dmitrovia/collector-metrics/internal/storage"
 )
 
-var errGetValueMetric = errors.New(
-	"value by name not found")
-
 // MemoryRepository - describing the storage.
+// Batch ids are kept with the time they were
+// marked and swept once the window has passed.
 type MemoryRepository struct {
 	gauges   map[string]bizmodels.Gauge
 	counters map[string]bizmodels.Counter
+	batches  map[string]time.Time
 	mutexG   *sync.Mutex
 	mutexC   *sync.Mutex
+	mutexB   *sync.Mutex
+	swept    time.Time
 }
 
-// AddMetrics - adds metrics to the memory.
+// AddMetrics - adds metrics to the memory,
+// returns the totals of the added counters.
 func (m *MemoryRepository) AddMetrics(
 	ctx *context.Context,
 	gauges map[string]bizmodels.Gauge,
 	counters map[string]bizmodels.Counter,
-) error {
+) (map[string]bizmodels.Counter, error) {
 	for _, gauge := range gauges {
 		err := m.AddGauge(ctx, &gauge)
 		if err != nil {
-			return fmt.Errorf("AddMetrics->m.AddGauge: %w", err)
+			return nil,
+				fmt.Errorf("AddMetrics->m.AddGauge: %w", err)
 		}
 	}
 
+	totals := make(map[string]bizmodels.Counter, len(counters))
+
 	for _, counter := range counters {
-		_, err := m.AddCounter(ctx, &counter, false)
+		total, err := m.AddCounter(ctx, &counter, false)
 		if err != nil {
-			return fmt.Errorf("AddMetrics->m.AddCounter: %w", err)
+			return nil,
+				fmt.Errorf("AddMetrics->m.AddCounter: %w", err)
 		}
+
+		totals[total.Name] = *total
 	}
 
-	return nil
+	return totals, nil
 }
 
 // Init - initialization of initial parameters.
 func (m *MemoryRepository) Init() {
 	m.gauges = make(map[string]bizmodels.Gauge)
 	m.counters = make(map[string]bizmodels.Counter)
+	m.batches = make(map[string]time.Time)
 	m.mutexG = &sync.Mutex{}
 	m.mutexC = &sync.Mutex{}
+	m.mutexB = &sync.Mutex{}
+}
+
+// AddBatch - marks the batch as applied and
+// adds its metrics, false without adding when
+// the batch was already marked within the
+// window. The mark of a batch that fails
+// to be added is removed. Returns the
+// totals of the added counters.
+func (m *MemoryRepository) AddBatch(
+	ctx *context.Context,
+	id string,
+	window time.Duration,
+	gauges map[string]bizmodels.Gauge,
+	counters map[string]bizmodels.Counter,
+) (map[string]bizmodels.Counter, bool, error) {
+	if !m.markBatch(id, window) {
+		return nil, false, nil
+	}
+
+	totals, err := m.AddMetrics(ctx, gauges, counters)
+	if err != nil {
+		m.unmarkBatch(id)
+
+		return nil, false,
+			fmt.Errorf("AddBatch->AddMetrics: %w", err)
+	}
+
+	return totals, true, nil
+}
+
+// markBatch - marks the batch as applied,
+// false when it was already marked within the window.
+func (m *MemoryRepository) markBatch(
+	id string,
+	window time.Duration,
+) bool {
+	m.mutexB.Lock()
+	defer m.mutexB.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(m.swept) >= window {
+		for key, marked := range m.batches {
+			if now.Sub(marked) >= window {
+				delete(m.batches, key)
+			}
+		}
+
+		m.swept = now
+	}
+
+	marked, ok := m.batches[id]
+	if ok && now.Sub(marked) < window {
+		return false
+	}
+
+	m.batches[id] = now
+
+	return true
+}
+
+// unmarkBatch - forgets the batch,
+// so it is applied when sent again.
+func (m *MemoryRepository) unmarkBatch(id string) {
+	m.mutexB.Lock()
+	defer m.mutexB.Unlock()
+
+	delete(m.batches, id)
 }
 
 // GetAllGauges - get all gauges metrics from memory.
@@ -80,7 +160,7 @@ func (m *MemoryRepository) GetGaugeMetric(
 		return &val, nil
 	}
 
-	return nil, errGetValueMetric
+	return nil, storage.ErrNotFound
 }
 
 // GetCounterMetric - get counter
@@ -94,7 +174,7 @@ func (m *MemoryRepository) GetCounterMetric(
 		return &val, nil
 	}
 
-	return nil, errGetValueMetric
+	return nil, storage.ErrNotFound
 }
 
 // AddGauge - add the gauge metric to the memory.
@@ -3,11 +3,17 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/dmitrovia/collector-metrics/internal/models/apimodels"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
 )
 
+// ErrNotFound - returned for a metric
+// missing from the storage.
+var ErrNotFound = errors.New("metric not found")
+
 // Repository - for working with storage metrics.
 type Repository interface {
 	Init()
@@ -28,8 +34,15 @@ type Repository interface {
 		error)
 	AddMetrics(ctx *context.Context,
 		gauges map[string]bizmodels.Gauge,
-		counters map[string]bizmodels.Counter) error
+		counters map[string]bizmodels.Counter) (
+		map[string]bizmodels.Counter, error)
 	GetAllMetricsAPI(ctx *context.Context) (
 		*apimodels.ArrMetrics,
 		error)
+	AddBatch(ctx *context.Context,
+		id string,
+		window time.Duration,
+		gauges map[string]bizmodels.Gauge,
+		counters map[string]bizmodels.Counter) (
+		map[string]bizmodels.Counter, bool, error)
 }
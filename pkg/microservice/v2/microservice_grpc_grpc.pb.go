@@ -0,0 +1,319 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: microservice/v2/microservice_grpc.proto
+
+package v2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MicroService_Sender_FullMethodName       = "/microservice.v2.MicroService/Sender"
+	MicroService_StreamSender_FullMethodName = "/microservice.v2.MicroService/StreamSender"
+	MicroService_Watch_FullMethodName        = "/microservice.v2.MicroService/Watch"
+	MicroService_GetMetric_FullMethodName    = "/microservice.v2.MicroService/GetMetric"
+	MicroService_GetMetrics_FullMethodName   = "/microservice.v2.MicroService/GetMetrics"
+	MicroService_ListMetrics_FullMethodName  = "/microservice.v2.MicroService/ListMetrics"
+)
+
+// MicroServiceClient is the client API for MicroService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MicroServiceClient interface {
+	Sender(ctx context.Context, in *SenderRequest, opts ...grpc.CallOption) (*SenderResponse, error)
+	// Long-lived ingestion stream, every batch is acknowledged
+	// with the number of accepted metrics.
+	StreamSender(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Batch, BatchAck], error)
+	// Streams the current values of the matching metrics
+	// and then every change of them.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetricEvent], error)
+	GetMetric(ctx context.Context, in *GetMetricRequest, opts ...grpc.CallOption) (*GetMetricResponse, error)
+	// Returns several metrics at once, unknown keys are reported as missing.
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error)
+	ListMetrics(ctx context.Context, in *ListMetricsRequest, opts ...grpc.CallOption) (*ListMetricsResponse, error)
+}
+
+type microServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMicroServiceClient(cc grpc.ClientConnInterface) MicroServiceClient {
+	return &microServiceClient{cc}
+}
+
+func (c *microServiceClient) Sender(ctx context.Context, in *SenderRequest, opts ...grpc.CallOption) (*SenderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SenderResponse)
+	err := c.cc.Invoke(ctx, MicroService_Sender_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *microServiceClient) StreamSender(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Batch, BatchAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MicroService_ServiceDesc.Streams[0], MicroService_StreamSender_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Batch, BatchAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MicroService_StreamSenderClient = grpc.BidiStreamingClient[Batch, BatchAck]
+
+func (c *microServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetricEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MicroService_ServiceDesc.Streams[1], MicroService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, MetricEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MicroService_WatchClient = grpc.ServerStreamingClient[MetricEvent]
+
+func (c *microServiceClient) GetMetric(ctx context.Context, in *GetMetricRequest, opts ...grpc.CallOption) (*GetMetricResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetricResponse)
+	err := c.cc.Invoke(ctx, MicroService_GetMetric_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *microServiceClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetricsResponse)
+	err := c.cc.Invoke(ctx, MicroService_GetMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *microServiceClient) ListMetrics(ctx context.Context, in *ListMetricsRequest, opts ...grpc.CallOption) (*ListMetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMetricsResponse)
+	err := c.cc.Invoke(ctx, MicroService_ListMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MicroServiceServer is the server API for MicroService service.
+// All implementations must embed UnimplementedMicroServiceServer
+// for forward compatibility.
+type MicroServiceServer interface {
+	Sender(context.Context, *SenderRequest) (*SenderResponse, error)
+	// Long-lived ingestion stream, every batch is acknowledged
+	// with the number of accepted metrics.
+	StreamSender(grpc.BidiStreamingServer[Batch, BatchAck]) error
+	// Streams the current values of the matching metrics
+	// and then every change of them.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[MetricEvent]) error
+	GetMetric(context.Context, *GetMetricRequest) (*GetMetricResponse, error)
+	// Returns several metrics at once, unknown keys are reported as missing.
+	GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error)
+	ListMetrics(context.Context, *ListMetricsRequest) (*ListMetricsResponse, error)
+	mustEmbedUnimplementedMicroServiceServer()
+}
+
+// UnimplementedMicroServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMicroServiceServer struct{}
+
+func (UnimplementedMicroServiceServer) Sender(context.Context, *SenderRequest) (*SenderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sender not implemented")
+}
+func (UnimplementedMicroServiceServer) StreamSender(grpc.BidiStreamingServer[Batch, BatchAck]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSender not implemented")
+}
+func (UnimplementedMicroServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[MetricEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedMicroServiceServer) GetMetric(context.Context, *GetMetricRequest) (*GetMetricResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetric not implemented")
+}
+func (UnimplementedMicroServiceServer) GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedMicroServiceServer) ListMetrics(context.Context, *ListMetricsRequest) (*ListMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMetrics not implemented")
+}
+func (UnimplementedMicroServiceServer) mustEmbedUnimplementedMicroServiceServer() {}
+func (UnimplementedMicroServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeMicroServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MicroServiceServer will
+// result in compilation errors.
+type UnsafeMicroServiceServer interface {
+	mustEmbedUnimplementedMicroServiceServer()
+}
+
+func RegisterMicroServiceServer(s grpc.ServiceRegistrar, srv MicroServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMicroServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MicroService_ServiceDesc, srv)
+}
+
+func _MicroService_Sender_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SenderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MicroServiceServer).Sender(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MicroService_Sender_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MicroServiceServer).Sender(ctx, req.(*SenderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MicroService_StreamSender_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MicroServiceServer).StreamSender(&grpc.GenericServerStream[Batch, BatchAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MicroService_StreamSenderServer = grpc.BidiStreamingServer[Batch, BatchAck]
+
+func _MicroService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MicroServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, MetricEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MicroService_WatchServer = grpc.ServerStreamingServer[MetricEvent]
+
+func _MicroService_GetMetric_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MicroServiceServer).GetMetric(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MicroService_GetMetric_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MicroServiceServer).GetMetric(ctx, req.(*GetMetricRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MicroService_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MicroServiceServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MicroService_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MicroServiceServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MicroService_ListMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MicroServiceServer).ListMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MicroService_ListMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MicroServiceServer).ListMetrics(ctx, req.(*ListMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MicroService_ServiceDesc is the grpc.ServiceDesc for MicroService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MicroService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "microservice.v2.MicroService",
+	HandlerType: (*MicroServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Sender",
+			Handler:    _MicroService_Sender_Handler,
+		},
+		{
+			MethodName: "GetMetric",
+			Handler:    _MicroService_GetMetric_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _MicroService_GetMetrics_Handler,
+		},
+		{
+			MethodName: "ListMetrics",
+			Handler:    _MicroService_ListMetrics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSender",
+			Handler:       _MicroService_StreamSender_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _MicroService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "microservice/v2/microservice_grpc.proto",
+}
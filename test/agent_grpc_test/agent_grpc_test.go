@@ -12,6 +12,7 @@ import (
 	"github.com/dmitrovia/collector-metrics/internal/agentimplement"
 	"github.com/dmitrovia/collector-metrics/internal/logger"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
+	"github.com/stretchr/testify/assert"
 )
 
 //nolint:gochecknoglobals
@@ -27,6 +28,7 @@ type testData1 struct {
 	cDefReportInterval string // r
 	cUpdateURL         string // update-url
 	cUseGRPC           string // use-grpc
+	cGRPCTLS           string // grpc-tls
 	grpcPort           string
 }
 
@@ -39,6 +41,7 @@ func getTestData1() *[]testData1 {
 			cDefCountJobs:      "5",
 			cDefReportInterval: "10",
 			cUseGRPC:           "true",
+			cGRPCTLS:           "true",
 			cUpdateURL:         "http://localhost:8091/v1/updates",
 			grpcPort:           "50053",
 		},
@@ -56,7 +59,9 @@ func TestMain1(t *testing.T) {
 			tobj.Parallel()
 
 			addFlags1(&test)
-			mainBody1()
+
+			err := mainBody1()
+			assert.NoError(tobj, err)
 		})
 	}
 }
@@ -69,10 +74,11 @@ func addFlags1(test *testData1) {
 	os.Args = append(os.Args, "-r="+test.cDefReportInterval)
 	os.Args = append(os.Args, "-update-url="+test.cUpdateURL)
 	os.Args = append(os.Args, "-use-grpc="+test.cUseGRPC)
+	os.Args = append(os.Args, "-grpc-tls="+test.cGRPCTLS)
 	os.Args = append(os.Args, "-grpcp="+test.grpcPort)
 }
 
-func mainBody1() {
+func mainBody1() error {
 	waitGroup := &sync.WaitGroup{}
 	monitor := &bizmodels.Monitor{}
 	client := &http.Client{}
@@ -81,9 +87,7 @@ func mainBody1() {
 	zlog, err := agentimplement.Initialization(params,
 		monitor)
 	if err != nil {
-		fmt.Println("main->initialization: %w", err)
-
-		return
+		return fmt.Errorf("main->initialization: %w", err)
 	}
 
 	logger.DoInfoLog("Build version: "+buildVersion1, zlog)
@@ -121,6 +125,8 @@ func mainBody1() {
 	go exit1(&channelCancel, &channelCancel1)
 
 	waitGroup.Wait()
+
+	return nil
 }
 
 func exit1(
@@ -12,6 +12,7 @@ import (
 
 	"github.com/dmitrovia/collector-metrics/internal/Interceptors/decompressinterceptor"
 	"github.com/dmitrovia/collector-metrics/internal/Interceptors/decryptinterceptor"
+	"github.com/dmitrovia/collector-metrics/internal/Interceptors/validateinterceptor"
 	"github.com/dmitrovia/collector-metrics/internal/grpcimplement"
 	"github.com/dmitrovia/collector-metrics/internal/logger"
 	"github.com/dmitrovia/collector-metrics/internal/models/bizmodels"
@@ -95,7 +96,11 @@ func mainBody() {
 	interceptors = append(interceptors,
 		grpc.ChainUnaryInterceptor(
 			decryptinterceptor.DecryptInterceptor(params),
-			decompressinterceptor.DecompressInterceptor(),
+			decompressinterceptor.DecompressInterceptor(params),
+			validateinterceptor.ValidateInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			validateinterceptor.StreamInterceptor(),
 		))
 	grpcServer := grpc.NewServer(interceptors...)
 